@@ -0,0 +1,182 @@
+package detective
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// adminCheck describes a registered check in the responses of the admin handler
+type adminCheck struct {
+	// Type is "dependency", "group", or "endpoint"
+	Type string `json:"type"`
+	// Name is the name of a dependency or group, or the URL of an endpoint
+	Name     string `json:"name"`
+	Method   string `json:"method,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// adminEndpoint is the request body used to add an endpoint with the admin handler
+type adminEndpoint struct {
+	URL                string            `json:"url"`
+	Method             string            `json:"method"`
+	Headers            map[string]string `json:"headers"`
+	Body               string            `json:"body"`
+	ExpectStatus       []int             `json:"expect_status"`
+	ExpectBodyContains string            `json:"expect_body_contains"`
+}
+
+func (a adminEndpoint) options() []EndpointOption {
+	var opts []EndpointOption
+	if a.Method != "" {
+		opts = append(opts, Method(a.Method))
+	}
+	for key, value := range a.Headers {
+		opts = append(opts, Header(key, value))
+	}
+	if a.Body != "" {
+		opts = append(opts, Body([]byte(a.Body)))
+	}
+	if len(a.ExpectStatus) > 0 {
+		opts = append(opts, ExpectStatus(a.ExpectStatus...))
+	}
+	if a.ExpectBodyContains != "" {
+		opts = append(opts, ExpectBodyContains(a.ExpectBodyContains))
+	}
+	return opts
+}
+
+// AdminHandler returns an HTTP handler to manage the checks of the instance at runtime. Every request has to be authenticated with the provided token, as an "Authorization: Bearer <token>" header. If the token is empty, all requests are rejected.
+// The handler should be mounted with its prefix stripped, and supports the following requests:
+//
+//	GET    /                 lists the registered dependencies, groups, and endpoints
+//	POST   /                 adds an endpoint, described by a JSON body like {"url": "http://localhost:8081/", "expect_status": [200]}
+//	DELETE /?url=<url>       removes an endpoint
+//	POST   /disable?url=<url> stops checking an endpoint (see DisableEndpoint)
+//	POST   /enable?url=<url>  resumes checking an endpoint
+//	POST   /run              checks all dependencies and endpoints immediately, refreshing the cached states, and responds with the new state
+//
+// For example:
+//
+//	http.Handle("/admin/checks/", http.StripPrefix("/admin/checks", d.AdminHandler(token)))
+func (d *Detective) AdminHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorizedBearer(r, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="detective"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch path := strings.TrimSuffix(r.URL.Path, "/"); {
+		case path == "" && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, d.adminChecks())
+		case path == "" && r.Method == http.MethodPost:
+			d.adminAddEndpoint(w, r)
+		case path == "" && r.Method == http.MethodDelete:
+			d.adminEndpointAction(w, r, d.RemoveEndpoint)
+		case path == "/disable" && r.Method == http.MethodPost:
+			d.adminEndpointAction(w, r, d.DisableEndpoint)
+		case path == "/enable" && r.Method == http.MethodPost:
+			d.adminEndpointAction(w, r, d.EnableEndpoint)
+		case path == "/run" && r.Method == http.MethodPost:
+			s := d.checkNow(r.Context())
+			if d.redactErrors {
+				s = s.withoutErrors()
+			}
+			writeJSON(w, d.statusCode(s), s)
+		case path == "" || path == "/disable" || path == "/enable" || path == "/run":
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func authorizedBearer(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+	if token == "" || !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (d *Detective) adminChecks() []adminCheck {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	checks := make([]adminCheck, 0, len(d.dependencies)+len(d.groups)+len(d.endpoints))
+	now := time.Now()
+	for _, dep := range d.dependencies {
+		dep.mu.RLock()
+		disabled := dep.isDisabled(now)
+		dep.mu.RUnlock()
+		checks = append(checks, adminCheck{Type: "dependency", Name: dep.name, Disabled: disabled})
+	}
+	for _, g := range d.groups {
+		checks = append(checks, adminCheck{Type: "group", Name: g.name})
+	}
+	for _, e := range d.endpoints {
+		checks = append(checks, adminCheck{Type: "endpoint", Name: e.req.URL.String(), Method: e.req.Method, Disabled: e.disabled.Load()})
+	}
+	return checks
+}
+
+func (d *Detective) adminAddEndpoint(w http.ResponseWriter, r *http.Request) {
+	var a adminEndpoint
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		http.Error(w, "invalid endpoint: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if a.URL == "" {
+		http.Error(w, "invalid endpoint: url is required", http.StatusBadRequest)
+		return
+	}
+	if err := d.EndpointWithOptions(a.URL, a.options()...); err != nil {
+		http.Error(w, "invalid endpoint: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	method := a.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	writeJSON(w, http.StatusCreated, adminCheck{Type: "endpoint", Name: a.URL, Method: method})
+}
+
+func (d *Detective) adminEndpointAction(w http.ResponseWriter, r *http.Request, action func(url string) bool) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		http.Error(w, "the url query parameter is required", http.StatusBadRequest)
+		return
+	}
+	if !action(url) {
+		http.Error(w, "no endpoint registered with url "+url, http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkNow checks all dependencies and endpoints immediately, and replaces the states cached by periodic checks, or by the cache TTL with the result
+func (d *Detective) checkNow(ctx context.Context) State {
+	d.periodicMu.RLock()
+	periodic := d.stopPeriodic != nil
+	d.periodicMu.RUnlock()
+	if periodic {
+		d.refreshCachedState(ctx)
+		if s, ok := d.getCachedState(); ok {
+			return s
+		}
+	}
+	s := d.getState(ctx, nil)
+	d.recordState(s)
+	if d.cache.ttl > 0 {
+		d.cache.set(s, time.Now())
+	}
+	return s
+}
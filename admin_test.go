@@ -0,0 +1,171 @@
+package detective
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func adminRequest(t *testing.T, h http.Handler, token, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	return rw
+}
+
+func TestAdminHandlerUnauthorized(t *testing.T) {
+	d := New("sample")
+	tests := []struct {
+		name    string
+		handler string
+		token   string
+	}{
+		{name: "missing token", handler: "secret"},
+		{name: "wrong token", handler: "secret", token: "guess"},
+		{name: "empty handler token", handler: "", token: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := adminRequest(t, d.AdminHandler(tt.handler), tt.token, http.MethodGet, "/", "")
+			assert.Equal(t, http.StatusUnauthorized, rw.Code)
+			assert.Equal(t, `Bearer realm="detective"`, rw.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	var failing int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	d := New("sample")
+	d.Dependency("db").Detect(func() error {
+		return nil
+	})
+	d.Group("cache")
+	h := d.AdminHandler("secret")
+
+	rw := adminRequest(t, h, "secret", http.MethodPost, "/", `{"url": "`+s.URL+`", "expect_status": [204]}`)
+	require.Equal(t, http.StatusCreated, rw.Code)
+	assert.JSONEq(t, `{"type": "endpoint", "name": "`+s.URL+`", "method": "GET"}`, rw.Body.String())
+
+	rw = adminRequest(t, h, "secret", http.MethodGet, "/", "")
+	require.Equal(t, http.StatusOK, rw.Code)
+	var checks []adminCheck
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &checks))
+	assert.Equal(t, []adminCheck{
+		{Type: "dependency", Name: "db"},
+		{Type: "group", Name: "cache"},
+		{Type: "endpoint", Name: s.URL, Method: http.MethodGet},
+	}, checks)
+
+	state := d.GetState()
+	require.Len(t, state.Dependencies, 3)
+	assert.True(t, state.Dependencies[2].Ok)
+
+	atomic.StoreInt32(&failing, 1)
+	assert.False(t, d.GetState().Ok)
+	rw = adminRequest(t, h, "secret", http.MethodPost, "/disable?url="+s.URL, "")
+	require.Equal(t, http.StatusNoContent, rw.Code)
+	state = d.GetState()
+	assert.Equal(t, Healthy, state.Health)
+	assert.Equal(t, State{Name: s.URL, Ok: true, Status: "Disabled", Health: Healthy, Disabled: true}, state.Dependencies[2])
+
+	rw = adminRequest(t, h, "secret", http.MethodPost, "/enable?url="+s.URL, "")
+	require.Equal(t, http.StatusNoContent, rw.Code)
+	assert.Equal(t, Unhealthy, d.GetState().Health)
+
+	rw = adminRequest(t, h, "secret", http.MethodDelete, "/?url="+s.URL, "")
+	require.Equal(t, http.StatusNoContent, rw.Code)
+	assert.Len(t, d.GetState().Dependencies, 2)
+
+	rw = adminRequest(t, h, "secret", http.MethodDelete, "/?url="+s.URL, "")
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func TestAdminHandlerErrors(t *testing.T) {
+	h := New("sample").AdminHandler("secret")
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		code   int
+	}{
+		{name: "invalid body", method: http.MethodPost, target: "/", body: "{", code: http.StatusBadRequest},
+		{name: "missing url", method: http.MethodPost, target: "/", body: `{"method": "POST"}`, code: http.StatusBadRequest},
+		{name: "invalid url", method: http.MethodPost, target: "/", body: `{"url": ":"}`, code: http.StatusBadRequest},
+		{name: "missing url parameter", method: http.MethodPost, target: "/disable", code: http.StatusBadRequest},
+		{name: "unknown endpoint", method: http.MethodPost, target: "/enable?url=http://localhost/", code: http.StatusNotFound},
+		{name: "unknown path", method: http.MethodGet, target: "/unknown", code: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodGet, target: "/run", code: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := adminRequest(t, h, "secret", tt.method, tt.target, tt.body)
+			assert.Equal(t, tt.code, rw.Code)
+		})
+	}
+}
+
+func TestAdminHandlerRun(t *testing.T) {
+	var failing int32
+	d := New("sample")
+	d.Dependency("db").Detect(func() error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	require.NoError(t, d.StartPeriodic(time.Hour))
+	defer d.Stop()
+	assert.True(t, waitFor(func() bool {
+		_, ok := d.getCachedState()
+		return ok
+	}))
+	assert.True(t, d.GetState().Ok)
+
+	atomic.StoreInt32(&failing, 1)
+	rw := adminRequest(t, d.AdminHandler("secret"), "secret", http.MethodPost, "/run", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Contains(t, rw.Body.String(), `"error":"failed"`)
+	assert.False(t, d.GetState().Ok)
+}
+
+func TestAdminHandlerRunCacheTTL(t *testing.T) {
+	var failing int32
+	d := New("sample", WithCacheTTL(time.Hour))
+	d.Dependency("db").Detect(func() error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	rw := httptest.NewRecorder()
+	d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	atomic.StoreInt32(&failing, 1)
+	rw = adminRequest(t, d.AdminHandler("secret"), "secret", http.MethodPost, "/run", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+
+	rw = httptest.NewRecorder()
+	d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+}
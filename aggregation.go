@@ -0,0 +1,76 @@
+package detective
+
+// An Aggregation decides the health of an entity from the states of its dependencies. The aggregations provided by this package never report an entity as unhealthy because of its non-critical dependencies, which only degrade an otherwise healthy entity when they fail.
+type Aggregation func(states []State) Health
+
+// AllMustPass reports the entity as unhealthy if any of its dependencies is unhealthy, and as degraded if any of them is degraded. This is the default aggregation.
+func AllMustPass() Aggregation {
+	return aggregateHealth
+}
+
+// AnyPass reports the entity as healthy if all of its dependencies are healthy, as degraded if at least one of them is working, and as unhealthy if none of them are. It is suited for redundant dependencies, like replicas of a service.
+func AnyPass() Aggregation {
+	return Quorum(1)
+}
+
+// Quorum reports the entity as healthy if all of its dependencies are healthy, as degraded if at least n of them are working, and as unhealthy otherwise. Degraded dependencies are considered to be working.
+func Quorum(n int) Aggregation {
+	return withNonCritical(func(states []State) Health {
+		working := 0
+		for i := range states {
+			if effectiveHealth(states[i]) != Unhealthy {
+				working++
+			}
+		}
+		return scoreHealth(states, working >= n)
+	})
+}
+
+// WeightedScore computes the sum of the weights of working dependencies, divided by the sum of the weights of all dependencies. The entity is reported as healthy if all of its dependencies are healthy, as degraded if the score is at least the threshold (between 0 and 1), and as unhealthy otherwise. Dependencies without a weight have a weight of 1.
+func WeightedScore(weights map[string]float64, threshold float64) Aggregation {
+	return withNonCritical(func(states []State) Health {
+		total, working := 0.0, 0.0
+		for i := range states {
+			w, ok := weights[states[i].Name]
+			if !ok {
+				w = 1
+			}
+			total += w
+			if effectiveHealth(states[i]) != Unhealthy {
+				working += w
+			}
+		}
+		return scoreHealth(states, total == 0 || working/total >= threshold)
+	})
+}
+
+// scoreHealth returns Healthy if all states are healthy, and otherwise Degraded or Unhealthy depending on whether enough states are working
+func scoreHealth(states []State, enough bool) Health {
+	if aggregateHealth(states) == Healthy {
+		return Healthy
+	}
+	if enough {
+		return Degraded
+	}
+	return Unhealthy
+}
+
+// withNonCritical applies the aggregation to the critical states only, and degrades the result if any non-critical state is not healthy
+func withNonCritical(a Aggregation) Aggregation {
+	return func(states []State) Health {
+		critical := make([]State, 0, len(states))
+		degraded := false
+		for i := range states {
+			if states[i].NonCritical {
+				degraded = degraded || effectiveHealth(states[i]) != Healthy
+				continue
+			}
+			critical = append(critical, states[i])
+		}
+		h := a(critical)
+		if h == Healthy && degraded {
+			return Degraded
+		}
+		return h
+	}
+}
@@ -0,0 +1,54 @@
+package detective
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAggregations(t *testing.T) {
+	healthy := func(name string) State { return State{Name: name}.withOk() }
+	unhealthy := func(name string) State { return State{Name: name}.withError(errors.New("failed")) }
+	degraded := func(name string) State { return State{Name: name}.withDegraded(errors.New("slow")) }
+	nonCritical := func(s State) State { s.NonCritical = true; return s }
+
+	tests := []struct {
+		name        string
+		aggregation Aggregation
+		states      []State
+		expected    Health
+	}{
+		{"all must pass with no states", AllMustPass(), nil, Healthy},
+		{"all must pass with one failure", AllMustPass(), []State{healthy("a"), unhealthy("b")}, Unhealthy},
+		{"any pass with all healthy", AnyPass(), []State{healthy("a"), healthy("b")}, Healthy},
+		{"any pass with one failure", AnyPass(), []State{healthy("a"), unhealthy("b")}, Degraded},
+		{"any pass with all failing", AnyPass(), []State{unhealthy("a"), unhealthy("b")}, Unhealthy},
+		{"any pass with a degraded state", AnyPass(), []State{degraded("a"), unhealthy("b")}, Degraded},
+		{"any pass with no states", AnyPass(), nil, Healthy},
+		{"quorum met", Quorum(2), []State{healthy("a"), healthy("b"), unhealthy("c")}, Degraded},
+		{"quorum not met", Quorum(2), []State{healthy("a"), unhealthy("b"), unhealthy("c")}, Unhealthy},
+		{"quorum ignores non-critical states", Quorum(1), []State{unhealthy("a"), nonCritical(healthy("b"))}, Unhealthy},
+		{"non-critical failures degrade", Quorum(1), []State{healthy("a"), nonCritical(unhealthy("b"))}, Degraded},
+		{"weighted score above threshold", WeightedScore(map[string]float64{"primary": 3}, 0.7), []State{healthy("primary"), unhealthy("replica")}, Degraded},
+		{"weighted score below threshold", WeightedScore(map[string]float64{"primary": 3}, 0.7), []State{unhealthy("primary"), healthy("replica")}, Unhealthy},
+		{"weighted score with all healthy", WeightedScore(nil, 1), []State{healthy("a"), healthy("b")}, Healthy},
+		{"weighted score with zero weights", WeightedScore(map[string]float64{"a": 0}, 0.5), []State{unhealthy("a")}, Degraded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.aggregation(tt.states))
+		})
+	}
+}
+
+func TestWithAggregation(t *testing.T) {
+	d := New("sample")
+	replicas := d.Group("replicas", WithAggregation(AnyPass()))
+	replicas.Dependency("replica-1")
+	replicas.Dependency("replica-2").Detect(func() error { return errors.New("connection refused") })
+
+	s := d.GetState()
+	assert.Equal(t, Degraded, s.Health)
+	assert.Equal(t, "Degraded: degraded dependency", s.Dependencies[0].Status)
+	assert.True(t, s.Ok)
+}
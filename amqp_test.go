@@ -0,0 +1,143 @@
+package detective
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveAMQP runs a minimal AMQP 0-9-1 broker, which accepts the guest credentials on the "/" virtual host, and has the "orders" queue
+func serveAMQP(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		l.Close()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go brokerAMQP(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func brokerAMQP(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil || string(header) != "AMQP\x00\x00\x09\x01" {
+		return
+	}
+	var start bytes.Buffer
+	start.Write([]byte{0, 9, 0, 0, 0, 0})
+	writeLongString(&start, "PLAIN")
+	writeLongString(&start, "en_US")
+	writeAMQPFrame(conn, 0, amqpConnectionStart, start.Bytes())
+
+	_, _, startOk, err := readAMQPFrame(r)
+	if err != nil {
+		return
+	}
+	// client properties, then the mechanism, and the response
+	props := binary.BigEndian.Uint32(startOk)
+	rest := startOk[4+props:]
+	rest = rest[1+int(rest[0]):]
+	response := string(rest[4 : 4+binary.BigEndian.Uint32(rest)])
+	if response != "\x00guest\x00guest" {
+		return
+	}
+	writeAMQPFrame(conn, 0, amqpConnectionTune, []byte{0x07, 0xff, 0, 2, 0, 0, 0, 60})
+	if _, _, _, err := readAMQPFrame(r); err != nil {
+		return
+	}
+	_, _, open, err := readAMQPFrame(r)
+	if err != nil {
+		return
+	}
+	if vhost := string(open[1 : 1+open[0]]); vhost != "/" {
+		var closeArgs bytes.Buffer
+		closeArgs.Write([]byte{0x02, 0x12})
+		writeShortString(&closeArgs, "NOT_ALLOWED - vhost "+vhost+" not found")
+		closeArgs.Write([]byte{0, 10, 0, 40})
+		writeAMQPFrame(conn, 0, amqpConnectionClose, closeArgs.Bytes())
+		return
+	}
+	writeAMQPFrame(conn, 0, amqpConnectionOpenOk, []byte{0})
+	// heartbeats are ignored by the client
+	conn.Write([]byte{amqpFrameHeartbeat, 0, 0, 0, 0, 0, 0, amqpFrameEnd})
+
+	for {
+		ch, method, args, err := readAMQPFrame(r)
+		if err != nil {
+			return
+		}
+		switch method {
+		case amqpChannelOpen:
+			writeAMQPFrame(conn, ch, amqpChannelOpenOk, []byte{0, 0, 0, 0})
+		case amqpQueueDeclare:
+			queue := string(args[3 : 3+args[2]])
+			if queue != "orders" {
+				var closeArgs bytes.Buffer
+				closeArgs.Write([]byte{0x01, 0x94})
+				writeShortString(&closeArgs, "NOT_FOUND - no queue '"+queue+"'")
+				closeArgs.Write([]byte{0, 50, 0, 10})
+				writeAMQPFrame(conn, ch, amqpChannelClose, closeArgs.Bytes())
+				continue
+			}
+			var ok bytes.Buffer
+			writeShortString(&ok, queue)
+			ok.Write([]byte{0, 0, 0, 5, 0, 0, 0, 1})
+			writeAMQPFrame(conn, ch, amqpQueueDeclareOk, ok.Bytes())
+		case amqpConnectionClose:
+			writeAMQPFrame(conn, 0, amqpConnectionCloseOk, nil)
+			return
+		}
+	}
+}
+
+func TestDetectAMQP(t *testing.T) {
+	addr := serveAMQP(t)
+	tests := []struct {
+		name    string
+		url     string
+		c       AMQPConfig
+		err     string
+		details map[string]interface{}
+	}{
+		{name: "connected", url: "amqp://" + addr},
+		{name: "queues", url: "amqp://guest:guest@" + addr + "/", c: AMQPConfig{Queues: []string{"orders"}}, details: map[string]interface{}{
+			"queues": map[string]interface{}{"orders": map[string]interface{}{"messages": uint32(5), "consumers": uint32(1)}},
+		}},
+		{name: "missing queues", url: "amqp://" + addr, c: AMQPConfig{Queues: []string{"payments", "orders", "refunds"}}, err: "amqp queues not found: payments, refunds", details: map[string]interface{}{
+			"queues": map[string]interface{}{"orders": map[string]interface{}{"messages": uint32(5), "consumers": uint32(1)}},
+		}},
+		{name: "invalid credentials", url: "amqp://guest:wrong@" + addr, err: "the credentials may be invalid"},
+		{name: "unknown vhost", url: "amqp://" + addr + "/staging", err: "amqp broker closed the connection: 530 NOT_ALLOWED - vhost staging not found"},
+		{name: "unsupported scheme", url: "http://" + addr, err: `unsupported amqp url scheme "http"`},
+		{name: "unreachable", url: "amqp://127.0.0.1:1", err: "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New("sample")
+			d.Dependency("amqp").WithTimeout(time.Second).DetectAMQP(tt.url, tt.c)
+			dep := d.GetState().Dependencies[0]
+			if tt.err == "" {
+				assert.True(t, dep.Ok, dep.Error)
+			} else {
+				assert.False(t, dep.Ok)
+				assert.Contains(t, dep.Error, tt.err)
+			}
+			assert.Equal(t, tt.details, dep.Details)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package detective
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// A TokenProviderFunc returns the bearer token sent to an endpoint registered with the TokenProvider option. It is called before every request, so it should cache tokens that are expensive to obtain.
+type TokenProviderFunc func(ctx context.Context) (string, error)
+
+// BasicAuth sets the username and password sent to the endpoint with HTTP basic authentication
+func BasicAuth(username, password string) EndpointOption {
+	return func(e *endpoint) {
+		e.authorizers = append(e.authorizers, func(ctx context.Context, req *http.Request) error {
+			req.SetBasicAuth(username, password)
+			return nil
+		})
+	}
+}
+
+// BearerToken sets the token sent to the endpoint in an "Authorization: Bearer <token>" header
+func BearerToken(token string) EndpointOption {
+	return TokenProvider(func(ctx context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// TokenProvider obtains the bearer token sent to the endpoint from the provided function before every request, so that tokens can be refreshed as they expire. If the function returns an error, the request is not sent and the endpoint is reported as unhealthy.
+func TokenProvider(provider TokenProviderFunc) EndpointOption {
+	return func(e *endpoint) {
+		e.authorizers = append(e.authorizers, func(ctx context.Context, req *http.Request) error {
+			token, err := provider(ctx)
+			if err != nil {
+				return fmt.Errorf("could not obtain a token for %s: %w", req.URL, err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEndpointCredentials(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		switch {
+		case ok && user == "user" && pass == "pass":
+		case r.Header.Get("Authorization") == "Bearer token":
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	tokens := 0
+	tests := []struct {
+		name     string
+		opt      EndpointOption
+		expected Health
+	}{
+		{name: "basic auth", opt: BasicAuth("user", "pass"), expected: Healthy},
+		{name: "wrong basic auth", opt: BasicAuth("user", "wrong"), expected: Unhealthy},
+		{name: "bearer token", opt: BearerToken("token"), expected: Healthy},
+		{name: "wrong bearer token", opt: BearerToken("wrong"), expected: Unhealthy},
+		{name: "token provider", opt: TokenProvider(func(ctx context.Context) (string, error) {
+			tokens++
+			return "token", nil
+		}), expected: Healthy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New("sample")
+			require.NoError(t, d.EndpointWithOptions(s.URL, ExpectStatus(http.StatusOK), tt.opt))
+			assert.Equal(t, tt.expected, d.GetState().Dependencies[0].Health)
+		})
+	}
+	assert.Equal(t, 1, tokens)
+}
+
+func TestTokenProviderError(t *testing.T) {
+	called := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer s.Close()
+
+	d := New("sample")
+	require.NoError(t, d.EndpointWithOptions(s.URL, ExpectStatus(http.StatusOK), TokenProvider(func(ctx context.Context) (string, error) {
+		return "", errors.New("token service unavailable")
+	})))
+	dep := d.GetState().Dependencies[0]
+	assert.Equal(t, Unhealthy, dep.Health)
+	assert.Equal(t, "could not obtain a token for "+s.URL+": token service unavailable", dep.Error)
+	assert.False(t, called)
+}
@@ -0,0 +1,41 @@
+package detective
+
+import (
+	"runtime/debug"
+)
+
+// BuildInfo describes the build of the application serving a Detective instance
+type BuildInfo struct {
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+}
+
+// WithBuildInfo adds the version, the commit and the build time of the application to the state of the instance, so that operators can confirm which build is serving before correlating failures. Empty values are filled in from the build information embedded in the binary by the Go toolchain, which contains the version of the main module, and the revision and time of its commit, if it was built from a version control checkout:
+//
+//	d := detective.New("application", detective.WithBuildInfo(version, "", ""))
+func WithBuildInfo(version, commit, buildTime string) Option {
+	return func(d *Detective) {
+		b := BuildInfo{Version: version, Commit: commit, BuildTime: buildTime}
+		if info, ok := debug.ReadBuildInfo(); ok {
+			b = b.withDefaults(info)
+		}
+		d.build = &b
+	}
+}
+
+// withDefaults fills in the empty values with the build information of the binary
+func (b BuildInfo) withDefaults(info *debug.BuildInfo) BuildInfo {
+	if b.Version == "" && info.Main.Version != "(devel)" {
+		b.Version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && b.Commit == "":
+			b.Commit = setting.Value
+		case setting.Key == "vcs.time" && b.BuildTime == "":
+			b.BuildTime = setting.Value
+		}
+	}
+	return b
+}
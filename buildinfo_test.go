@@ -0,0 +1,33 @@
+package detective
+
+import (
+	"github.com/stretchr/testify/assert"
+	"runtime/debug"
+	"testing"
+)
+
+func TestBuildInfoDefaults(t *testing.T) {
+	info := &debug.BuildInfo{
+		Main: debug.Module{Path: "example.com/app", Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.time", Value: "2024-05-01T12:00:00Z"},
+		},
+	}
+	assert.Equal(t, BuildInfo{Version: "v1.2.3", Commit: "0123456789abcdef", BuildTime: "2024-05-01T12:00:00Z"}, BuildInfo{}.withDefaults(info))
+	assert.Equal(t, BuildInfo{Version: "v2.0.0", Commit: "abc", BuildTime: "2024-05-01T12:00:00Z"}, BuildInfo{Version: "v2.0.0", Commit: "abc"}.withDefaults(info))
+
+	info.Main.Version = "(devel)"
+	assert.Equal(t, "", BuildInfo{}.withDefaults(info).Version)
+}
+
+func TestWithBuildInfo(t *testing.T) {
+	d := New("sample", WithBuildInfo("v1.2.3", "abc", "2024-05-01T12:00:00Z"))
+	d.Dependency("db")
+	d.Group("storage").Dependency("s3")
+	s := d.GetState()
+	assert.Equal(t, &BuildInfo{Version: "v1.2.3", Commit: "abc", BuildTime: "2024-05-01T12:00:00Z"}, s.Build)
+	assert.Nil(t, s.Dependencies[1].Build)
+	assert.Nil(t, s.summary().Build)
+	assert.Nil(t, New("sample").GetState().Build)
+}
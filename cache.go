@@ -0,0 +1,61 @@
+package detective
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resultCache holds the most recent state computed by the handler, when a cache TTL is set
+type resultCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	state *State
+	at    time.Time
+}
+
+func (c *resultCache) get() (State, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == nil {
+		return State{}, time.Time{}, false
+	}
+	return *c.state, c.at, true
+}
+
+func (c *resultCache) set(s State, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = &s
+	c.at = at
+}
+
+// sharedState is similar to currentState, but concurrent calls with the same calling chain share the result of the same checks.
+// If a cache TTL is set, the most recently computed state is returned as long as it is fresher than the TTL. Once it is stale, it is still returned, and refreshed in the background.
+func (d *Detective) sharedState(ctx context.Context, fromChain []string) State {
+	if d.cache.ttl <= 0 || contains(fromChain, d.name) {
+		return d.flightState(ctx, fromChain)
+	}
+	if s, ok := d.getCachedState(); ok {
+		return s
+	}
+	s, at, ok := d.cache.get()
+	if !ok {
+		return d.flightState(ctx, nil)
+	}
+	if time.Now().Sub(at) >= d.cache.ttl {
+		go d.flightState(context.Background(), nil)
+	}
+	return s
+}
+
+func (d *Detective) flightState(ctx context.Context, fromChain []string) State {
+	return d.flight.do(ctx, d.name, strings.Join(fromChain, "|"), func(ctx context.Context) State {
+		s := d.currentState(ctx, fromChain)
+		if d.cache.ttl > 0 && !contains(fromChain, d.name) {
+			d.cache.set(s, time.Now())
+		}
+		return s
+	})
+}
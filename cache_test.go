@@ -0,0 +1,63 @@
+package detective
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheTTL(t *testing.T) {
+	var failing int32
+	d := New("sample", WithCacheTTL(time.Hour))
+	d.Dependency("sampledep").Detect(func() error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	serve := func() int {
+		rw := httptest.NewRecorder()
+		d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rw.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve())
+	atomic.StoreInt32(&failing, 1)
+	assert.Equal(t, http.StatusOK, serve(), "the cached state should be served within the TTL")
+	assert.Equal(t, Unhealthy, d.GetState().Health, "GetState should not use the cache of the handler")
+}
+
+func TestCacheTTLStaleWhileRevalidate(t *testing.T) {
+	var calls int32
+	release := make(chan struct{}, 1)
+	d := New("sample", WithCacheTTL(time.Millisecond))
+	d.Dependency("sampledep").Detect(func() error {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			<-release
+			return errors.New("failed")
+		}
+		return nil
+	})
+	serve := func() int {
+		rw := httptest.NewRecorder()
+		d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rw.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve())
+	time.Sleep(2 * time.Millisecond)
+	// The stale state is served without waiting for the slow refresh
+	assert.Equal(t, http.StatusOK, serve())
+	assert.True(t, waitFor(func() bool { return atomic.LoadInt32(&calls) == 2 }))
+	release <- struct{}{}
+	assert.True(t, waitFor(func() bool {
+		s, _, _ := d.cache.get()
+		return s.Health == Unhealthy
+	}))
+	close(release)
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+}
@@ -0,0 +1,41 @@
+package detective
+
+import (
+	"context"
+)
+
+// cassandraQuery is a trivial query, which every Cassandra and ScyllaDB node can answer from its local system tables
+const cassandraQuery = "SELECT release_version FROM system.local"
+
+// CassandraSession is the minimal interface required to check the health of a Cassandra or ScyllaDB cluster. The Scan method runs a query that returns a single row, and copies its columns into dest. Most CQL drivers can be adapted to this interface with a small wrapper, or with the CassandraSessionFunc type.
+type CassandraSession interface {
+	Scan(ctx context.Context, stmt string, dest ...interface{}) error
+}
+
+// The CassandraSessionFunc type is an adapter to allow the use of ordinary functions as a CassandraSession
+type CassandraSessionFunc func(ctx context.Context, stmt string, dest ...interface{}) error
+
+// Scan calls f(ctx, stmt, dest...)
+func (f CassandraSessionFunc) Scan(ctx context.Context, stmt string, dest ...interface{}) error {
+	return f(ctx, stmt, dest...)
+}
+
+// DetectCassandra registers a detector function that queries the release version of a Cassandra or ScyllaDB node from the system.local table. The round trip time of the query is reported as the latency of the dependency, and the release version in the details of its state. The query is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+//
+// For example, with the github.com/gocql/gocql driver:
+//
+//	d.Dependency("cassandra").DetectCassandra(detective.CassandraSessionFunc(func(ctx context.Context, stmt string, dest ...interface{}) error {
+//		return session.Query(stmt).WithContext(ctx).Scan(dest...)
+//	}))
+func (d *Dependency) DetectCassandra(s CassandraSession) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		var version string
+		if err := s.Scan(ctx, cassandraQuery, &version); err != nil {
+			return err
+		}
+		SetDetail(ctx, "release_version", version)
+		return nil
+	})
+}
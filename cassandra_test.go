@@ -0,0 +1,33 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDetectCassandra(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var hasDeadline bool
+		d := newDependency("cassandra")
+		d.DetectCassandra(CassandraSessionFunc(func(ctx context.Context, stmt string, dest ...interface{}) error {
+			_, hasDeadline = ctx.Deadline()
+			assert.Equal(t, "SELECT release_version FROM system.local", stmt)
+			*dest[0].(*string) = "4.1.3"
+			return nil
+		}))
+		st := d.getState(context.Background())
+		assertStatesEqual(t, State{Name: "cassandra", Ok: true, Status: "Ok"}, st)
+		assert.True(t, hasDeadline, "query should have a deadline")
+		assert.Equal(t, map[string]interface{}{"release_version": "4.1.3"}, st.Details)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		d := newDependency("cassandra")
+		d.DetectCassandra(CassandraSessionFunc(func(ctx context.Context, stmt string, dest ...interface{}) error {
+			return errors.New("gocql: no hosts available in the pool")
+		}))
+		assertStatesEqual(t, State{Name: "cassandra", Ok: false, Status: "Error: gocql: no hosts available in the pool"}, d.getState(context.Background()))
+	})
+}
@@ -0,0 +1,74 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// A Checker checks the health of a dependency, and describes it with a Result. Implement it to report more information than a detector function can, like a degraded health, or details about the dependency.
+type Checker interface {
+	Check(ctx context.Context) Result
+}
+
+// CheckerFunc is an adapter to allow the use of an ordinary function as a Checker
+type CheckerFunc func(ctx context.Context) Result
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) Result {
+	return f(ctx)
+}
+
+// Result describes the outcome of a check
+type Result struct {
+	// Health is the health of the dependency. If it is empty, the dependency is unhealthy if Err is set (or degraded, if Err was wrapped with Degrade), and healthy otherwise.
+	Health Health
+	// Err describes why the dependency is not healthy
+	Err error
+	// Latency is reported as the latency of the dependency, if it is set. Otherwise, the duration of the check is reported.
+	Latency time.Duration
+	// Details contains additional information about the dependency, which is included in its state. The values should be serializable as JSON.
+	Details map[string]interface{}
+}
+
+func (r Result) health() Health {
+	switch {
+	case r.Health != "":
+		return r.Health
+	case r.Err == nil:
+		return Healthy
+	case isDegraded(r.Err):
+		return Degraded
+	}
+	return Unhealthy
+}
+
+// mergeDetails adds the details of the result to the details reported with SetDetail. The details of the result take precedence.
+func (r Result) mergeDetails(details map[string]interface{}) map[string]interface{} {
+	if len(r.Details) == 0 {
+		return details
+	}
+	if details == nil {
+		details = make(map[string]interface{}, len(r.Details))
+	}
+	for k, v := range r.Details {
+		details[k] = v
+	}
+	return details
+}
+
+func (s State) withResult(r Result) State {
+	switch r.health() {
+	case Healthy:
+		return s.withOk()
+	case Degraded:
+		if r.Err == nil {
+			return s.withDegraded(errors.New("degraded"))
+		}
+		return s.withDegraded(r.Err)
+	}
+	if r.Err == nil {
+		return s.withError(errors.New("unhealthy"))
+	}
+	return s.withError(r.Err)
+}
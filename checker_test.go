@@ -0,0 +1,58 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDetectChecker(t *testing.T) {
+	tests := []struct {
+		name          string
+		result        Result
+		expectedState State
+	}{
+		{"healthy", Result{}, State{Name: "sample", Ok: true, Status: "Ok"}},
+		{"error", Result{Err: errors.New("failed")}, State{Name: "sample", Ok: false, Status: "Error: failed"}},
+		{"degraded error", Result{Err: Degrade(errors.New("slow"))}, State{Name: "sample", Ok: true, Status: "Degraded: slow"}},
+		{"degraded health", Result{Health: Degraded, Err: errors.New("slow")}, State{Name: "sample", Ok: true, Status: "Degraded: slow"}},
+		{"degraded without error", Result{Health: Degraded}, State{Name: "sample", Ok: true, Status: "Degraded: degraded"}},
+		{"unhealthy without error", Result{Health: Unhealthy}, State{Name: "sample", Ok: false, Status: "Error: unhealthy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("sample")
+			d.DetectChecker(CheckerFunc(func(ctx context.Context) Result {
+				return tt.result
+			}))
+			assertStatesEqual(t, tt.expectedState, d.getState(context.Background()))
+		})
+	}
+}
+
+func TestDetectCheckerLatencyAndDetails(t *testing.T) {
+	d := newDependency("sample")
+	d.DetectChecker(CheckerFunc(func(ctx context.Context) Result {
+		SetDetail(ctx, "version", "1.0")
+		SetDetail(ctx, "connections", 1)
+		return Result{Latency: 42 * time.Millisecond, Details: map[string]interface{}{"connections": 3}}
+	}))
+	s := d.getState(context.Background())
+	assert.Equal(t, 42*time.Millisecond, s.Latency)
+	assert.Equal(t, 42.0, s.LatencyMs)
+	assert.Equal(t, map[string]interface{}{"version": "1.0", "connections": 3}, s.Details)
+}
+
+func TestDetectCheckerTimeout(t *testing.T) {
+	d := newDependency("sample").WithTimeout(time.Millisecond)
+	d.DetectChecker(CheckerFunc(func(ctx context.Context) Result {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return Result{}
+	}))
+	s := d.getState(context.Background())
+	assert.Equal(t, Unhealthy, s.Health)
+	assert.Equal(t, context.DeadlineExceeded.Error(), s.Error)
+}
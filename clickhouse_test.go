@@ -0,0 +1,30 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDetectClickHouse(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ClickHouse-User") != "reader" || r.Header.Get("X-ClickHouse-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("Code: 516. DB::Exception: reader: Authentication failed. (AUTHENTICATION_FAILED)\n"))
+			return
+		}
+		assert.Equal(t, "SELECT 1", r.URL.Query().Get("query"))
+		w.Write([]byte("1\n"))
+	}))
+	defer s.Close()
+
+	d := newDependency("clickhouse")
+	d.DetectClickHouse(strings.Replace(s.URL, "http://", "http://reader:secret@", 1)+"/", ClickHouseConfig{})
+	assertStatesEqual(t, State{Name: "clickhouse", Ok: true, Status: "Ok"}, d.getState(context.Background()))
+
+	d.DetectClickHouse(strings.Replace(s.URL, "http://", "http://reader:wrong@", 1), ClickHouseConfig{})
+	assertStatesEqual(t, State{Name: "clickhouse", Ok: false, Status: "Error: clickhouse returned http status 401: Code: 516. DB::Exception: reader: Authentication failed. (AUTHENTICATION_FAILED)"}, d.getState(context.Background()))
+}
@@ -0,0 +1,53 @@
+// Command detective queries the health of detective instances from the command line, and serves standalone instances described by a configuration file.
+//
+//	detective query [-watch] [-interval 2s] [-H "Authorization: Bearer token"] http://localhost:8080/
+//	detective serve -config checks.yaml [-listen :8080] [-reload-interval 10s]
+//
+// The query command prints the state tree of the instance, and exits with a non-zero status if the instance is unhealthy, or cannot be reached, so that it can be used in deploy scripts and smoke tests.
+// The serve command runs a health aggregator built from the configuration (see the config package), which is reloaded on SIGHUP. It serves the state of the instance at /, its Prometheus metrics at /metrics, and its status page at /status.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: detective <command> [flags]
+
+commands:
+  query    print the state of a detective instance, and exit with a non-zero status if it is unhealthy
+  serve    serve a detective instance built from a configuration file
+`
+
+// Exit statuses of the commands
+const (
+	exitHealthy   = 0
+	exitUnhealthy = 1
+	exitUsage     = 2
+)
+
+var commands = map[string]func(args []string, stdout, stderr io.Writer) int{
+	"query": query,
+	"serve": serve,
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if args[0] != "-h" && args[0] != "-help" && args[0] != "help" {
+			fmt.Fprintf(stderr, "detective: unknown command %q\n", args[0])
+		}
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	return cmd(args[1:], stdout, stderr)
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
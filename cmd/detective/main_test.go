@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/sohamkamani/detective"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRenderTree(t *testing.T) {
+	s := detective.State{Name: "app", Health: detective.Unhealthy, Error: "dependency failure", Latency: 12 * time.Millisecond, Dependencies: []detective.State{
+		{Name: "db", Health: detective.Healthy, Latency: 3 * time.Millisecond},
+		{Name: "service", Health: detective.Unhealthy, Error: "dependency failure", Dependencies: []detective.State{
+			{Name: "cache", Health: detective.Unhealthy, Error: "connection refused", NonCritical: true},
+			{Name: "queue", Health: detective.Healthy, Disabled: true},
+		}},
+		{Name: "search", Health: detective.Degraded, Error: "degraded dependency"},
+	}}
+	expected := `✗ app (12ms) dependency failure
+├── ✓ db (3ms)
+├── ✗ service (0s) dependency failure
+│   ├── ✗ cache (0s) [non-critical] connection refused
+│   └── - queue (0s) disabled
+└── ! search (0s) degraded dependency
+`
+	assert.Equal(t, expected, renderTree(s, false))
+	assert.Equal(t, colorRed+"✗ app (12ms) dependency failure"+colorReset+"\n", renderTree(detective.State{Name: "app", Health: detective.Unhealthy, Error: "dependency failure", Latency: 12 * time.Millisecond}, true))
+}
+
+func TestQuery(t *testing.T) {
+	healthy := detective.New("healthy")
+	healthy.Dependency("db").Detect(func() error {
+		return nil
+	})
+	unhealthy := detective.New("unhealthy")
+	unhealthy.Dependency("db").Detect(func() error {
+		return errors.New("failed")
+	})
+	degraded := detective.New("degraded")
+	degraded.Dependency("db").NonCritical().Detect(func() error {
+		return errors.New("failed")
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/healthy", healthy)
+	mux.Handle("/unhealthy", unhealthy)
+	mux.Handle("/degraded", degraded)
+	mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		healthy.ServeHTTP(w, r)
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+		stdout string
+		stderr string
+	}{
+		{name: "healthy", args: []string{"query", s.URL + "/healthy"}, status: exitHealthy, stdout: "✓ healthy"},
+		{name: "unhealthy", args: []string{"query", s.URL + "/unhealthy"}, status: exitUnhealthy, stdout: "└── ✗ db"},
+		{name: "degraded", args: []string{"query", s.URL + "/degraded"}, status: exitHealthy, stdout: "! degraded"},
+		{name: "fail degraded", args: []string{"query", "-fail-degraded", s.URL + "/degraded"}, status: exitUnhealthy, stdout: "! degraded"},
+		{name: "headers", args: []string{"query", "-H", "Authorization: Bearer token", s.URL + "/headers"}, status: exitHealthy, stdout: "✓ healthy"},
+		{name: "not a detective instance", args: []string{"query", s.URL + "/missing"}, status: exitUnhealthy, stderr: "returned http status 404 Not Found"},
+		{name: "unreachable", args: []string{"query", "-timeout", "100ms", "http://127.0.0.1:1/"}, status: exitUnhealthy, stderr: "detective: "},
+		{name: "missing url", args: []string{"query"}, status: exitUsage, stderr: "usage: detective query"},
+		{name: "invalid header", args: []string{"query", "-H", "invalid", s.URL}, status: exitUsage, stderr: `formatted as "Key: Value"`},
+		{name: "no command", args: nil, status: exitUsage, stderr: "usage: detective <command>"},
+		{name: "unknown command", args: []string{"unknown"}, status: exitUsage, stderr: `unknown command "unknown"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tt.status, run(tt.args, &stdout, &stderr))
+			assert.Contains(t, stdout.String(), tt.stdout)
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/sohamkamani/detective"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// headers collects the repeated -H flags
+type headers http.Header
+
+func (h headers) String() string {
+	return ""
+}
+
+func (h headers) Set(value string) error {
+	key, val, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(key) == "" {
+		return errors.New(`headers should be formatted as "Key: Value"`)
+	}
+	http.Header(h).Add(strings.TrimSpace(key), strings.TrimSpace(val))
+	return nil
+}
+
+func query(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	watch := fs.Bool("watch", false, "refresh the state until interrupted")
+	interval := fs.Duration("interval", 2*time.Second, "refresh interval of -watch")
+	timeout := fs.Duration("timeout", 10*time.Second, "maximum duration of each request")
+	failDegraded := fs.Bool("fail-degraded", false, "exit with a non-zero status if the instance is degraded")
+	noColor := fs.Bool("no-color", false, "disable colors, which are also disabled if NO_COLOR is set, or if the output is not a terminal")
+	h := headers{}
+	fs.Var(h, "H", `header to send with the request, formatted as "Key: Value" (can be repeated)`)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: detective query [flags] <url>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	url := fs.Arg(0)
+	client := &http.Client{Timeout: *timeout}
+	colors := !*noColor && useColors(stdout)
+
+	check := func() int {
+		s, err := fetchState(client, url, http.Header(h))
+		if err != nil {
+			fmt.Fprintf(stderr, "detective: %v\n", err)
+			return exitUnhealthy
+		}
+		io.WriteString(stdout, renderTree(s, colors))
+		return exitStatus(s, *failDegraded)
+	}
+	if !*watch {
+		return check()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		if colors {
+			// Clear the screen, so that the tree is redrawn in place
+			io.WriteString(stdout, "\033[H\033[2J")
+		}
+		fmt.Fprintf(stdout, "%s  %s\n\n", url, time.Now().Format(time.TimeOnly))
+		status := check()
+		select {
+		case <-ctx.Done():
+			return status
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchState requests the state of the instance. Unhealthy instances respond with an error status, along with their state.
+func fetchState(client *http.Client, url string, h http.Header) (detective.State, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return detective.State{}, err
+	}
+	for key, values := range h {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return detective.State{}, err
+	}
+	defer res.Body.Close()
+	var s detective.State
+	if err := json.NewDecoder(res.Body).Decode(&s); err != nil || s.Name == "" {
+		return detective.State{}, fmt.Errorf("%s returned http status %s, without the state of a detective instance", url, res.Status)
+	}
+	return s, nil
+}
+
+func health(s detective.State) detective.Health {
+	if s.Health != "" {
+		return s.Health
+	}
+	if s.Ok {
+		return detective.Healthy
+	}
+	return detective.Unhealthy
+}
+
+func exitStatus(s detective.State, failDegraded bool) int {
+	switch health(s) {
+	case detective.Unhealthy:
+		return exitUnhealthy
+	case detective.Degraded:
+		if failDegraded {
+			return exitUnhealthy
+		}
+	}
+	return exitHealthy
+}
+
+// useColors returns true if the output is a terminal, and colors have not been disabled with NO_COLOR (see https://no-color.org)
+func useColors(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"github.com/sohamkamani/detective"
+	"strings"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorGray   = "\033[90m"
+)
+
+// renderTree describes the state, and all of its dependencies on one line each, as a tree
+func renderTree(s detective.State, colors bool) string {
+	var b strings.Builder
+	b.WriteString(renderLine(s, colors) + "\n")
+	renderDependencies(&b, s.Dependencies, "", colors)
+	return b.String()
+}
+
+func renderDependencies(b *strings.Builder, deps []detective.State, prefix string, colors bool) {
+	for i, dep := range deps {
+		branch, indent := "├── ", "│   "
+		if i == len(deps)-1 {
+			branch, indent = "└── ", "    "
+		}
+		b.WriteString(prefix + branch + renderLine(dep, colors) + "\n")
+		renderDependencies(b, dep.Dependencies, prefix+indent, colors)
+	}
+}
+
+func renderLine(s detective.State, colors bool) string {
+	symbol, color := "✓", colorGreen
+	switch {
+	case s.Disabled:
+		symbol, color = "-", colorGray
+	case health(s) == detective.Unhealthy:
+		symbol, color = "✗", colorRed
+	case health(s) == detective.Degraded:
+		symbol, color = "!", colorYellow
+	}
+	line := fmt.Sprintf("%s %s (%s)", symbol, s.Name, s.Latency)
+	if s.Disabled {
+		line += " disabled"
+	}
+	if s.NonCritical {
+		line += " [non-critical]"
+	}
+	if s.Error != "" {
+		line += " " + s.Error
+	}
+	if colors {
+		return color + line + colorReset
+	}
+	return line
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/sohamkamani/detective"
+	"github.com/sohamkamani/detective/config"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func serve(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "path of the YAML or JSON configuration of the checks (required)")
+	listen := fs.String("listen", ":8080", "address to listen on")
+	reloadInterval := fs.Duration("reload-interval", 0, "reload the configuration when the file changes, checking it once every interval (the configuration is always reloaded on SIGHUP)")
+	verbose := fs.Bool("v", false, "log the progress of every check")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: detective serve -config <file> [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *configPath == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+	r, err := config.NewReloader(*configPath, detective.WithLogger(detective.SlogLogger(logger)))
+	if err != nil {
+		fmt.Fprintf(stderr, "detective: %v\n", err)
+		return exitUnhealthy
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	r.OnError(func(err error) {
+		logger.Error("could not reload the configuration", "path", *configPath, "error", err)
+	})
+	r.WatchSignal(ctx)
+	if *reloadInterval > 0 {
+		r.WatchFile(ctx, *reloadInterval)
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(stderr, "detective: %v\n", err)
+		return exitUnhealthy
+	}
+	logger.Info("serving the health of " + r.Detective().GetState().Name + " on http://" + l.Addr().String() + "/")
+	if err := serveUntilDone(ctx, l, serveHandler(r)); err != nil {
+		fmt.Fprintf(stderr, "detective: %v\n", err)
+		return exitUnhealthy
+	}
+	r.Detective().Stop()
+	return exitHealthy
+}
+
+// serveHandler serves the state of the current instance of the reloader, along with its metrics and status page
+func serveHandler(r *config.Reloader) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", r)
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
+		r.Detective().MetricsHandler().ServeHTTP(w, req)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, req *http.Request) {
+		r.Detective().DashboardHandler().ServeHTTP(w, req)
+	})
+	return mux
+}
+
+// serveUntilDone serves HTTP requests until the context is done, and then waits for the requests in flight to complete
+func serveUntilDone(ctx context.Context, l net.Listener, h http.Handler) error {
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(l)
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
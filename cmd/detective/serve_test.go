@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/sohamkamani/detective/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServeHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checks.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("name: aggregator\nchecks:\n  - {name: tmp, type: disk, path: "+os.TempDir()+"}\n"), 0600))
+	r, err := config.NewReloader(path)
+	require.NoError(t, err)
+	h := serveHandler(r)
+
+	tests := []struct {
+		path     string
+		contains string
+	}{
+		{path: "/", contains: `"name":"aggregator"`},
+		{path: "/metrics", contains: `detective_dependency_up{detective="aggregator",dependency="tmp"} 1`},
+		{path: "/status", contains: "<html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, http.StatusOK, rw.Code)
+			assert.Contains(t, rw.Body.String(), tt.contains)
+		})
+	}
+}
+
+func TestServeUntilDone(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveUntilDone(ctx, l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}))
+	}()
+
+	res, err := http.Get("http://" + l.Addr().String() + "/")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusTeapot, res.StatusCode)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the server was not shut down")
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+		stderr string
+	}{
+		{name: "missing config", args: []string{"serve"}, status: exitUsage, stderr: "usage: detective serve"},
+		{name: "invalid config", args: []string{"serve", "-config", filepath.Join(t.TempDir(), "missing.yaml")}, status: exitUnhealthy, stderr: "missing.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tt.status, run(tt.args, &stdout, &stderr))
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
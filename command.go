@@ -0,0 +1,71 @@
+package detective
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maxCommandOutput bounds the number of bytes of the output of a command that are kept
+const maxCommandOutput = 4096
+
+// DetectCommand registers a detector function that runs a command, like an existing check script, with the provided arguments. The dependency is healthy if the command exits with a zero status. The trimmed standard output of a successful command is reported in the details of the state of the dependency, and the error of a failing command includes its trimmed standard error (or standard output, if it did not write to standard error), so that it is hidden by WithRedactedErrors.
+// The command is not run in a shell, and is killed if it does not exit within the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectCommand(name string, args ...string) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, name, args...)
+		stdout := &limitedBuffer{max: maxCommandOutput}
+		stderr := &limitedBuffer{max: maxCommandOutput}
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		// Children of the command may keep its output open after it is killed
+		cmd.WaitDelay = time.Second
+		err := cmd.Run()
+		output := strings.TrimSpace(stdout.String())
+		if err == nil {
+			if output != "" {
+				SetDetail(ctx, "output", output)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = output
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.Exited() {
+			err = fmt.Errorf("command exited with status %d", exitErr.ExitCode())
+		}
+		if msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	})
+}
+
+// limitedBuffer is a buffer that discards writes beyond its maximum size, without failing them
+type limitedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.max - b.buf.Len(); remaining < len(p) {
+		b.buf.Write(p[:max(remaining, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
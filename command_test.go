@@ -0,0 +1,73 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test commands require a POSIX shell")
+	}
+	tests := []struct {
+		name    string
+		script  string
+		status  string
+		details map[string]interface{}
+	}{
+		{"success", "echo '  replication lag: 2s  '", "Ok", map[string]interface{}{"output": "replication lag: 2s"}},
+		{"silent success", "true", "Ok", nil},
+		{"failure with stderr", "echo checking; echo 'replica is down' >&2; exit 2", "Error: command exited with status 2: replica is down", nil},
+		{"failure with stdout", "echo 'CRITICAL: disk full'; exit 1", "Error: command exited with status 1: CRITICAL: disk full", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("script")
+			d.DetectCommand("sh", "-c", tt.script)
+			st := d.getState(context.Background())
+			assert.Equal(t, tt.status, st.Status)
+			assert.Equal(t, tt.details, st.Details)
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		d := newDependency("script").WithTimeout(50 * time.Millisecond)
+		d.DetectCommand("sleep", "10")
+		start := time.Now()
+		assert.Equal(t, "Error: context deadline exceeded", d.getState(context.Background()).Status)
+		assert.True(t, time.Since(start) < 5*time.Second, "the command should be killed at the timeout")
+	})
+
+	t.Run("missing command", func(t *testing.T) {
+		d := newDependency("script")
+		d.DetectCommand("./does-not-exist")
+		assert.Contains(t, d.getState(context.Background()).Status, "does-not-exist")
+	})
+
+	t.Run("large output", func(t *testing.T) {
+		d := newDependency("script")
+		d.DetectCommand("sh", "-c", "yes | head -c 100000")
+		st := d.getState(context.Background())
+		assert.True(t, st.Ok, st.Error)
+		assert.True(t, len(st.Details["output"].(string)) <= maxCommandOutput, "the output should be truncated")
+		assert.True(t, strings.HasPrefix(st.Details["output"].(string), "y\ny"))
+	})
+}
+
+func TestDetectCommandRedactedErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test commands require a POSIX shell")
+	}
+	d := New("sample", WithRedactedErrors())
+	d.Dependency("script").DetectCommand("sh", "-c", "echo 'connecting to postgres://admin:secret@db failed'; exit 1")
+	rw := httptest.NewRecorder()
+	d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.NotContains(t, rw.Body.String(), "secret")
+}
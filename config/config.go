@@ -0,0 +1,281 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/sohamkamani/detective"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"time"
+)
+
+// Config describes a Detective instance, along with its dependencies and endpoints
+type Config struct {
+	// Name is the name of the Detective instance
+	Name string `yaml:"name"`
+	// Timeout is the maximum duration for checking the health of all dependencies and endpoints (for example, "5s")
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxConcurrentChecks limits the number of dependencies and endpoints that are checked at the same time
+	MaxConcurrentChecks int `yaml:"max_concurrent_checks"`
+	// Interval starts checking the dependencies in the background with Detective.StartPeriodic, once every interval
+	Interval time.Duration `yaml:"interval"`
+	// Endpoints are the HTTP endpoints of other Detective instances, or of plain HTTP services
+	Endpoints []Endpoint `yaml:"endpoints"`
+	// Checks are the dependencies of the instance
+	Checks []Check `yaml:"checks"`
+}
+
+// Endpoint describes an endpoint registered with Detective.EndpointWithOptions
+type Endpoint struct {
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// ExpectStatus treats the endpoint as a plain HTTP service, which is healthy if it responds with one of the status codes
+	ExpectStatus []int `yaml:"expect_status"`
+	// ExpectBodyContains treats the endpoint as a plain HTTP service, which is healthy if its response body contains the substring
+	ExpectBodyContains string `yaml:"expect_body_contains"`
+	// Mode is "raw" to treat the endpoint as a plain HTTP service, or "peer" to treat it as another Detective instance. By default, the mode is inferred from the expectations.
+	Mode string `yaml:"mode"`
+}
+
+// Check describes a dependency. The type of the check selects the built-in detector, and the fields it uses:
+//
+//	tcp:  address
+//	sql:  driver, dsn, query
+//	dns:  host, resolver, max_latency
+//	tls:  address, expiry_window, degrade
+//	disk: path, min_free_bytes, min_free_percent
+type Check struct {
+	Name        string        `yaml:"name"`
+	Type        string        `yaml:"type"`
+	Timeout     time.Duration `yaml:"timeout"`
+	NonCritical bool          `yaml:"non_critical"`
+	Retries     int           `yaml:"retries"`
+	RetryDelay  time.Duration `yaml:"retry_delay"`
+	// Metadata describes the dependency, like its owner team or runbook URL (see Dependency.WithMetadata)
+	Metadata map[string]string `yaml:"metadata"`
+
+	Address string `yaml:"address"`
+
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+	Query  string `yaml:"query"`
+
+	Host       string        `yaml:"host"`
+	Resolver   string        `yaml:"resolver"`
+	MaxLatency time.Duration `yaml:"max_latency"`
+
+	ExpiryWindow time.Duration `yaml:"expiry_window"`
+	Degrade      bool          `yaml:"degrade"`
+
+	Path           string  `yaml:"path"`
+	MinFreeBytes   uint64  `yaml:"min_free_bytes"`
+	MinFreePercent float64 `yaml:"min_free_percent"`
+}
+
+// Parse decodes a YAML or JSON configuration. Unknown fields are reported as errors, so that misspelled options are not silently ignored.
+func Parse(data []byte) (*Config, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	c := &Config{}
+	if err := dec.Decode(c); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load reads the configuration file at the provided path, and builds a Detective instance from it. The options are applied after the ones of the configuration.
+func Load(path string, opts ...detective.Option) (*detective.Detective, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c.Build(opts...)
+}
+
+// Validate checks that the configuration describes a valid instance
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.Interval < 0 {
+		return errors.New("interval must be positive")
+	}
+	for i, e := range c.Endpoints {
+		if e.URL == "" {
+			return fmt.Errorf("endpoints[%d]: url is required", i)
+		}
+		if e.Mode != "" && e.Mode != "raw" && e.Mode != "peer" {
+			return fmt.Errorf("endpoints[%d]: unknown mode %q", i, e.Mode)
+		}
+	}
+	names := map[string]bool{}
+	for i, ch := range c.Checks {
+		if ch.Name == "" {
+			return fmt.Errorf("checks[%d]: name is required", i)
+		}
+		if names[ch.Name] {
+			return fmt.Errorf("checks[%d]: duplicate name %q", i, ch.Name)
+		}
+		names[ch.Name] = true
+		if err := ch.validate(); err != nil {
+			return fmt.Errorf("checks[%d] %s: %w", i, ch.Name, err)
+		}
+	}
+	return nil
+}
+
+func (ch Check) validate() error {
+	required := func(field, value string) error {
+		if value == "" {
+			return fmt.Errorf("%s is required for %s checks", field, ch.Type)
+		}
+		return nil
+	}
+	switch ch.Type {
+	case "tcp", "tls":
+		return required("address", ch.Address)
+	case "sql":
+		if err := required("driver", ch.Driver); err != nil {
+			return err
+		}
+		if err := required("dsn", ch.DSN); err != nil {
+			return err
+		}
+		for _, driver := range sql.Drivers() {
+			if driver == ch.Driver {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown sql driver %q (the driver package has to be imported by the application)", ch.Driver)
+	case "dns":
+		return required("host", ch.Host)
+	case "disk":
+		return required("path", ch.Path)
+	case "":
+		return errors.New("type is required")
+	default:
+		return fmt.Errorf("unknown type %q", ch.Type)
+	}
+}
+
+// Build creates a Detective instance from the configuration. The options are applied after the ones of the configuration.
+func (c *Config) Build(opts ...detective.Option) (*detective.Detective, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	var options []detective.Option
+	if c.Timeout > 0 {
+		options = append(options, detective.WithTimeout(c.Timeout))
+	}
+	if c.MaxConcurrentChecks > 0 {
+		options = append(options, detective.WithMaxConcurrentChecks(c.MaxConcurrentChecks))
+	}
+	d := detective.New(c.Name, append(options, opts...)...)
+
+	for _, e := range c.Endpoints {
+		if err := d.EndpointWithOptions(e.URL, e.options()...); err != nil {
+			return nil, err
+		}
+	}
+	for _, ch := range c.Checks {
+		ch.register(d.Dependency(ch.Name))
+	}
+	if c.Interval > 0 {
+		if err := d.StartPeriodic(c.Interval); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (e Endpoint) options() []detective.EndpointOption {
+	var opts []detective.EndpointOption
+	if e.Method != "" {
+		opts = append(opts, detective.Method(e.Method))
+	}
+	for key, value := range e.Headers {
+		opts = append(opts, detective.Header(key, value))
+	}
+	if e.Body != "" {
+		opts = append(opts, detective.Body([]byte(e.Body)))
+	}
+	if len(e.ExpectStatus) > 0 {
+		opts = append(opts, detective.ExpectStatus(e.ExpectStatus...))
+	}
+	if e.ExpectBodyContains != "" {
+		opts = append(opts, detective.ExpectBodyContains(e.ExpectBodyContains))
+	}
+	switch e.Mode {
+	case "raw":
+		opts = append(opts, detective.Raw())
+	case "peer":
+		opts = append(opts, detective.Peer())
+	}
+	return opts
+}
+
+func (ch Check) register(dep *detective.Dependency) {
+	if ch.Timeout > 0 {
+		dep.WithTimeout(ch.Timeout)
+	}
+	if ch.NonCritical {
+		dep.NonCritical()
+	}
+	for key, value := range ch.Metadata {
+		dep.WithMetadata(key, value)
+	}
+	if ch.Retries > 0 {
+		dep.WithRetry(detective.RetryPolicy{Attempts: ch.Retries + 1, Backoff: ch.RetryDelay})
+	}
+	switch ch.Type {
+	case "tcp":
+		dep.DetectTCP(ch.Address)
+	case "sql":
+		dep.DetectContext(ch.detectSQL)
+	case "dns":
+		dep.DetectDNSConfig(ch.Host, detective.DNSConfig{Resolver: ch.Resolver, MaxLatency: ch.MaxLatency})
+	case "tls":
+		dep.DetectTLSCertificate(ch.Address, detective.TLSCertificateConfig{ExpiryWindow: ch.ExpiryWindow, Degrade: ch.Degrade})
+	case "disk":
+		dep.DetectDiskSpace(ch.Path, detective.DiskSpaceConfig{MinFreeBytes: ch.MinFreeBytes, MinFreePercent: ch.MinFreePercent})
+	}
+}
+
+// detectSQL opens a new connection for every check, so that instances built from configurations that are discarded do not leak connection pools
+func (ch Check) detectSQL(ctx context.Context) error {
+	db, err := sql.Open(ch.Driver, ch.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+	if err := db.PingContext(ctx); err != nil {
+		return err
+	}
+	if ch.Query == "" {
+		return nil
+	}
+	rows, err := db.QueryContext(ctx, ch.Query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return rows.Err()
+}
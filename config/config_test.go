@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/sohamkamani/detective"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeDriver is an SQL driver whose connections fail to ping when the DSN is "ping-error"
+type fakeDriver struct{}
+
+type fakeConn struct {
+	dsn string
+}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{dsn: dsn}, nil
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	if c.dsn == "ping-error" {
+		return errors.New("ping failed")
+	}
+	return nil
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func init() {
+	sql.Register("config-fake", fakeDriver{})
+}
+
+const sampleYAML = `
+name: application
+timeout: 2s
+max_concurrent_checks: 2
+endpoints:
+  - url: http://localhost:8081/health
+    method: POST
+    headers:
+      Authorization: Bearer token
+    expect_status: [200, 204]
+checks:
+  - name: database
+    type: sql
+    driver: config-fake
+    dsn: ok
+    query: SELECT 1
+    timeout: 500ms
+    retries: 2
+    retry_delay: 10ms
+  - name: cache
+    type: tcp
+    address: localhost:6379
+    non_critical: true
+`
+
+const sampleJSON = `{
+	"name": "application",
+	"timeout": "2s",
+	"max_concurrent_checks": 2,
+	"endpoints": [{"url": "http://localhost:8081/health", "method": "POST", "headers": {"Authorization": "Bearer token"}, "expect_status": [200, 204]}],
+	"checks": [
+		{"name": "database", "type": "sql", "driver": "config-fake", "dsn": "ok", "query": "SELECT 1", "timeout": "500ms", "retries": 2, "retry_delay": "10ms"},
+		{"name": "cache", "type": "tcp", "address": "localhost:6379", "non_critical": true}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	expected := &Config{
+		Name:                "application",
+		Timeout:             2 * time.Second,
+		MaxConcurrentChecks: 2,
+		Endpoints: []Endpoint{{
+			URL:          "http://localhost:8081/health",
+			Method:       "POST",
+			Headers:      map[string]string{"Authorization": "Bearer token"},
+			ExpectStatus: []int{200, 204},
+		}},
+		Checks: []Check{
+			{Name: "database", Type: "sql", Driver: "config-fake", DSN: "ok", Query: "SELECT 1", Timeout: 500 * time.Millisecond, Retries: 2, RetryDelay: 10 * time.Millisecond},
+			{Name: "cache", Type: "tcp", Address: "localhost:6379", NonCritical: true},
+		},
+	}
+	for name, data := range map[string]string{"yaml": sampleYAML, "json": sampleJSON} {
+		t.Run(name, func(t *testing.T) {
+			c, err := Parse([]byte(data))
+			require.NoError(t, err)
+			assert.Equal(t, expected, c)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{name: "missing name", config: `checks: []`, err: "name is required"},
+		{name: "unknown field", config: "name: app\ntimeuot: 1s", err: "field timeuot not found"},
+		{name: "missing type", config: "name: app\nchecks:\n  - name: db", err: "checks[0] db: type is required"},
+		{name: "unknown type", config: "name: app\nchecks:\n  - name: db\n    type: oracle", err: `unknown type "oracle"`},
+		{name: "missing address", config: "name: app\nchecks:\n  - name: db\n    type: tcp", err: "address is required for tcp checks"},
+		{name: "unknown driver", config: "name: app\nchecks:\n  - name: db\n    type: sql\n    driver: oracle\n    dsn: x", err: `unknown sql driver "oracle"`},
+		{name: "duplicate name", config: "name: app\nchecks:\n  - {name: db, type: dns, host: localhost}\n  - {name: db, type: dns, host: localhost}", err: `duplicate name "db"`},
+		{name: "missing url", config: "name: app\nendpoints:\n  - method: GET", err: "endpoints[0]: url is required"},
+		{name: "unknown mode", config: "name: app\nendpoints:\n  - url: http://localhost:8081/\n    mode: plain", err: `endpoints[0]: unknown mode "plain"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.config))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	path := filepath.Join(t.TempDir(), "detective.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+name: application
+endpoints:
+  - url: `+s.URL+`
+    headers:
+      Authorization: Bearer token
+    expect_status: [204]
+checks:
+  - name: database
+    type: sql
+    driver: config-fake
+    dsn: ping-error
+    non_critical: true
+    metadata:
+      team: payments
+  - name: cache
+    type: tcp
+    address: `+l.Addr().String()+`
+`), 0600))
+
+	d, err := Load(path)
+	require.NoError(t, err)
+	state := d.GetState()
+	assert.Equal(t, "application", state.Name)
+	assert.Equal(t, detective.Degraded, state.Health)
+	require.Len(t, state.Dependencies, 3)
+	assert.Equal(t, "database", state.Dependencies[0].Name)
+	assert.Equal(t, "ping failed", state.Dependencies[0].Error)
+	assert.Equal(t, map[string]string{"team": "payments"}, state.Dependencies[0].Metadata)
+	assert.Equal(t, "cache", state.Dependencies[1].Name)
+	assert.True(t, state.Dependencies[1].Ok)
+	assert.True(t, state.Dependencies[2].Ok)
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
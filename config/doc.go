@@ -0,0 +1,23 @@
+// Package config builds detective instances from a declarative YAML or JSON description of their checks, so that checks can be added without recompiling the application.
+//
+//	name: application
+//	timeout: 5s
+//	endpoints:
+//	  - url: http://localhost:8081/health
+//	checks:
+//	  - name: database
+//	    type: sql
+//	    driver: postgres
+//	    dsn: postgres://localhost/app
+//	    timeout: 2s
+//	  - name: cache
+//	    type: tcp
+//	    address: localhost:6379
+//	    non_critical: true
+//
+// The instance is then created with Load, or with NewReloader to replace it whenever the file changes:
+//
+//	d, err := config.Load("detective.yaml")
+//
+// Since JSON documents are valid YAML, the same fields can be provided as JSON. SQL checks open a connection with the database/sql driver of the check, which has to be imported by the application.
+package config
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"context"
+	"github.com/sohamkamani/detective"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// A Reloader serves the Detective instance built from a configuration file, and replaces it with a new instance whenever the file is reloaded.
+// Requests that are being served while the instance is replaced complete with the previous instance, and the following requests are served by the new one. If a reloaded configuration is invalid, the previous instance keeps being served.
+type Reloader struct {
+	path    string
+	opts    []detective.Option
+	current atomic.Pointer[detective.Detective]
+
+	// mu serializes reloads
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	onError func(error)
+}
+
+// NewReloader loads the configuration file at the provided path, and returns a Reloader serving the instance built from it. The options are applied to every instance built by the Reloader.
+func NewReloader(path string, opts ...detective.Option) (*Reloader, error) {
+	r := &Reloader{path: path, opts: opts}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Detective returns the instance built from the latest valid configuration
+func (r *Reloader) Detective() *detective.Detective {
+	return r.current.Load()
+}
+
+// ServeHTTP serves the health of the current instance
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Detective().ServeHTTP(w, req)
+}
+
+// OnError registers a function that is called when the configuration cannot be reloaded by WatchSignal or WatchFile
+func (r *Reloader) OnError(fn func(error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onError = fn
+}
+
+// Reload reads the configuration file, and atomically replaces the current instance with one built from it. The periodic checks of the previous instance, if any, are stopped. If the configuration cannot be loaded, the error is returned and the current instance is kept.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reload()
+}
+
+func (r *Reloader) reload() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	d, err := Load(r.path, r.opts...)
+	if err != nil {
+		return err
+	}
+	r.modTime, r.size = info.ModTime(), info.Size()
+	if old := r.current.Swap(d); old != nil {
+		old.Stop()
+	}
+	return nil
+}
+
+func (r *Reloader) reportError(err error) {
+	if err != nil && r.onError != nil {
+		r.onError(err)
+	}
+}
+
+// WatchSignal reloads the configuration whenever the process receives one of the signals, or SIGHUP if none are provided, until the context is cancelled
+func (r *Reloader) WatchSignal(ctx context.Context, sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+	go func() {
+		defer signal.Stop(c)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c:
+				r.mu.Lock()
+				r.reportError(r.reload())
+				r.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// WatchFile checks the modification time and size of the configuration file once every interval, and reloads it when either of them changes, until the context is cancelled
+func (r *Reloader) WatchFile(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.mu.Lock()
+				info, err := os.Stat(r.path)
+				if err != nil {
+					r.reportError(err)
+				} else if !info.ModTime().Equal(r.modTime) || info.Size() != r.size {
+					// An invalid file is only reported once, and reloaded when it changes again
+					r.modTime, r.size = info.ModTime(), info.Size()
+					r.reportError(r.reload())
+				}
+				r.mu.Unlock()
+			}
+		}
+	}()
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, path string, checks ...string) {
+	config := "name: application\nchecks:\n"
+	for _, name := range checks {
+		config += "  - {name: " + name + ", type: disk, path: " + os.TempDir() + "}\n"
+	}
+	require.NoError(t, os.WriteFile(path, []byte(config), 0600))
+}
+
+func dependencyNames(r *Reloader) []string {
+	var names []string
+	for _, dep := range r.Detective().GetState().Dependencies {
+		names = append(names, dep.Name)
+	}
+	return names
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "detective.yaml")
+	writeConfig(t, path, "disk")
+	r, err := NewReloader(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"disk"}, dependencyNames(r))
+
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), `"name":"disk"`)
+
+	writeConfig(t, path, "disk", "tmp")
+	require.NoError(t, r.Reload())
+	assert.Equal(t, []string{"disk", "tmp"}, dependencyNames(r))
+
+	require.NoError(t, os.WriteFile(path, []byte("name: application\nchecks:\n  - name: broken"), 0600))
+	assert.Error(t, r.Reload())
+	assert.Equal(t, []string{"disk", "tmp"}, dependencyNames(r))
+}
+
+func TestNewReloaderError(t *testing.T) {
+	_, err := NewReloader(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestReloaderStopsPreviousInstance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "detective.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("name: application\ninterval: 10ms"), 0600))
+	r, err := NewReloader(path)
+	require.NoError(t, err)
+	previous := r.Detective()
+	defer r.Detective().Stop()
+
+	require.NoError(t, r.Reload())
+	assert.True(t, previous != r.Detective(), "reloading should build a new instance")
+}
+
+func TestReloaderWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "detective.yaml")
+	writeConfig(t, path, "disk")
+	r, err := NewReloader(path)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var errs []error
+	r.OnError(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.WatchFile(ctx, 5*time.Millisecond)
+
+	writeConfig(t, path, "disk", "tmp")
+	assert.True(t, waitFor(func() bool {
+		return len(dependencyNames(r)) == 2
+	}))
+
+	require.NoError(t, os.WriteFile(path, []byte("name: application\nchecks:\n  - name: broken"), 0600))
+	assert.True(t, waitFor(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) == 1
+	}))
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	assert.Len(t, errs, 1)
+	mu.Unlock()
+	assert.Equal(t, []string{"disk", "tmp"}, dependencyNames(r))
+}
+
+func TestReloaderWatchSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent to the current process on windows")
+	}
+	path := filepath.Join(t.TempDir(), "detective.yaml")
+	writeConfig(t, path, "disk")
+	r, err := NewReloader(path)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.WatchSignal(ctx)
+
+	writeConfig(t, path, "disk", "tmp")
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, p.Signal(syscall.SIGHUP))
+	assert.True(t, waitFor(func() bool {
+		return len(dependencyNames(r)) == 2
+	}))
+}
+
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
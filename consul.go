@@ -0,0 +1,70 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ConsulConfig configures the detector registered with DetectConsul
+type ConsulConfig struct {
+	// Token is sent as the ACL token of the requests, if set
+	Token string
+	// Client is used to send requests to the Consul agent. If it is nil, http.DefaultClient is used.
+	Client Doer
+}
+
+// DetectConsul registers a detector function that checks the health of a Consul agent, at a URL like "http://localhost:8500". The agent has to respond to the /v1/agent/self endpoint, and the dependency is unhealthy unless the cluster of the agent has elected a leader. The leader, and the datacenter and node name of the agent are reported in the details of the state of the dependency.
+// The requests are bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectConsul(agentURL string, c ConsulConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		base, err := url.Parse(strings.TrimSuffix(agentURL, "/"))
+		if err != nil {
+			return err
+		}
+		get := func(path string, v interface{}) error {
+			req, err := http.NewRequest(http.MethodGet, base.String()+path, nil)
+			if err != nil {
+				return err
+			}
+			if c.Token != "" {
+				req.Header.Set("X-Consul-Token", c.Token)
+			}
+			code, err := doJSON(c.Client, req.WithContext(ctx), v)
+			if err != nil {
+				return err
+			}
+			if code != http.StatusOK {
+				return fmt.Errorf("consul %s returned http status: %d", path, code)
+			}
+			return nil
+		}
+
+		var self struct {
+			Config struct {
+				Datacenter string `json:"Datacenter"`
+				NodeName   string `json:"NodeName"`
+			} `json:"Config"`
+		}
+		if err := get("/v1/agent/self", &self); err != nil {
+			return err
+		}
+		SetDetail(ctx, "datacenter", self.Config.Datacenter)
+		SetDetail(ctx, "node", self.Config.NodeName)
+
+		var leader string
+		if err := get("/v1/status/leader", &leader); err != nil {
+			return err
+		}
+		if leader == "" {
+			return errors.New("consul cluster has no leader")
+		}
+		SetDetail(ctx, "leader", leader)
+		return nil
+	})
+}
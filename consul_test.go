@@ -0,0 +1,51 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectConsul(t *testing.T) {
+	leader := `"10.0.0.1:8300"`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Permission denied"))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/agent/self":
+			w.Write([]byte(`{"Config": {"Datacenter": "dc1", "NodeName": "node-1"}, "Member": {}}`))
+		case "/v1/status/leader":
+			w.Write([]byte(leader))
+		}
+	}))
+	defer s.Close()
+
+	t.Run("leader", func(t *testing.T) {
+		d := newDependency("consul")
+		d.DetectConsul(s.URL, ConsulConfig{Token: "secret"})
+		st := d.getState(context.Background())
+		assertStatesEqual(t, State{Name: "consul", Ok: true, Status: "Ok"}, st)
+		assert.Equal(t, map[string]interface{}{"datacenter": "dc1", "node": "node-1", "leader": "10.0.0.1:8300"}, st.Details)
+	})
+
+	t.Run("no leader", func(t *testing.T) {
+		leader = `""`
+		defer func() {
+			leader = `"10.0.0.1:8300"`
+		}()
+		d := newDependency("consul")
+		d.DetectConsul(s.URL, ConsulConfig{Token: "secret"})
+		assertStatesEqual(t, State{Name: "consul", Ok: false, Status: "Error: consul cluster has no leader"}, d.getState(context.Background()))
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		d := newDependency("consul")
+		d.DetectConsul(s.URL, ConsulConfig{})
+		assertStatesEqual(t, State{Name: "consul", Ok: false, Status: "Error: consul /v1/agent/self returned http status: 403"}, d.getState(context.Background()))
+	})
+}
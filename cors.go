@@ -0,0 +1,72 @@
+package detective
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the Cross-Origin Resource Sharing headers of the HTTP handler, so that status pages hosted on another origin can fetch the state of the instance from the browser
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to fetch the state, like "https://status.example.com". The "*" origin allows all origins.
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in cross-origin requests. If it is empty, GET and HEAD are allowed.
+	AllowedMethods []string
+	// MaxAge is the duration for which browsers can cache the response to a preflight request
+	MaxAge time.Duration
+}
+
+// allowedOrigin returns the value of the Access-Control-Allow-Origin header for the origin, which is empty if the origin is not allowed
+func (c *CORSConfig) allowedOrigin(origin string) string {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+func (c *CORSConfig) allowedMethods() []string {
+	if len(c.AllowedMethods) == 0 {
+		return []string{http.MethodGet, http.MethodHead}
+	}
+	return c.AllowedMethods
+}
+
+// handleCORS adds the CORS headers to the response of a request from an allowed origin, and returns true if the request was a preflight request that has been answered
+func (d *Detective) handleCORS(w http.ResponseWriter, r *http.Request) bool {
+	c := d.cors
+	if c == nil {
+		return false
+	}
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	allowed := ""
+	if origin != "" {
+		allowed = c.allowedOrigin(origin)
+	}
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if allowed == "" {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+	w.Header().Set("Access-Control-Allow-Origin", allowed)
+	if !preflight {
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.allowedMethods(), ", "))
+	// Browsers send If-None-Match when revalidating a cached state
+	w.Header().Set("Access-Control-Allow-Headers", "Accept, If-None-Match")
+	if c.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
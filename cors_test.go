@@ -0,0 +1,56 @@
+package detective
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	d := New("sample", WithCORS(CORSConfig{AllowedOrigins: []string{"https://status.example.com"}, MaxAge: 10 * time.Minute}))
+	d.Dependency("db")
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		code    int
+		origin  string
+		methods string
+	}{
+		{"same origin", http.MethodGet, nil, http.StatusOK, "", ""},
+		{"allowed origin", http.MethodGet, map[string]string{"Origin": "https://status.example.com"}, http.StatusOK, "https://status.example.com", ""},
+		{"other origin", http.MethodGet, map[string]string{"Origin": "https://evil.example.com"}, http.StatusOK, "", ""},
+		{"preflight", http.MethodOptions, map[string]string{"Origin": "https://status.example.com", "Access-Control-Request-Method": "GET"}, http.StatusNoContent, "https://status.example.com", "GET, HEAD"},
+		{"forbidden preflight", http.MethodOptions, map[string]string{"Origin": "https://evil.example.com", "Access-Control-Request-Method": "GET"}, http.StatusForbidden, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			rw := httptest.NewRecorder()
+			d.ServeHTTP(rw, r)
+			assert.Equal(t, tt.code, rw.Code)
+			assert.Equal(t, tt.origin, rw.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.methods, rw.Header().Get("Access-Control-Allow-Methods"))
+			assert.Equal(t, "Origin", rw.Header().Get("Vary"))
+			if tt.code == http.StatusNoContent {
+				assert.Equal(t, "600", rw.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+
+	all := New("sample", WithCORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{http.MethodGet}}))
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://anywhere.example.com")
+	r.Header.Set("Access-Control-Request-Method", "GET")
+	rw := httptest.NewRecorder()
+	all.SummaryHandler().ServeHTTP(rw, r)
+	assert.Equal(t, http.StatusNoContent, rw.Code)
+	assert.Equal(t, "*", rw.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET", rw.Header().Get("Access-Control-Allow-Methods"))
+}
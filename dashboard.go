@@ -0,0 +1,78 @@
+package detective
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"health": effectiveHealth,
+	"timestamp": func(t *time.Time) string {
+		return t.Format("2006-01-02 15:04:05 MST")
+	},
+	"latency": func(s State) string {
+		return s.Latency.Round(100 * time.Microsecond).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.State.Name}} - detective</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292e; }
+ul { list-style: none; padding-left: 1.5em; border-left: 1px solid #e1e4e8; }
+ul.tree { padding-left: 0; border-left: none; }
+li { margin: 0.4em 0; }
+.health { display: inline-block; min-width: 6em; padding: 0.1em 0.5em; border-radius: 3px; color: #fff; text-align: center; font-size: 0.85em; }
+.healthy { background: #28a745; }
+.degraded { background: #dbab09; }
+.unhealthy { background: #d73a49; }
+.latency, .checked { color: #6a737d; font-size: 0.85em; }
+.error { color: #d73a49; font-family: monospace; margin: 0.2em 0 0 7em; }
+li > .checked { margin: 0.2em 0 0 7em; }
+</style>
+</head>
+<body>
+<h1>{{.State.Name}}</h1>
+{{with .State.CheckedAt}}<p class="checked">Checked at {{timestamp .}}</p>{{end}}
+<ul class="tree">{{template "state" .State}}</ul>
+</body>
+</html>
+{{define "state"}}<li>
+<span class="health {{health .}}">{{health .}}</span> <strong>{{.Name}}</strong> <span class="latency">{{latency .}}</span>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{if not .Ok}}{{with .LastSuccessAt}}<div class="checked">Last working at {{timestamp .}}</div>{{end}}{{end}}
+{{if .Dependencies}}<ul>{{range .Dependencies}}{{template "state" .}}{{end}}</ul>{{end}}
+</li>{{end}}`))
+
+// DashboardHandler returns an HTTP handler that renders the state of the Detective instance as an HTML page, showing the tree of dependencies along with their health and latencies. It is meant to be opened in a browser, while the handler of the instance itself continues to serve JSON.
+func (d *Detective) DashboardHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := d.sharedState(r.Context(), nil)
+		if d.redactErrors {
+			s = s.withoutErrors()
+		}
+		body, err := renderDashboard(s)
+		if err != nil {
+			d.log(slog.LevelError, fmt.Sprintf("detective %s: failed to render dashboard: %v", d.name, err), slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(d.statusCode(s))
+		w.Write(body)
+	})
+}
+
+// renderDashboard renders the state as the HTML page of the dashboard
+func renderDashboard(s State) ([]byte, error) {
+	var body bytes.Buffer
+	err := dashboardTemplate.Execute(&body, struct {
+		State State
+	}{s})
+	return body.Bytes(), err
+}
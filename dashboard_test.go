@@ -0,0 +1,35 @@
+package detective
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDashboardHandler(t *testing.T) {
+	d := New("sample")
+	d.Dependency("db").Detect(func() error { return errors.New("<connection refused>") })
+	d.Dependency("cache").NonCritical().Detect(func() error { return nil })
+
+	rw := httptest.NewRecorder()
+	d.DashboardHandler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rw.Header().Get("Content-Type"))
+	body := rw.Body.String()
+	assert.Contains(t, body, "<title>sample - detective</title>")
+	assert.Contains(t, body, `<span class="health unhealthy">unhealthy</span> <strong>db</strong>`)
+	assert.Contains(t, body, `<span class="health healthy">healthy</span> <strong>cache</strong>`)
+	assert.Contains(t, body, `<div class="error">&lt;connection refused&gt;</div>`, "errors should be escaped")
+	assert.Contains(t, body, `<p class="checked">Checked at `)
+}
+
+func TestDashboardHandlerRedactedErrors(t *testing.T) {
+	d := New("sample", WithRedactedErrors())
+	d.Dependency("db").Detect(func() error { return errors.New("connection refused") })
+
+	rw := httptest.NewRecorder()
+	d.DashboardHandler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	assert.NotContains(t, rw.Body.String(), "connection refused")
+}
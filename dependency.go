@@ -1,48 +1,235 @@
 package detective
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 )
 
 // The DetectorFunc type represents the function signature to check the health of a dependency
 type DetectorFunc func() error
 
+// The ContextDetectorFunc type is similar to DetectorFunc, but also receives the context of the current health check. The context is cancelled once the result of the check is no longer required
+type ContextDetectorFunc func(ctx context.Context) error
+
 // The Dependency type represents a detectable unit. The function provided in the Detect method will be called to monitor the state of the dependency
 type Dependency struct {
-	name     string
-	detector DetectorFunc
-	state    State
+	name string
+
+	// mu guards the configuration of the dependency, which can be changed while its state is being checked
+	mu       sync.RWMutex
+	detector Checker
+	timeout  time.Duration
+	// nonCritical dependencies only degrade the state of the Detective instance when they fail
+	nonCritical bool
+	retry       RetryPolicy
+	// liveness dependencies are in-process self checks, which are included in the liveness handler
+	liveness bool
+	// disabled dependencies, and dependencies within a maintenance window are not checked
+	disabled      bool
+	disabledUntil time.Time
+	maintenance   []MaintenanceWindow
+	// metadata describes the dependency, like the team owning it, and is copied to each of its states
+	metadata map[string]string
+
+	tracker   stateTracker
+	successes successTracker
+}
+
+// defaultDetectorTimeout bounds the built-in detectors when neither the dependency, nor the health check have a deadline
+const defaultDetectorTimeout = 5 * time.Second
+
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, defaultDetectorTimeout)
 }
 
-func noopDetectorFunc() DetectorFunc {
-	return func() error {
+func noopDetectorFunc() ContextDetectorFunc {
+	return func(context.Context) error {
 		return nil
 	}
 }
 
+// detectorChecker adapts a detector function to the Checker interface
+func detectorChecker(df ContextDetectorFunc) Checker {
+	return CheckerFunc(func(ctx context.Context) Result {
+		return Result{Err: df(ctx)}
+	})
+}
+
 func newDependency(name string) *Dependency {
 	return &Dependency{
 		name:     name,
-		detector: noopDetectorFunc(),
+		detector: detectorChecker(noopDetectorFunc()),
 	}
 }
 
 // Detect registers a function that will be called to detect the health of a dependency. If the dependency is healthy, a nil value should be returned as the error.
 func (d *Dependency) Detect(df DetectorFunc) {
-	d.detector = df
+	d.DetectContext(func(context.Context) error {
+		return df()
+	})
+}
+
+// DetectContext is similar to Detect, but registers a function that receives the context of the health check. Detector functions that can block for a long time should use this method, and return once the context is done.
+func (d *Dependency) DetectContext(df ContextDetectorFunc) {
+	d.DetectChecker(detectorChecker(df))
+}
+
+// DetectChecker is similar to DetectContext, but registers a Checker, whose result can describe the health of the dependency in more detail than an error
+func (d *Dependency) DetectChecker(c Checker) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.detector = c
+}
+
+// WithTimeout sets the maximum duration that the detector function is allowed to run for. If the detector function does not return within this duration, the dependency is considered unhealthy.
+func (d *Dependency) WithTimeout(timeout time.Duration) *Dependency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.timeout = timeout
+	return d
+}
+
+// NonCritical marks the dependency as optional. When a non-critical dependency fails, the Detective instance is reported as degraded instead of unhealthy.
+func (d *Dependency) NonCritical() *Dependency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nonCritical = true
+	return d
+}
+
+// Liveness marks the dependency as an in-process self check, which is included in the state reported by the liveness handler of the Detective instance. Dependencies registered with DetectHeapSize, DetectRSS and DetectGoroutines are marked automatically.
+func (d *Dependency) Liveness() *Dependency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.liveness = true
+	return d
+}
+
+func (d *Dependency) isLiveness() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.liveness
+}
+
+// WithMetadata attaches a key/value pair describing the dependency, like its owner team, runbook URL or tier, which is included in its state, and added as a label to the detective_dependency_up Prometheus metric, so that alerts can be routed to the right people. Keys that are not valid Prometheus label names have their invalid characters replaced with underscores in metrics.
+func (d *Dependency) WithMetadata(key, value string) *Dependency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.metadata == nil {
+		d.metadata = map[string]string{}
+	}
+	d.metadata[key] = value
+	return d
 }
 
-func (d *Dependency) updateState() {
-	d.state = d.getState()
+// WithRetry sets the policy used to retry the detector function when it fails. The dependency is only considered unhealthy if all attempts fail. The timeout of the dependency applies to each attempt separately.
+func (d *Dependency) WithRetry(p RetryPolicy) *Dependency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.retry = p
+	return d
 }
 
-func (d *Dependency) getState() State {
+func (d *Dependency) getState(ctx context.Context) State {
+	d.mu.RLock()
+	detector, timeout, nonCritical, retry := d.detector, d.timeout, d.nonCritical, d.retry
+	disabled := d.isDisabled(time.Now())
+	var metadata map[string]string
+	if len(d.metadata) > 0 {
+		// The state is shared with hooks and handlers, which must not see later changes
+		metadata = make(map[string]string, len(d.metadata))
+		for k, v := range d.metadata {
+			metadata[k] = v
+		}
+	}
+	d.mu.RUnlock()
+	if disabled {
+		return State{Name: d.name, NonCritical: nonCritical, Metadata: metadata}.withDisabled()
+	}
+
 	init := time.Now()
-	err := d.detector()
-	diff := time.Now().Sub(init)
-	s := State{Name: d.name, Latency: diff}
-	if err != nil {
-		return s.withError(err)
+	var r Result
+	var details *detailsCollector
+	attempts := 0
+	for {
+		attempts++
+		var detailsCtx context.Context
+		detailsCtx, details = withDetailsCollector(ctx)
+		r = detectWithTimeout(detailsCtx, detector, timeout)
+		if r.health() != Unhealthy || attempts >= retry.maxAttempts() || !sleep(ctx, retry.backoff(attempts)) {
+			break
+		}
+	}
+	latency := r.Latency
+	if latency <= 0 {
+		latency = time.Now().Sub(init)
+	}
+	s := State{Name: d.name, NonCritical: nonCritical, Metadata: metadata, Details: r.mergeDetails(details.get())}.withLatency(latency)
+	if retry.maxAttempts() > 1 {
+		s.Attempts = attempts
+	}
+	return d.successes.track(s.withResult(r), time.Now())
+}
+
+// Degrade wraps an error, so that a dependency whose detector function returns it is reported as degraded instead of unhealthy. Use it to report problems that need attention, but do not yet stop the dependency from working.
+func Degrade(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &degradedError{err: err}
+}
+
+type degradedError struct {
+	err error
+}
+
+func (e *degradedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *degradedError) Unwrap() error {
+	return e.err
+}
+
+func isDegraded(err error) bool {
+	var de *degradedError
+	return errors.As(err, &de)
+}
+
+func detectWithTimeout(ctx context.Context, detector Checker, timeout time.Duration) Result {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return detect(ctx, detector)
+}
+
+// detect runs the checker, and returns early with the contexts error if the context is done before the checker returns
+func detect(ctx context.Context, detector Checker) Result {
+	if err := ctx.Err(); err != nil {
+		return Result{Err: err}
+	}
+	results := make(chan Result, 1)
+	go func() {
+		// A panicking checker only fails the dependency, instead of crashing the process
+		defer func() {
+			if p := recover(); p != nil {
+				results <- Result{Err: fmt.Errorf("panic: %v", p)}
+			}
+		}()
+		results <- detector.Check(ctx)
+	}()
+	select {
+	case r := <-results:
+		return r
+	case <-ctx.Done():
+		return Result{Err: ctx.Err()}
 	}
-	return s.withOk()
 }
@@ -0,0 +1,157 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDependency(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	tests := []struct {
+		name          string
+		detector      DetectorFunc
+		timeout       time.Duration
+		expectedState State
+	}{
+		{
+			name:          "success",
+			detector:      func() error { return nil },
+			expectedState: State{Name: "sample", Ok: true, Status: "Ok"},
+		},
+		{
+			name:          "failure",
+			detector:      func() error { return errors.New("failed") },
+			expectedState: State{Name: "sample", Ok: false, Status: "Error: failed"},
+		},
+		{
+			name: "timeout",
+			detector: func() error {
+				<-block
+				return nil
+			},
+			timeout:       10 * time.Millisecond,
+			expectedState: State{Name: "sample", Ok: false, Status: "Error: " + context.DeadlineExceeded.Error()},
+		},
+		{
+			name: "within timeout",
+			detector: func() error {
+				return nil
+			},
+			timeout:       time.Second,
+			expectedState: State{Name: "sample", Ok: true, Status: "Ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("sample").WithTimeout(tt.timeout)
+			d.Detect(tt.detector)
+			s := d.getState(context.Background())
+			assertStatesEqual(t, tt.expectedState, s)
+		})
+	}
+}
+
+func TestDependencyRetry(t *testing.T) {
+	t.Run("succeeds after retries", func(t *testing.T) {
+		calls := 0
+		d := newDependency("sample").WithRetry(RetryPolicy{Attempts: 3, Backoff: time.Millisecond, Jitter: time.Millisecond})
+		d.Detect(func() error {
+			calls++
+			if calls < 3 {
+				return errors.New("connection reset")
+			}
+			return nil
+		})
+		s := d.getState(context.Background())
+		assertStatesEqual(t, State{Name: "sample", Ok: true, Status: "Ok"}, s)
+		assert.Equal(t, 3, s.Attempts)
+	})
+
+	t.Run("fails once attempts are exhausted", func(t *testing.T) {
+		calls := 0
+		d := newDependency("sample").WithRetry(RetryPolicy{Attempts: 2})
+		d.Detect(func() error {
+			calls++
+			return errors.New("connection reset")
+		})
+		s := d.getState(context.Background())
+		assertStatesEqual(t, State{Name: "sample", Ok: false, Status: "Error: connection reset"}, s)
+		assert.Equal(t, 2, s.Attempts)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("stops retrying once the context is done", func(t *testing.T) {
+		d := newDependency("sample").WithRetry(RetryPolicy{Attempts: 5, Backoff: time.Hour})
+		d.Detect(func() error {
+			return errors.New("connection reset")
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		s := d.getState(ctx)
+		assert.False(t, s.Ok)
+		assert.Equal(t, 1, s.Attempts)
+	})
+
+	t.Run("attempts are not reported without a retry policy", func(t *testing.T) {
+		d := newDependency("sample")
+		assert.Equal(t, 0, d.getState(context.Background()).Attempts)
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{Attempts: 1000, Backoff: time.Second}
+	assert.Equal(t, time.Second, p.backoff(1))
+	assert.Equal(t, 8*time.Second, p.backoff(4))
+	// The backoff of later attempts is capped, rather than overflowing to zero or a negative duration
+	for _, attempt := range []int{6, 64, 65, 999} {
+		assert.Equal(t, defaultMaxBackoff, p.backoff(attempt), attempt)
+	}
+	p.MaxBackoff = 5 * time.Second
+	assert.Equal(t, 5*time.Second, p.backoff(999))
+	p.Backoff = time.Duration(1) << 62
+	p.MaxBackoff = time.Duration(1<<63 - 1)
+	assert.Equal(t, p.MaxBackoff, p.backoff(3))
+	assert.Equal(t, time.Duration(0), RetryPolicy{Attempts: 1000}.backoff(999))
+}
+
+func TestDependencyDegrade(t *testing.T) {
+	calls := 0
+	d := newDependency("sample").WithRetry(RetryPolicy{Attempts: 3})
+	d.Detect(func() error {
+		calls++
+		return Degrade(errors.New("slow responses"))
+	})
+	s := d.getState(context.Background())
+	assertStatesEqual(t, State{Name: "sample", Ok: true, Status: "Degraded: slow responses"}, s)
+	assert.Equal(t, Degraded, s.Health)
+	assert.Equal(t, 1, calls, "degraded dependencies should not be retried")
+	assert.Nil(t, Degrade(nil))
+}
+
+func TestDependencyDetails(t *testing.T) {
+	d := newDependency("sample")
+	d.DetectContext(func(ctx context.Context) error {
+		SetDetail(ctx, "connections", 3)
+		return nil
+	})
+	s := d.getState(context.Background())
+	assert.Equal(t, map[string]interface{}{"connections": 3}, s.Details)
+
+	// Details cannot be reported outside of a check
+	SetDetail(context.Background(), "connections", 3)
+	assert.Nil(t, newDependency("other").getState(context.Background()).Details)
+}
+
+func TestDependencyPanic(t *testing.T) {
+	d := newDependency("sample")
+	d.Detect(func() error {
+		panic("nil map")
+	})
+	assertStatesEqual(t, State{Name: "sample", Ok: false, Status: "Error: panic: nil map"}, d.getState(context.Background()))
+}
@@ -0,0 +1,47 @@
+package detective
+
+import (
+	"context"
+	"sync"
+)
+
+type detailsKey struct{}
+
+// detailsCollector gathers the details reported by a detector function during a single check
+type detailsCollector struct {
+	mu      sync.Mutex
+	details map[string]interface{}
+}
+
+// SetDetail reports additional information about the dependency being checked (for example, its current disk usage), which is included in the details of its state. It can be called from detector functions registered with DetectContext, using the context they receive. The value should be serializable as JSON.
+func SetDetail(ctx context.Context, key string, value interface{}) {
+	c, ok := ctx.Value(detailsKey{}).(*detailsCollector)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.details == nil {
+		c.details = map[string]interface{}{}
+	}
+	c.details[key] = value
+}
+
+func withDetailsCollector(ctx context.Context) (context.Context, *detailsCollector) {
+	c := &detailsCollector{}
+	return context.WithValue(ctx, detailsKey{}, c), c
+}
+
+// get returns a copy of the reported details, since detector functions that did not return in time can still report details
+func (c *detailsCollector) get() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.details) == 0 {
+		return nil
+	}
+	details := make(map[string]interface{}, len(c.details))
+	for k, v := range c.details {
+		details[k] = v
+	}
+	return details
+}
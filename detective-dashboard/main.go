@@ -27,7 +27,8 @@ func getStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	res, err := client.Do(req)
-	if err != nil || res.StatusCode != http.StatusOK {
+	// Unhealthy detective instances respond with 503, along with their state
+	if err != nil || (res.StatusCode != http.StatusOK && res.StatusCode != http.StatusServiceUnavailable) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
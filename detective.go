@@ -1,110 +1,475 @@
 package detective
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // A Detective instance manages registered dependencies and endpoints.
 // Dependencies can be registered with an instance.
 // Each instance has a state which represents the health of its components.
 type Detective struct {
-	name         string
-	client       Doer
+	name   string
+	client Doer
+
+	// mu guards the registered dependencies and endpoints, which can be changed while the handler is serving requests
+	mu           sync.RWMutex
 	dependencies []*Dependency
 	endpoints    []*endpoint
+	groups       []*Detective
+
+	statusCodes  map[Health]int
+	metrics      *metrics
+	redactErrors bool
+	timeout      time.Duration
+	concurrency  int
+	aggregation  Aggregation
+	tracer       Tracer
+	logger       Logger
+	tlsConfig    *tls.Config
+	proxy        *url.URL
+	maxDepth     int
+	cacheControl string
+	cors         *CORSConfig
+	build        *BuildInfo
+	ordering     Ordering
+	// observers receive the state of the instance after every check of all dependencies
+	observers []func(State)
+
+	periodicMu       sync.RWMutex
+	cachedState      *State
+	stopPeriodic     context.CancelFunc
+	failureThreshold int
+	// failures counts the consecutive failures of each dependency, when a failure threshold is set
+	failures map[string]int
+
+	tracker       stateTracker
+	notifications notifications
+	flight        flightGroup
+	cache         resultCache
+	successes     successTracker
+	stream        broadcaster
+	history       HistoryStore
 }
 
 // New creates a new Detective instance. To avoid confusion, the name provided should preferably be unique among dependent detective instances.
-func New(name string) *Detective {
-	return &Detective{
+// The instance can be configured with options:
+//
+//	d := detective.New("application", detective.WithTimeout(5*time.Second), detective.WithMaxConcurrentChecks(10))
+func New(name string, opts ...Option) *Detective {
+	d := &Detective{
 		name:   name,
 		client: &http.Client{},
+		statusCodes: map[Health]int{
+			Healthy:   http.StatusOK,
+			Degraded:  http.StatusOK,
+			Unhealthy: http.StatusServiceUnavailable,
+		},
+		metrics: newMetrics(),
+		logger:  noopLogger{},
 	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 
 // WithHTTPClient sets the HTTP Client to be used while hitting the endpoint of another detective HTTP ping handler.
+//
+// Deprecated: use the WithHTTPClient option with New instead.
 func (d *Detective) WithHTTPClient(c Doer) *Detective {
-	d.client = c
+	WithHTTPClient(c)(d)
+	return d
+}
+
+// WithStatusCode sets the HTTP status code that the HTTP handler responds with when the Detective instance has the given health.
+//
+// Deprecated: use the WithStatusCode option with New instead.
+func (d *Detective) WithStatusCode(h Health, code int) *Detective {
+	WithStatusCode(h, code)(d)
+	return d
+}
+
+// WithRedactedErrors removes error details from the response of the HTTP handler.
+//
+// Deprecated: use the WithRedactedErrors option with New instead.
+func (d *Detective) WithRedactedErrors() *Detective {
+	WithRedactedErrors()(d)
 	return d
 }
 
 // Dependency adds a new dependency to the Detective instance. The name provided should preferably be unique among dependencies registered within the same detective instance.
 func (d *Detective) Dependency(name string) *Dependency {
 	dependency := newDependency(name)
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.dependencies = append(d.dependencies, dependency)
 	return dependency
 }
 
-// Endpoint adds an HTTP endpoint as a dependency to the Detective instance, thereby allowing you to compose detective instances. This method creates a GET request to the provided url. If you want to customize the request (like using a different HTTP method, or adding headers), consider using the EndpointReq method instead.
+// RemoveDependency removes all dependencies registered with the provided name. It returns false if no such dependency was found.
+func (d *Detective) RemoveDependency(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dependencies := make([]*Dependency, 0, len(d.dependencies))
+	for _, dep := range d.dependencies {
+		if dep.name != name {
+			dependencies = append(dependencies, dep)
+		}
+	}
+	removed := len(dependencies) != len(d.dependencies)
+	d.dependencies = dependencies
+	return removed
+}
+
+// Endpoint adds an HTTP endpoint as a dependency to the Detective instance, thereby allowing you to compose detective instances. This method creates a GET request to the provided url. Services listening on Unix domain sockets can be checked with URLs like "unix:///var/run/app.sock/health", where the path of the socket ends with ".sock". If you want to customize the request (like using a different HTTP method, or adding headers), consider using the EndpointWithOptions, or EndpointReq methods instead.
 func (d *Detective) Endpoint(url string) error {
+	return d.EndpointWithOptions(url)
+}
+
+// EndpointReq is similar to Endpoint, but takes an HTTP request object instead of a URL. Use this method if you want to customize the request to the ping handler of another detective instance.
+func (d *Detective) EndpointReq(req *http.Request) {
+	d.addEndpoint(d.newEndpoint(req))
+}
+
+// EndpointWithOptions is similar to Endpoint, but allows the request to be customized with options, like the Method, Header, and Body options:
+//
+//	d.EndpointWithOptions("http://localhost:8081/", detective.Method(http.MethodPost), detective.Header("Authorization", "Bearer token"))
+func (d *Detective) EndpointWithOptions(url string, opts ...EndpointOption) error {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return err
 	}
-	d.EndpointReq(req)
+	e := d.newEndpoint(req)
+	if e.unixErr != nil {
+		return e.unixErr
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	d.addEndpoint(e)
 	return nil
 }
 
-// EndpointReq is similar to Endpoint, but takes an HTTP request object instead of a URL. Use this method if you want to customize the request to the ping handler of another detective instance.
-func (d *Detective) EndpointReq(req *http.Request) {
-	e := &endpoint{
-		name:   d.name,
-		client: d.client,
-		req:    *req,
+// RemoveEndpoint removes all endpoints registered with the provided URL. It returns false if no such endpoint was found.
+func (d *Detective) RemoveEndpoint(url string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	endpoints := make([]*endpoint, 0, len(d.endpoints))
+	for _, e := range d.endpoints {
+		if e.req.URL.String() != url {
+			endpoints = append(endpoints, e)
+		}
+	}
+	removed := len(endpoints) != len(d.endpoints)
+	d.endpoints = endpoints
+	return removed
+}
+
+// DisableEndpoint stops checking the endpoints registered with the provided URL, until they are enabled again with EnableEndpoint. Disabled endpoints are reported with a Disabled state, and do not affect the health of the instance. It returns false if no such endpoint was found.
+func (d *Detective) DisableEndpoint(url string) bool {
+	return d.setEndpointDisabled(url, true)
+}
+
+// EnableEndpoint resumes checking the endpoints registered with the provided URL, which were disabled with DisableEndpoint. It returns false if no such endpoint was found.
+func (d *Detective) EnableEndpoint(url string) bool {
+	return d.setEndpointDisabled(url, false)
+}
+
+func (d *Detective) setEndpointDisabled(url string, disabled bool) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	found := false
+	for _, e := range d.endpoints {
+		if e.req.URL.String() == url {
+			e.disabled.Store(disabled)
+			found = true
+		}
 	}
+	return found
+}
+
+func (d *Detective) addEndpoint(e *endpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.endpoints = append(d.endpoints, e)
 }
 
-func (d *Detective) getState(fromChain []string) State {
-	depLength := len(d.dependencies)
-	epLength := len(d.endpoints)
-	var wg sync.WaitGroup
+func (d *Detective) newEndpoint(req *http.Request) *endpoint {
+	e := &endpoint{
+		name:      d.name,
+		client:    d.client,
+		req:       *req,
+		tlsConfig: d.tlsConfig,
+		proxy:     d.proxy,
+		// The default limit is set here, rather than in fetchState, so that the MaxBodySize option can remove it
+		maxBodySize: defaultMaxBodySize,
+	}
+	if req.URL.Scheme == unixScheme {
+		e.unixSocket, e.unixURL, e.unixErr = splitUnixURL(req.URL)
+	}
+	return e
+}
+
+// GetState returns the aggregated state of the Detective instance, so that it can be used outside of the HTTP handler (for example, in an admin UI, or a CLI). It is equivalent to calling GetStateContext with a background context.
+func (d *Detective) GetState() State {
+	return d.GetStateContext(context.Background())
+}
 
-	depStates := make([]State, depLength)
-	wg.Add(depLength)
-	for iDep, dep := range d.dependencies {
-		go func(dep *Dependency, i int) {
-			s := dep.getState()
-			depStates[i] = s
-			wg.Done()
-		}(dep, iDep)
+// GetStateContext checks the health of all registered dependencies and endpoints, and returns the aggregated state of the Detective instance. Checks that are still running once the context is done are reported as failed.
+// If periodic checking is running, the most recently collected state is returned instead.
+func (d *Detective) GetStateContext(ctx context.Context) State {
+	return d.currentState(ctx, nil)
+}
+
+// currentState returns the cached state if periodic checking is running, and checks all dependencies otherwise
+func (d *Detective) currentState(ctx context.Context, fromChain []string) State {
+	// If this instance is already part of the calling chain, the cached state cannot be used, since it contains the states of endpoints that may be part of the chain as well
+	partOfChain := contains(fromChain, d.name)
+	if s, ok := d.getCachedState(); ok && !partOfChain {
+		return s
+	}
+	s := d.getState(ctx, fromChain)
+	// The state of an instance that is part of the calling chain does not include its endpoints, and would be reported as a change
+	if !partOfChain {
+		d.recordState(s)
 	}
+	return s
+}
 
-	epStates := []State{}
-	fromChainStr := strings.Join(append(fromChain, d.name), "|")
+func (d *Detective) getState(ctx context.Context, fromChain []string) State {
+	s := d.checkState(ctx, fromChain, allChecks)
+	if d.maxDepth > 0 {
+		s = s.withMaxDepth(d.maxDepth)
+	}
+	// The state of an instance that is part of the calling chain does not include its endpoints
 	if !contains(fromChain, d.name) {
-		epStates = make([]State, epLength)
-		wg.Add(epLength)
-		for iEp, e := range d.endpoints {
-			go func(e *endpoint, i int) {
-				s := e.getState(fromChainStr)
-				epStates[i] = s
-				wg.Done()
-			}(e, iEp)
+		d.metrics.observe(s)
+		for _, observe := range d.observers {
+			observe(s)
+		}
+	}
+	return s
+}
+
+// A selection decides which of the registered dependencies, and whether the endpoints are checked. All members of the groups selected with group are checked.
+type selection struct {
+	dependency func(*Dependency) bool
+	group      func(*Detective) bool
+	endpoints  bool
+}
+
+var allChecks = selection{endpoints: true}
+
+// namedSelection selects the dependencies and groups with the provided names
+func namedSelection(dependencies, groups []string) selection {
+	return selection{
+		dependency: func(dep *Dependency) bool { return contains(dependencies, dep.name) },
+		group:      func(g *Detective) bool { return contains(groups, g.name) },
+	}
+}
+
+// checkState checks the selected dependencies, groups and endpoints, and returns the aggregated state
+func (d *Detective) checkState(ctx context.Context, fromChain []string, sel selection) State {
+	d.mu.RLock()
+	dependencies := d.dependencies
+	endpoints := d.endpoints
+	groups := d.groups
+	d.mu.RUnlock()
+
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	ctx, endSpan := d.startSpan(ctx, "detective.check", map[string]string{"detective.name": d.name})
+	checks := make([]func(context.Context) State, 0, len(dependencies)+len(groups)+len(endpoints))
+	for _, dep := range dependencies {
+		if sel.dependency == nil || sel.dependency(dep) {
+			checks = append(checks, d.traced("detective.dependency", map[string]string{"detective.dependency": dep.name}, dep.getState))
 		}
 	}
+	for _, g := range groups {
+		if sel.group != nil && sel.group(g) {
+			checks = append(checks, recoverCheck(g.name, d.groupCheck(g, fromChain, allChecks)))
+		} else if sel.endpoints || g.hasSelected(sel) {
+			checks = append(checks, recoverCheck(g.name, d.groupCheck(g, fromChain, sel)))
+		}
+	}
+	fromChainStr := strings.Join(append(fromChain, d.name), "|")
+	if sel.endpoints && !contains(fromChain, d.name) && !d.exceedsMaxDepth(fromChain) {
+		for _, e := range endpoints {
+			url := e.req.URL.String()
+			checks = append(checks, recoverCheck(url, d.traced("detective.endpoint", map[string]string{"http.url": url}, func(e *endpoint) func(context.Context) State {
+				return func(ctx context.Context) State {
+					if e.disabled.Load() {
+						return State{Name: e.req.URL.String()}.withDisabled()
+					}
+					return e.successes.track(e.getState(ctx, fromChainStr), time.Now())
+				}
+			}(e))))
+		}
+	}
+
+	d.log(slog.LevelDebug, fmt.Sprintf("detective %s: checking %d dependencies", d.name, len(checks)))
+	init := time.Now()
+	aggregate := d.aggregation
+	if aggregate == nil {
+		aggregate = aggregateHealth
+	}
+	states := d.runChecks(ctx, checks)
+	if d.ordering == AlphabeticalOrder {
+		sort.SliceStable(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	}
+	s := State{Name: d.name, Build: d.build}.withAggregatedDependencies(states, aggregate)
+	if sel.endpoints {
+		s = d.successes.track(s, time.Now())
+	} else {
+		// A partial selection of checks does not tell whether the instance is working
+		checkedAt := time.Now().UTC()
+		s.CheckedAt = &checkedAt
+	}
+	duration := time.Now().Sub(init)
+	d.log(slog.LevelDebug, fmt.Sprintf("detective %s: checked %d dependencies in %s", d.name, len(checks), duration), slog.Duration("duration", duration), slog.String("health", string(s.Health)))
+	for _, dep := range s.Dependencies {
+		if effectiveHealth(dep) == Unhealthy {
+			d.log(slog.LevelError, fmt.Sprintf("detective %s: dependency %s failed: %s", d.name, dep.Name, dep.Error), slog.String("dependency", dep.Name), slog.String("error", dep.Error))
+		}
+	}
+	endSpan(s)
+	return s
+}
+
+// runChecks runs all checks concurrently. If a limit is set, the checks are run by a pool of at most d.concurrency workers, so that instances with many dependencies do not start a goroutine for each of them. The returned states are in the same order as the checks.
+func (d *Detective) runChecks(ctx context.Context, checks []func(context.Context) State) []State {
+	states := make([]State, len(checks))
+	workers := len(checks)
+	if d.concurrency > 0 && d.concurrency < workers {
+		workers = d.concurrency
+	}
+	indexes := make(chan int, len(checks))
+	for i := range checks {
+		indexes <- i
+	}
+	close(indexes)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				states[i] = checks[i](ctx)
+			}
+		}()
+	}
 	wg.Wait()
-	s := State{Name: d.name}
-	return s.withDependencies(append(depStates, epStates...))
+	return states
+}
+
+// recoverCheck wraps a check, so that a panic (for example, in a custom HTTP client) is reported as the failure of an entity with the provided name, instead of crashing the process
+func recoverCheck(name string, check func(context.Context) State) func(context.Context) State {
+	return func(ctx context.Context) (s State) {
+		defer func() {
+			if p := recover(); p != nil {
+				s = State{Name: name}.withError(fmt.Errorf("panic: %v", p))
+			}
+		}()
+		return check(ctx)
+	}
 }
 
 const fromHeader = "X_DETECTIVE_FROM_CHAIN"
 
-// ServeHTTP is the HTTP handler function for getting the state of the Detective instance
+// ServeHTTP is the HTTP handler function for getting the state of the Detective instance. The state is encoded as JSON, unless a plain text summary (format=text), a Nagios compatible line (format=nagios), or the Health Check Response Format for HTTP APIs (format=health) is requested with the format query parameter. The HTML page of the dashboard can be requested with format=html, and all formats, except the Nagios line, can also be requested with the Accept header. JSON responses are indented if the pretty query parameter is set, like "?pretty=1".
+// With verbose=false, only the aggregated state of the instance is reported, without its dependencies. See SummaryHandler for public health URLs, where the dependency tree should never be reported.
+// The check and group query parameters, like "?check=postgres" or "?group=storage", select the dependencies and groups with the provided names, at any depth, which are then the only ones checked. Endpoints are not checked, and the handler responds with a 404 status if nothing matches.
 func (d *Detective) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if d.handleCORS(w, r) {
+		return
+	}
+	query := r.URL.Query()
+	verbose, err := strconv.ParseBool(query.Get("verbose"))
+	summary := err == nil && !verbose
+	if checks, groups := query["check"], query["group"]; len(checks) > 0 || len(groups) > 0 {
+		sel := namedSelection(checks, groups)
+		if !d.hasSelected(sel) {
+			http.Error(w, "no dependency or group matches the selection", http.StatusNotFound)
+			return
+		}
+		d.serveState(w, r, summary, &sel)
+		return
+	}
+	d.serveState(w, r, summary, nil)
+}
+
+// SummaryHandler returns an HTTP handler that only reports the aggregated state of the Detective instance, and responds with the same status codes as the handler of the instance itself, but never includes the dependencies, so that it can be exposed publicly without leaking the internal topology of the application. For example:
+//
+//	http.Handle("/health", d.SummaryHandler())
+//	internal.Handle("/health", d)
+func (d *Detective) SummaryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.handleCORS(w, r) {
+			return
+		}
+		d.serveState(w, r, true, nil)
+	})
+}
+
+// serveState writes the state of the selected checks, or the shared state of all checks if sel is nil
+func (d *Detective) serveState(w http.ResponseWriter, r *http.Request, summary bool, sel *selection) {
 	fromChainRaw := r.Header.Get(fromHeader)
 	fromChain := strings.Split(fromChainRaw, "|")
-	s := d.getState(fromChain)
-	sBody, err := json.Marshal(s)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	ctx := r.Context()
+	if d.tracer != nil {
+		ctx = d.tracer.Extract(ctx, r.Header)
+	}
+	var s State
+	if sel != nil {
+		s = d.checkState(ctx, fromChain, *sel)
+	} else {
+		s = d.sharedState(ctx, fromChain)
+	}
+	if d.redactErrors {
+		s = s.withoutErrors()
+	}
+	if summary {
+		s = s.summary()
+	}
+	d.writeState(w, r, s)
+}
+
+func (d *Detective) statusCode(s State) int {
+	if code, ok := d.statusCodes[s.Health]; ok {
+		return code
+	}
+	if s.Ok {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+// exceedsMaxDepth returns true if the calling chain already contains as many instances as the maximum depth of the instance
+func (d *Detective) exceedsMaxDepth(fromChain []string) bool {
+	if d.maxDepth <= 0 {
+		return false
+	}
+	depth := 0
+	for _, name := range fromChain {
+		if name != "" {
+			depth++
+		}
 	}
-	w.Write(sBody)
-	return
+	return depth >= d.maxDepth
 }
 
 func contains(ss []string, val string) bool {
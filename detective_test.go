@@ -2,14 +2,19 @@ package detective
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	dm "github.com/sohamkamani/detective/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestDetective(t *testing.T) {
@@ -18,7 +23,7 @@ func TestDetective(t *testing.T) {
 
 		mockClient := &dm.MockClient{}
 		mockClient.On("Do", mock.Anything).Return(dm.MockJSONResponse(`{"name":"sample","status":"Ok", "active":true}`, http.StatusOK), nil).Once()
-		d := New("sample").WithHTTPClient(mockClient)
+		d := New("sample", WithHTTPClient(mockClient))
 		d.Endpoint("http://sample")
 		dep := d.Dependency("sampledep")
 		depCalled := false
@@ -27,7 +32,7 @@ func TestDetective(t *testing.T) {
 			return nil
 		})
 
-		s := d.getState([]string{})
+		s := d.getState(context.Background(), []string{})
 		expectedState := State{
 			Name:   "sample",
 			Ok:     true,
@@ -41,9 +46,34 @@ func TestDetective(t *testing.T) {
 		assert.True(t, depCalled)
 	})
 
+	t.Run("get state with context deadline", func(t *testing.T) {
+		d := New("sample")
+		block := make(chan struct{})
+		defer close(block)
+		d.Dependency("blocking").Detect(func() error {
+			<-block
+			return nil
+		})
+		d.Dependency("sampledep")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		s := d.GetStateContext(ctx)
+		expectedState := State{
+			Name:   "sample",
+			Ok:     false,
+			Status: "Error: dependency failure",
+			Dependencies: []State{
+				State{Name: "blocking", Ok: false, Status: "Error: " + context.DeadlineExceeded.Error()},
+				State{Name: "sampledep", Ok: true, Status: "Ok"},
+			},
+		}
+		assertStatesEqual(t, expectedState, s)
+	})
+
 	t.Run("handler", func(t *testing.T) {
 		mockClient := &dm.MockClient{}
-		d := New("sample").WithHTTPClient(mockClient)
+		d := New("sample", WithHTTPClient(mockClient))
 		d.Endpoint("http://sample")
 		d.Dependency("sampledep")
 		mockClient.On("Do", mock.Anything).Return(dm.MockJSONResponse(`{"name":"sample","status":"Ok", "active":true}`, http.StatusOK), nil).Once()
@@ -75,7 +105,7 @@ func TestDetective(t *testing.T) {
 
 	t.Run("handler with name in fromHeader chain", func(t *testing.T) {
 		mockClient := &dm.MockClient{}
-		d := New("sample").WithHTTPClient(mockClient)
+		d := New("sample", WithHTTPClient(mockClient))
 		d.Endpoint("http://sample")
 		d.Dependency("sampledep")
 		mockClient.On("Do", mock.Anything).Return(dm.MockJSONResponse(`{"name":"sample2","status":"Ok", "active":true}`, http.StatusOK), nil).Once()
@@ -99,4 +129,333 @@ func TestDetective(t *testing.T) {
 		}
 		assertStatesEqual(t, expectedState, gotState)
 	})
+
+	t.Run("handler with failing dependency", func(t *testing.T) {
+		d := New("sample")
+		d.Dependency("sampledep").Detect(func() error {
+			return errors.New("failed")
+		})
+		rw := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		d.ServeHTTP(rw, req)
+		res := rw.Result()
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		var gotState State
+		json.NewDecoder(res.Body).Decode(&gotState)
+		defer res.Body.Close()
+		assert.Equal(t, Unhealthy, gotState.Health)
+	})
+
+	t.Run("handler with custom status code", func(t *testing.T) {
+		d := New("sample", WithStatusCode(Unhealthy, http.StatusOK))
+		d.Dependency("sampledep").Detect(func() error {
+			return errors.New("failed")
+		})
+		rw := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		d.ServeHTTP(rw, req)
+		assert.Equal(t, http.StatusOK, rw.Result().StatusCode)
+	})
+
+	t.Run("handler with failing non-critical dependency", func(t *testing.T) {
+		d := New("sample")
+		d.Dependency("sampledep").NonCritical().Detect(func() error {
+			return errors.New("failed")
+		})
+		rw := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		d.ServeHTTP(rw, req)
+		res := rw.Result()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		var gotState State
+		json.NewDecoder(res.Body).Decode(&gotState)
+		defer res.Body.Close()
+		assert.Equal(t, Degraded, gotState.Health)
+		require.Len(t, gotState.Dependencies, 1)
+		assert.True(t, gotState.Dependencies[0].NonCritical)
+	})
+
+	t.Run("concurrent handler calls", func(t *testing.T) {
+		var mu sync.Mutex
+		chains := map[string]bool{}
+		remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			chains[r.Header.Get(fromHeader)] = true
+			mu.Unlock()
+			w.Write([]byte(`{"name":"remote","status":"Ok", "active":true}`))
+		}))
+		defer remote.Close()
+
+		d := New("sample")
+		d.Endpoint(remote.URL)
+		for i := 0; i < 5; i++ {
+			d.Dependency("sampledep" + strconv.Itoa(i))
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				rw := httptest.NewRecorder()
+				req, err := http.NewRequest(http.MethodGet, "", nil)
+				require.NoError(t, err)
+				req.Header.Add(fromHeader, "caller"+strconv.Itoa(i))
+				d.ServeHTTP(rw, req)
+				var gotState State
+				require.NoError(t, json.NewDecoder(rw.Result().Body).Decode(&gotState))
+				assert.True(t, gotState.Ok)
+				require.Len(t, gotState.Dependencies, 6)
+				for j := 0; j < 5; j++ {
+					assert.Equal(t, "sampledep"+strconv.Itoa(j), gotState.Dependencies[j].Name)
+				}
+				assert.Equal(t, "remote", gotState.Dependencies[5].Name)
+			}(i)
+		}
+		wg.Wait()
+
+		for i := 0; i < 10; i++ {
+			assert.True(t, chains["caller"+strconv.Itoa(i)+"|sample"])
+		}
+	})
+
+	t.Run("handler with redacted errors", func(t *testing.T) {
+		d := New("sample", WithRedactedErrors())
+		d.Dependency("sampledep").Detect(func() error {
+			return errors.New("failed")
+		})
+		rw := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		d.ServeHTTP(rw, req)
+		var gotState State
+		require.NoError(t, json.NewDecoder(rw.Result().Body).Decode(&gotState))
+		require.Len(t, gotState.Dependencies, 1)
+		assert.Equal(t, "", gotState.Dependencies[0].Error)
+		assert.Equal(t, "Error", gotState.Dependencies[0].Status)
+	})
+
+	t.Run("remove dependencies and endpoints", func(t *testing.T) {
+		d := New("sample")
+		d.Dependency("db")
+		d.Dependency("cache")
+		d.Endpoint("http://localhost:1")
+		assert.True(t, d.RemoveDependency("db"))
+		assert.False(t, d.RemoveDependency("db"))
+		assert.True(t, d.RemoveEndpoint("http://localhost:1"))
+		assert.False(t, d.RemoveEndpoint("http://localhost:1"))
+
+		s := d.GetStateContext(context.Background())
+		require.Len(t, s.Dependencies, 1)
+		assert.Equal(t, "cache", s.Dependencies[0].Name)
+	})
+
+	t.Run("register dependencies while serving", func(t *testing.T) {
+		d := New("sample")
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				name := "dep" + strconv.Itoa(i)
+				d.Dependency(name).WithTimeout(time.Second).Detect(func() error {
+					return nil
+				})
+				d.RemoveDependency(name)
+			}(i)
+			go func() {
+				defer wg.Done()
+				d.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+		}
+		wg.Wait()
+		assert.Empty(t, d.GetStateContext(context.Background()).Dependencies)
+	})
+}
+
+type panickingClient struct{}
+
+func (panickingClient) Do(*http.Request) (*http.Response, error) {
+	panic("transport is broken")
+}
+
+func TestEndpointPanic(t *testing.T) {
+	d := New("sample", WithHTTPClient(panickingClient{}))
+	require.NoError(t, d.Endpoint("http://localhost:8081/"))
+	d.Dependency("db")
+	s := d.GetState()
+	assertStatesEqual(t, State{Name: "sample", Ok: false, Status: "Error: dependency failure", Dependencies: []State{
+		{Name: "db", Ok: true, Status: "Ok"},
+		{Name: "http://localhost:8081/", Ok: false, Status: "Error: panic: transport is broken"},
+	}}, s)
+}
+
+func TestMaxDepth(t *testing.T) {
+	// Each instance depends on the next one: a -> b -> c -> d
+	instances := make([]*Detective, 4)
+	servers := make([]*httptest.Server, 4)
+	for i, name := range []string{"a", "b", "c", "d"} {
+		instances[i] = New(name, WithMaxDepth(2))
+		servers[i] = httptest.NewServer(instances[i])
+		defer servers[i].Close()
+	}
+	instances[3].Dependency("db")
+	for i := 0; i < 3; i++ {
+		require.NoError(t, instances[i].Endpoint(servers[i+1].URL))
+	}
+
+	s := instances[0].GetState()
+	require.Len(t, s.Dependencies, 1)
+	b := s.Dependencies[0]
+	assert.Equal(t, "b", b.Name)
+	require.Len(t, b.Dependencies, 1)
+	c := b.Dependencies[0]
+	assert.Equal(t, "c", c.Name)
+	// The dependencies of c are beyond the maximum depth of a, and are not checked, since the request has passed through two instances
+	assert.Empty(t, c.Dependencies)
+	assert.True(t, s.Ok)
+
+	// Without a maximum depth, the whole tree is reported
+	instances[0].maxDepth, instances[1].maxDepth, instances[2].maxDepth = 0, 0, 0
+	s = instances[0].GetState()
+	assert.Equal(t, "db", s.Dependencies[0].Dependencies[0].Dependencies[0].Dependencies[0].Name)
+}
+
+func TestOrdering(t *testing.T) {
+	names := func(s State) []string {
+		var names []string
+		for _, dep := range s.Dependencies {
+			names = append(names, dep.Name)
+		}
+		return names
+	}
+	d := New("sample")
+	for _, name := range []string{"redis", "db", "cache"} {
+		delay := time.Duration(len(name)) * time.Millisecond
+		d.Dependency(name).Detect(func() error {
+			time.Sleep(delay)
+			return nil
+		})
+	}
+	d.Group("queues").Dependency("kafka")
+	// The order does not depend on which check completes first
+	assert.Equal(t, []string{"redis", "db", "cache", "queues"}, names(d.GetState()))
+
+	d.ordering = AlphabeticalOrder
+	assert.Equal(t, []string{"cache", "db", "queues", "redis"}, names(d.GetState()))
+
+	g := New("sample", WithOrdering(AlphabeticalOrder)).Group("storage")
+	g.Dependency("s3")
+	g.Dependency("disk")
+	assert.Equal(t, []string{"disk", "s3"}, names(g.GetState()))
+}
+
+func TestStateSchema(t *testing.T) {
+	now := time.Now()
+	s := State{
+		Name: "sample", Error: "timeout", NonCritical: true, Disabled: true, Attempts: 2,
+		Details: map[string]interface{}{"b": 2, "a": 1}, Metadata: map[string]string{"team": "core"},
+		CheckedAt: &now, LastSuccessAt: &now, Build: &BuildInfo{}, Dependencies: []State{{Name: "db"}},
+	}
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	// Renaming or removing any of these fields breaks consumers of the JSON state
+	assert.ElementsMatch(t, []string{"name", "active", "status", "error", "health", "non_critical", "disabled", "attempts", "latency", "latency_ms", "details", "metadata", "checked_at", "last_success_at", "build", "dependencies"}, keys)
+	assert.JSONEq(t, `{"a": 1, "b": 2}`, string(fields["details"]))
+	again, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+}
+
+func TestSummary(t *testing.T) {
+	d := New("sample")
+	d.Dependency("db").Detect(func() error { return errors.New("connection refused") })
+
+	for _, tt := range []struct {
+		name    string
+		handler http.Handler
+		url     string
+		summary bool
+	}{
+		{"verbose", d, "/", false},
+		{"verbose=true", d, "/?verbose=true", false},
+		{"verbose=false", d, "/?verbose=false", true},
+		{"summary handler", d.SummaryHandler(), "/", true},
+		{"summary handler ignores verbose", d.SummaryHandler(), "/?verbose=true", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+			var s State
+			require.NoError(t, json.NewDecoder(rw.Body).Decode(&s))
+			assert.Equal(t, "sample", s.Name)
+			assert.Equal(t, Unhealthy, s.Health)
+			assert.Equal(t, tt.summary, len(s.Dependencies) == 0)
+		})
+	}
+}
+
+func TestServeHTTPSelection(t *testing.T) {
+	var checked sync.Map
+	detect := func(name string) func() error {
+		return func() error {
+			checked.Store(name, true)
+			return nil
+		}
+	}
+	d := New("sample")
+	d.Dependency("postgres").Detect(detect("postgres"))
+	d.Dependency("redis").Detect(detect("redis"))
+	storage := d.Group("storage")
+	storage.Dependency("s3").Detect(detect("s3"))
+	storage.Dependency("nfs").Detect(detect("nfs"))
+	d.Group("queues").Dependency("kafka").Detect(detect("kafka"))
+
+	tests := []struct {
+		url     string
+		code    int
+		checked []string
+	}{
+		{"/?check=postgres", http.StatusOK, []string{"postgres"}},
+		{"/?check=postgres&check=nfs", http.StatusOK, []string{"postgres", "nfs"}},
+		{"/?group=storage", http.StatusOK, []string{"s3", "nfs"}},
+		{"/?check=kafka&group=storage", http.StatusOK, []string{"s3", "nfs", "kafka"}},
+		{"/?check=mysql", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			checked.Range(func(key, value interface{}) bool {
+				checked.Delete(key)
+				return true
+			})
+			rw := httptest.NewRecorder()
+			d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			assert.Equal(t, tt.code, rw.Code)
+			var names []string
+			checked.Range(func(key, value interface{}) bool {
+				names = append(names, key.(string))
+				return true
+			})
+			assert.ElementsMatch(t, tt.checked, names)
+		})
+	}
+
+	rw := httptest.NewRecorder()
+	d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?check=nfs", nil))
+	var s State
+	require.NoError(t, json.NewDecoder(rw.Body).Decode(&s))
+	require.Len(t, s.Dependencies, 1)
+	assert.Equal(t, "storage", s.Dependencies[0].Name)
+	require.Len(t, s.Dependencies[0].Dependencies, 1)
+	assert.Equal(t, "nfs", s.Dependencies[0].Dependencies[0].Name)
 }
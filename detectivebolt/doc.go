@@ -0,0 +1,11 @@
+// Package detectivebolt persists the history of detective instances in a Bolt database, so that the results of checks survive restarts, and detective can be used as a lightweight standalone monitor.
+//
+//	store, err := detectivebolt.Open("detective.db", 30*24*time.Hour)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer store.Close()
+//	d := detective.New("application", detective.WithHistoryStore(store))
+//
+// Results older than the retention period are removed as new results are recorded.
+package detectivebolt
@@ -0,0 +1,154 @@
+package detectivebolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/sohamkamani/detective"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+// historyBucket contains a nested bucket for each entity, whose keys are the timestamps of its results
+var historyBucket = []byte("history")
+
+// bucketName returns the name of the bucket of an entity. Bucket names cannot be empty, like the name of the instance itself, so they are all prefixed.
+func bucketName(name string) []byte {
+	return []byte("/" + name)
+}
+
+// Store is a detective.HistoryStore that persists the results of checks in a Bolt database
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration
+}
+
+var _ detective.HistoryStore = (*Store)(nil)
+
+// Open opens, or creates the Bolt database at the provided path. Results older than the retention period are removed, unless it is 0.
+func Open(path string, retention time.Duration) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(db, retention)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// New creates a store using an open Bolt database, which can be shared with the application. Results older than the retention period are removed, unless it is 0.
+func New(db *bolt.DB, retention time.Duration) (*Store, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(historyBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db, retention: retention}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// key orders the results by their time. Results of the same entity recorded at the same time are distinguished by a sequence number.
+func key(t time.Time, seq uint64) []byte {
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(k[8:], seq)
+	return k
+}
+
+// Record stores the results of a check, and removes the results older than the retention period
+func (s *Store) Record(ctx context.Context, entries map[string]detective.HistoryEntry) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		history := tx.Bucket(historyBucket)
+		for name, e := range entries {
+			b, err := history.CreateBucketIfNotExists(bucketName(name))
+			if err != nil {
+				return err
+			}
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key(e.Time, seq), value); err != nil {
+				return err
+			}
+			if s.retention > 0 {
+				if err := prune(b, e.Time.Add(-s.retention)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// prune removes the results recorded before the provided time
+func prune(b *bolt.Bucket, before time.Time) error {
+	c := b.Cursor()
+	limit := key(before, 0)
+	for k, _ := c.First(); k != nil && string(k) < string(limit); k, _ = c.Next() {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Entries returns the results of the entity recorded at or after since, oldest first. If limit is positive, only the limit most recent results are returned.
+func (s *Store) Entries(ctx context.Context, name string, since time.Time, limit int) ([]detective.HistoryEntry, error) {
+	var entries []detective.HistoryEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(historyBucket).Bucket(bucketName(name))
+		if b == nil {
+			return nil
+		}
+		first := key(since, 0)
+		if since.IsZero() {
+			first = nil
+		}
+		// The most recent results are read backwards, so that a limit does not require reading all of them
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil && (first == nil || string(k) >= string(first)); k, v = c.Prev() {
+			if limit > 0 && len(entries) == limit {
+				break
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var e detective.HistoryEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, err
+}
+
+// Names returns the names of the entities that have results, sorted
+func (s *Store) Names(ctx context.Context) ([]string, error) {
+	var names []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(historyBucket).ForEachBucket(func(k []byte) error {
+			names = append(names, string(k[1:]))
+			return nil
+		})
+	})
+	return names, err
+}
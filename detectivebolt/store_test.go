@@ -0,0 +1,79 @@
+package detectivebolt
+
+import (
+	"context"
+	"errors"
+	"github.com/sohamkamani/detective"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "detective.db")
+	s, err := Open(path, time.Hour)
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		at := start.Add(time.Duration(i) * 20 * time.Minute)
+		require.NoError(t, s.Record(ctx, map[string]detective.HistoryEntry{
+			"":   {Time: at, Health: detective.Healthy, LatencyMs: float64(i)},
+			"db": {Time: at, Health: detective.Unhealthy, Error: "connection refused", LatencyMs: float64(i)},
+		}))
+	}
+	names, err := s.Names(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "db"}, names)
+
+	latencies := func(entries []detective.HistoryEntry) []float64 {
+		var l []float64
+		for _, e := range entries {
+			l = append(l, e.LatencyMs)
+		}
+		return l
+	}
+	// The results older than an hour before the latest one have been removed
+	entries, err := s.Entries(ctx, "db", time.Time{}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 2, 3, 4}, latencies(entries))
+	assert.Equal(t, "connection refused", entries[0].Error)
+	assert.True(t, entries[0].Time.Equal(start.Add(20*time.Minute)))
+
+	entries, err = s.Entries(ctx, "db", time.Time{}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{3, 4}, latencies(entries))
+	entries, err = s.Entries(ctx, "", start.Add(50*time.Minute), 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{3, 4}, latencies(entries))
+	entries, err = s.Entries(ctx, "redis", time.Time{}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// The results survive a restart
+	require.NoError(t, s.Close())
+	s, err = Open(path, 0)
+	require.NoError(t, err)
+	defer s.Close()
+	entries, err = s.Entries(ctx, "db", time.Time{}, 0)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+}
+
+func TestHistoryStore(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "detective.db"), 0)
+	require.NoError(t, err)
+	defer s.Close()
+	d := detective.New("sample", detective.WithHistoryStore(s))
+	d.Dependency("db").Detect(func() error { return errors.New("connection refused") })
+	d.GetState()
+	d.GetState()
+
+	entries, err := s.Entries(context.Background(), "db", time.Time{}, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, detective.Unhealthy, entries[1].Health)
+}
@@ -0,0 +1,6 @@
+// Package detectiveotel records the health checks of detective instances as OpenTelemetry spans.
+//
+//	d := detective.New("application", detective.WithTracer(detectiveotel.Tracer(tracerProvider, propagation.TraceContext{})))
+//
+// Every check of the instance is recorded as a span, with a child span for each dependency and endpoint. Requests to endpoints carry the trace context, so that the checks of other traced detective instances are part of the same trace.
+package detectiveotel
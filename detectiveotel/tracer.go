@@ -0,0 +1,66 @@
+package detectiveotel
+
+import (
+	"context"
+	"github.com/sohamkamani/detective"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"sort"
+)
+
+const instrumentationName = "github.com/sohamkamani/detective"
+
+// Tracer returns a detective.Tracer that records spans with the provided TracerProvider, and propagates the trace context of endpoint requests with the provided propagator. If either of them is nil, the global TracerProvider or propagator is used.
+func Tracer(tp trace.TracerProvider, p propagation.TextMapPropagator) detective.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	if p == nil {
+		p = otel.GetTextMapPropagator()
+	}
+	return &tracer{tracer: tp.Tracer(instrumentationName), propagator: p}
+}
+
+type tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func (t *tracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, detective.Span) {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]attribute.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, attribute.String(k, attributes[k]))
+	}
+	ctx, s := t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	return ctx, span{s}
+}
+
+func (t *tracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+func (t *tracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return t.propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+type span struct {
+	span trace.Span
+}
+
+// End records the health of the checked entity, and marks the span as failed if the entity is unhealthy
+func (s span) End(st detective.State) {
+	s.span.SetAttributes(attribute.String("detective.health", string(st.Health)))
+	if st.Health == detective.Unhealthy {
+		s.span.SetStatus(codes.Error, st.Error)
+	}
+	s.span.End()
+}
@@ -0,0 +1,58 @@
+package detectiveotel
+
+import (
+	"errors"
+	"github.com/sohamkamani/detective"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := Tracer(tp, propagation.TraceContext{})
+
+	remote := detective.New("remote", detective.WithTracer(tracer))
+	remote.Dependency("cache")
+	s := httptest.NewServer(remote)
+	defer s.Close()
+
+	d := detective.New("sample", detective.WithTracer(tracer))
+	d.Dependency("db").Detect(func() error { return errors.New("connection refused") })
+	require.NoError(t, d.Endpoint(s.URL))
+	d.GetState()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		key := span.Name()
+		for _, attr := range span.Attributes() {
+			if attr.Key == "detective.name" || attr.Key == "detective.dependency" {
+				key += " " + attr.Value.AsString()
+			}
+		}
+		spans[key] = span
+	}
+	require.Contains(t, spans, "detective.check sample")
+	require.Contains(t, spans, "detective.dependency db")
+	require.Contains(t, spans, "detective.endpoint")
+	require.Contains(t, spans, "detective.check remote")
+	require.Contains(t, spans, "detective.dependency cache")
+
+	root := spans["detective.check sample"]
+	assert.Equal(t, codes.Error, root.Status().Code)
+	assert.Equal(t, "dependency failure", root.Status().Description)
+	assert.Equal(t, root.SpanContext().SpanID(), spans["detective.dependency db"].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans["detective.dependency db"].Status().Code)
+	assert.Equal(t, root.SpanContext().SpanID(), spans["detective.endpoint"].Parent().SpanID())
+
+	// The checks of the remote instance continue the trace of the endpoint request
+	assert.Equal(t, root.SpanContext().TraceID(), spans["detective.check remote"].SpanContext().TraceID())
+	assert.Equal(t, spans["detective.endpoint"].SpanContext().SpanID(), spans["detective.check remote"].Parent().SpanID())
+	assert.Equal(t, codes.Unset, spans["detective.check remote"].Status().Code)
+}
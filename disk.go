@@ -0,0 +1,38 @@
+package detective
+
+import (
+	"context"
+	"fmt"
+)
+
+// DiskSpaceConfig configures the thresholds of the detector registered with DetectDiskSpace. A dependency fails if either of the thresholds that are set is not met.
+type DiskSpaceConfig struct {
+	// MinFreeBytes is the minimum number of bytes that should be available
+	MinFreeBytes uint64
+	// MinFreePercent is the minimum percentage (between 0 and 100) of the total space that should be available
+	MinFreePercent float64
+}
+
+// DetectDiskSpace registers a detector function that checks the space available to the process on the file system containing the provided path (for example, "/var"). The total, free and used space are reported in the details of the state of the dependency.
+func (d *Dependency) DetectDiskSpace(path string, c DiskSpaceConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		total, free, err := diskUsage(path)
+		if err != nil {
+			return err
+		}
+		freePercent := 100.0
+		if total > 0 {
+			freePercent = float64(free) / float64(total) * 100
+		}
+		SetDetail(ctx, "total_bytes", total)
+		SetDetail(ctx, "free_bytes", free)
+		SetDetail(ctx, "used_percent", 100-freePercent)
+		if free < c.MinFreeBytes {
+			return fmt.Errorf("%s has %d bytes free, which is less than %d", path, free, c.MinFreeBytes)
+		}
+		if freePercent < c.MinFreePercent {
+			return fmt.Errorf("%s has %.1f%% free, which is less than %.1f%%", path, freePercent, c.MinFreePercent)
+		}
+		return nil
+	})
+}
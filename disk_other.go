@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd
+
+package detective
+
+import (
+	"errors"
+)
+
+func diskUsage(path string) (total, free uint64, err error) {
+	return 0, 0, errors.New("disk space checks are not supported on this platform")
+}
@@ -0,0 +1,45 @@
+//go:build linux || darwin || freebsd
+
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestDetectDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		config   DiskSpaceConfig
+		expected Health
+	}{
+		{"no thresholds", DiskSpaceConfig{}, Healthy},
+		{"enough free bytes", DiskSpaceConfig{MinFreeBytes: 1}, Healthy},
+		{"not enough free bytes", DiskSpaceConfig{MinFreeBytes: math.MaxUint64}, Unhealthy},
+		{"not enough free percent", DiskSpaceConfig{MinFreePercent: 101}, Unhealthy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("disk")
+			d.DetectDiskSpace(dir, tt.config)
+			s := d.getState(context.Background())
+			assert.Equal(t, tt.expected, s.Health, s.Error)
+			require.Contains(t, s.Details, "total_bytes")
+			require.Contains(t, s.Details, "free_bytes")
+			assert.Contains(t, s.Details, "used_percent")
+			assert.True(t, s.Details["free_bytes"].(uint64) <= s.Details["total_bytes"].(uint64))
+		})
+	}
+}
+
+func TestDetectDiskSpaceMissingPath(t *testing.T) {
+	d := newDependency("disk")
+	d.DetectDiskSpace("/does/not/exist", DiskSpaceConfig{})
+	s := d.getState(context.Background())
+	assert.Equal(t, Unhealthy, s.Health)
+	assert.Empty(t, s.Details)
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd
+
+package detective
+
+import (
+	"syscall"
+)
+
+// diskUsage returns the total size of the file system containing the path, and the number of bytes available to unprivileged users
+func diskUsage(path string) (total, free uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return uint64(st.Blocks) * uint64(st.Bsize), uint64(st.Bavail) * uint64(st.Bsize), nil
+}
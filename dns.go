@@ -0,0 +1,52 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// DNSConfig configures the detector registered with DetectDNSConfig
+type DNSConfig struct {
+	// Resolver is the address of the DNS server to query (for example, "8.8.8.8:53"). If empty, the system resolver is used.
+	Resolver string
+	// MaxLatency is the maximum time the resolution is allowed to take. If zero, only the timeout of the dependency applies.
+	MaxLatency time.Duration
+}
+
+// DetectDNS registers a detector function that resolves the provided hostname using the system resolver. The dependency is considered unhealthy if the hostname cannot be resolved.
+func (d *Dependency) DetectDNS(host string) {
+	d.DetectDNSConfig(host, DNSConfig{})
+}
+
+// DetectDNSConfig is similar to DetectDNS, but allows the resolver, and the maximum acceptable resolution latency to be configured
+func (d *Dependency) DetectDNSConfig(host string, c DNSConfig) {
+	resolver := net.DefaultResolver
+	if c.Resolver != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, c.Resolver)
+			},
+		}
+	}
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		init := time.Now()
+		addrs, err := resolver.LookupHost(ctx, host)
+		latency := time.Now().Sub(init)
+		if err != nil {
+			return err
+		}
+		if len(addrs) == 0 {
+			return errors.New("no addresses found for " + host)
+		}
+		if c.MaxLatency > 0 && latency > c.MaxLatency {
+			return errors.New("resolving " + host + " took " + latency.String() + ", which exceeds " + c.MaxLatency.String())
+		}
+		return nil
+	})
+}
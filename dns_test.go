@@ -0,0 +1,32 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectDNS(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		d := newDependency("dns")
+		d.DetectDNS("localhost")
+		assertStatesEqual(t, State{Name: "dns", Ok: true, Status: "Ok"}, d.getState(context.Background()))
+	})
+
+	t.Run("latency threshold exceeded", func(t *testing.T) {
+		d := newDependency("dns")
+		d.DetectDNSConfig("localhost", DNSConfig{MaxLatency: time.Nanosecond})
+		s := d.getState(context.Background())
+		assert.False(t, s.Ok)
+		assert.True(t, strings.HasPrefix(s.Error, "resolving localhost took "), s.Error)
+	})
+
+	t.Run("unreachable resolver", func(t *testing.T) {
+		d := newDependency("dns").WithTimeout(time.Second)
+		d.DetectDNSConfig("detective.test", DNSConfig{Resolver: "127.0.0.1:1"})
+		s := d.getState(context.Background())
+		assert.False(t, s.Ok)
+	})
+}
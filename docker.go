@@ -0,0 +1,79 @@
+package detective
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// defaultDockerHost is the socket of the Docker daemon on Linux and macOS
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+// DockerConfig configures the detector registered with DetectDocker
+type DockerConfig struct {
+	// TLSConfig is used to connect to a daemon listening on a tcp:// address with TLS, if set
+	TLSConfig *tls.Config
+}
+
+// DetectDocker registers a detector function that pings a Docker daemon at a host like "unix:///var/run/docker.sock" or "tcp://docker.example.com:2376". If the host is empty, the DOCKER_HOST environment variable is used, or the default socket of the daemon if it is not set. The API version of the daemon is reported in the details of the state of the dependency.
+// The request is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectDocker(host string, c DockerConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		if host == "" {
+			host = os.Getenv("DOCKER_HOST")
+		}
+		if host == "" {
+			host = defaultDockerHost
+		}
+		u, err := url.Parse(host)
+		if err != nil {
+			return err
+		}
+		transport := &http.Transport{TLSClientConfig: c.TLSConfig}
+		pingURL := url.URL{Scheme: "http", Host: u.Host, Path: "/_ping"}
+		switch u.Scheme {
+		case unixScheme:
+			socket := u.Path
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			}
+			pingURL.Host = "docker"
+		case "tcp":
+			if c.TLSConfig != nil {
+				pingURL.Scheme = "https"
+			}
+		default:
+			return fmt.Errorf("unsupported docker host scheme %q", u.Scheme)
+		}
+		defer transport.CloseIdleConnections()
+		req, err := http.NewRequest(http.MethodGet, pingURL.String(), nil)
+		if err != nil {
+			return err
+		}
+		res, err := (&http.Client{Transport: transport}).Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if version := res.Header.Get("Api-Version"); version != "" {
+			SetDetail(ctx, "api_version", version)
+		}
+		body, err := io.ReadAll(io.LimitReader(res.Body, 1024))
+		if err != nil {
+			return err
+		}
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("docker daemon returned http status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+		}
+		return nil
+	})
+}
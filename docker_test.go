@@ -0,0 +1,71 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDetectDocker(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_ping" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Api-Version", "1.43")
+		w.Write([]byte("OK"))
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		s := httptest.NewServer(handler)
+		defer s.Close()
+		d := newDependency("docker")
+		d.DetectDocker(strings.Replace(s.URL, "http://", "tcp://", 1), DockerConfig{})
+		st := d.getState(context.Background())
+		assertStatesEqual(t, State{Name: "docker", Ok: true, Status: "Ok"}, st)
+		assert.Equal(t, map[string]interface{}{"api_version": "1.43"}, st.Details)
+	})
+
+	t.Run("unix socket from the environment", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("unix domain sockets are not supported")
+		}
+		socket := filepath.Join(t.TempDir(), "docker.sock")
+		l, err := net.Listen("unix", socket)
+		require.NoError(t, err)
+		s := httptest.NewUnstartedServer(handler)
+		s.Listener = l
+		s.Start()
+		defer s.Close()
+		t.Setenv("DOCKER_HOST", "unix://"+socket)
+
+		d := newDependency("docker")
+		d.DetectDocker("", DockerConfig{})
+		assertStatesEqual(t, State{Name: "docker", Ok: true, Status: "Ok"}, d.getState(context.Background()))
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error response from daemon: containerd is not running\n"))
+		}))
+		defer s.Close()
+		d := newDependency("docker")
+		d.DetectDocker(strings.Replace(s.URL, "http://", "tcp://", 1), DockerConfig{})
+		assertStatesEqual(t, State{Name: "docker", Ok: false, Status: "Error: docker daemon returned http status 500: Error response from daemon: containerd is not running"}, d.getState(context.Background()))
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		d := newDependency("docker")
+		d.DetectDocker("npipe:////./pipe/docker_engine", DockerConfig{})
+		assertStatesEqual(t, State{Name: "docker", Ok: false, Status: `Error: unsupported docker host scheme "npipe"`}, d.getState(context.Background()))
+	})
+
+}
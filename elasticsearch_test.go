@@ -0,0 +1,63 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDetectElasticsearch(t *testing.T) {
+	var status, authorization string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if r.URL.Path != "/_cluster/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"cluster_name": "search", "status": "` + status + `", "number_of_nodes": 3, "unassigned_shards": 2}`))
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		status   string
+		expected Health
+		err      string
+	}{
+		{"green", Healthy, ""},
+		{"yellow", Degraded, "elasticsearch cluster status is yellow"},
+		{"red", Unhealthy, "elasticsearch cluster status is red"},
+		{"blue", Unhealthy, `unknown elasticsearch cluster status: "blue"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			status = tt.status
+			d := newDependency("elasticsearch")
+			d.DetectElasticsearch(s.URL+"/", ElasticsearchConfig{})
+			st := d.getState(context.Background())
+			assert.Equal(t, tt.expected, st.Health)
+			assert.Equal(t, tt.err, st.Error)
+			assert.Equal(t, map[string]interface{}{"cluster_name": "search", "cluster_status": tt.status, "number_of_nodes": 3, "unassigned_shards": 2}, st.Details)
+		})
+	}
+
+	t.Run("credentials", func(t *testing.T) {
+		status = "green"
+		d := newDependency("elasticsearch")
+		d.DetectElasticsearch(strings.Replace(s.URL, "http://", "http://elastic:secret@", 1), ElasticsearchConfig{})
+		d.getState(context.Background())
+		assert.Equal(t, "Basic ZWxhc3RpYzpzZWNyZXQ=", authorization)
+
+		d.DetectElasticsearch(s.URL, ElasticsearchConfig{APIKey: "key"})
+		d.getState(context.Background())
+		assert.Equal(t, "ApiKey key", authorization)
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		d := newDependency("elasticsearch")
+		d.DetectElasticsearch(s.URL+"/missing", ElasticsearchConfig{})
+		assertStatesEqual(t, State{Name: "elasticsearch", Ok: false, Status: "Error: elasticsearch cluster health api returned http status: 404"}, d.getState(context.Background()))
+	})
+}
@@ -1,9 +1,18 @@
 package detective
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,30 +24,265 @@ type Doer interface {
 type endpoint struct {
 	name   string
 	req    http.Request
+	body   []byte
 	client Doer
+	// statuses and bodyMatchers are the expectations of an endpoint that is not a detective instance
+	statuses     []statusMatcher
+	bodyMatchers []bodyMatcher
+	// mode is set by the Raw and Peer options, and is inferred from the expectations otherwise
+	mode endpointMode
+	// authorizers add credentials to each request, like the Authorization header
+	authorizers []func(ctx context.Context, req *http.Request) error
+	// tlsConfig customizes the transport of the client, which is built once by httpClient
+	tlsConfig *tls.Config
+	proxy     *url.URL
+	// checkRedirect is the redirect policy of the client, if the endpoint has its own
+	checkRedirect func(req *http.Request, via []*http.Request) error
+	clientOnce    sync.Once
+	builtClient   Doer
+	// unixSocket is the path of the Unix domain socket of a "unix://" endpoint, which is requested with unixURL
+	unixSocket string
+	unixURL    *url.URL
+	unixErr    error
+	// maxResponseTime is the response time beyond which a healthy endpoint is reported as degraded
+	maxResponseTime time.Duration
+	// maxBodySize and bodyReadTimeout guard the reading of the response body, see newEndpoint for the default size
+	maxBodySize     int64
+	bodyReadTimeout time.Duration
+
+	successes successTracker
+	// disabled endpoints are not checked
+	disabled atomic.Bool
+}
+
+// An EndpointOption customizes the request sent to an endpoint registered with the EndpointWithOptions method
+type EndpointOption func(*endpoint)
+
+// Method sets the HTTP method of the request sent to the endpoint. The default method is GET.
+func Method(method string) EndpointOption {
+	return func(e *endpoint) {
+		e.req.Method = method
+	}
+}
+
+// Header adds a header to the request sent to the endpoint
+func Header(key, value string) EndpointOption {
+	return func(e *endpoint) {
+		e.req.Header.Add(key, value)
+	}
+}
+
+// Body sets the body of the request sent to the endpoint. The same body is sent every time the endpoint is checked.
+func Body(body []byte) EndpointOption {
+	return func(e *endpoint) {
+		e.body = body
+	}
+}
+
+type endpointMode int
+
+const (
+	inferredMode endpointMode = iota
+	rawMode
+	peerMode
+)
+
+// Raw treats the endpoint as a plain HTTP service, rather than another detective instance, even if it has no expectations set with the Expect options. The endpoint is healthy if it responds with a 2xx status, or one of the expected statuses, and its response body is not parsed as a detective state.
+func Raw() EndpointOption {
+	return func(e *endpoint) {
+		e.mode = rawMode
+	}
+}
+
+// Peer treats the endpoint as another detective instance, whose state is parsed from its response body and embedded in the state of this instance, even if expectations are set with the Expect options, which are then ignored.
+func Peer() EndpointOption {
+	return func(e *endpoint) {
+		e.mode = peerMode
+	}
+}
+
+// MaxResponseTime sets the maximum acceptable response time of the endpoint. Endpoints that respond slower than the threshold are reported as degraded, even if their response is otherwise healthy, since latency regressions often precede outages.
+func MaxResponseTime(d time.Duration) EndpointOption {
+	return func(e *endpoint) {
+		e.maxResponseTime = d
+	}
+}
+
+// MaxBodySize sets the maximum number of bytes read from the response body of the endpoint, so that a misbehaving service streaming a huge response cannot exhaust the memory of the application. Endpoints whose body exceeds the limit are reported as unhealthy. The default limit is 10 MiB, and a limit of 0 or less removes it.
+func MaxBodySize(n int64) EndpointOption {
+	return func(e *endpoint) {
+		e.maxBodySize = n
+	}
+}
+
+// BodyReadTimeout sets the maximum duration of reading the response body of the endpoint, once its headers have been received, so that a service that responds quickly but streams its body slowly cannot stall health checks. Endpoints whose body takes longer to read are reported as unhealthy.
+func BodyReadTimeout(d time.Duration) EndpointOption {
+	return func(e *endpoint) {
+		e.bodyReadTimeout = d
+	}
 }
 
-func (e *endpoint) getState(fromChain string) State {
+// defaultMaxBodySize is the maximum size of the response body of an endpoint without a MaxBodySize option
+const defaultMaxBodySize = 10 << 20
+
+func (e *endpoint) getState(ctx context.Context, fromChain string) State {
+	s := e.fetchState(ctx, fromChain)
+	if e.maxResponseTime > 0 && s.Latency > e.maxResponseTime && effectiveHealth(s) == Healthy {
+		return s.withDegraded(fmt.Errorf("response time of %s exceeds %s", s.Latency.Round(time.Millisecond), e.maxResponseTime))
+	}
+	return s
+}
+
+func (e *endpoint) fetchState(ctx context.Context, fromChain string) State {
 	init := time.Now()
-	currentReq := e.req
+	// The request is shared between concurrent health checks, so the headers are copied before being modified
+	currentReq := e.req.WithContext(ctx)
+	currentReq.Header = cloneHeader(e.req.Header)
 	currentReq.Header.Set(fromHeader, fromChain)
-	res, err := e.client.Do(&currentReq)
+	if e.unixErr != nil {
+		return State{Name: e.name}.withError(e.unixErr)
+	}
+	if e.unixURL != nil {
+		currentReq.URL = e.unixURL
+		currentReq.Host = e.unixURL.Host
+	}
+	injectTraceContext(ctx, currentReq.Header)
+	for _, authorize := range e.authorizers {
+		if err := authorize(ctx, currentReq); err != nil {
+			return State{Name: e.name}.withLatency(time.Now().Sub(init)).withError(err)
+		}
+	}
+	if e.body != nil {
+		currentReq.Body = ioutil.NopCloser(bytes.NewReader(e.body))
+		currentReq.ContentLength = int64(len(e.body))
+	}
+	// Cancelling the request aborts the reading of its body, once the body read timeout is exceeded
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	currentReq = currentReq.WithContext(ctx)
+	res, err := e.httpClient().Do(currentReq)
 	diff := time.Now().Sub(init)
-	s := State{Name: e.name, Latency: diff}
+	s := State{Name: e.name}.withLatency(diff)
 	if err != nil {
 		return s.withError(err)
 	}
+	var timedOut atomic.Bool
+	if e.bodyReadTimeout > 0 {
+		defer time.AfterFunc(e.bodyReadTimeout, func() {
+			timedOut.Store(true)
+			cancel()
+		}).Stop()
+	}
+	if res.Body != nil {
+		defer res.Body.Close()
+		if res.Body != http.NoBody {
+			res.Body = &guardedBody{ReadCloser: res.Body, remaining: e.maxBodySize, max: e.maxBodySize, timeout: e.bodyReadTimeout, timedOut: &timedOut}
+		}
+	}
+	if e.isPlain() {
+		return e.plainState(res, diff)
+	}
 	if res.StatusCode != http.StatusOK {
+		// Unhealthy detective instances respond with an error status, but still include their state in the response body
+		var state State
+		// A state that claims to be healthy along with an error status cannot be trusted
+		errorStatus := res.StatusCode < 200 || res.StatusCode >= 300
+		if res.Body != nil && json.NewDecoder(res.Body).Decode(&state) == nil && state.Name != "" && !(errorStatus && effectiveHealth(state) == Healthy) {
+			return state.withLatency(diff).withDefaultHealth()
+		}
 		return s.withError(errors.New("service " + e.name + " returned http status: " + res.Status))
 	}
-	if res.Body == nil {
+	if res.Body == nil || res.Body == http.NoBody {
 		return s.withError(errors.New("service " + e.name + " returned no response body"))
 	}
-	defer res.Body.Close()
 	var state State
 	if err := json.NewDecoder(res.Body).Decode(&state); err != nil {
 		return s.withError(err)
 	}
-	state.Latency = diff
-	return state
+	return state.withLatency(diff).withDefaultHealth()
+}
+
+// guardedBody is the response body of an endpoint, which returns an error once more than max bytes have been read, or if reading it took longer than timeout
+type guardedBody struct {
+	io.ReadCloser
+	remaining, max int64
+	timeout        time.Duration
+	timedOut       *atomic.Bool
+}
+
+func (b *guardedBody) Read(p []byte) (int, error) {
+	if b.max > 0 {
+		if b.remaining < 0 {
+			return 0, fmt.Errorf("response body exceeds %d bytes", b.max)
+		}
+		// One more byte than the remaining size is read, to detect bodies that are larger than the limit
+		if int64(len(p)) > b.remaining+1 {
+			p = p[:b.remaining+1]
+		}
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.max > 0 && b.remaining < 0 {
+		return 0, fmt.Errorf("response body exceeds %d bytes", b.max)
+	}
+	if err != nil && err != io.EOF && b.timedOut.Load() {
+		return n, fmt.Errorf("reading the response body took longer than %s", b.timeout)
+	}
+	return n, err
+}
+
+// isPlain returns true if the endpoint is treated as a plain HTTP service, rather than another detective instance. Unless the mode is set with the Raw or Peer options, endpoints with expectations on their response are plain.
+func (e *endpoint) isPlain() bool {
+	switch e.mode {
+	case rawMode:
+		return true
+	case peerMode:
+		return false
+	}
+	return len(e.statuses) > 0 || len(e.bodyMatchers) > 0
+}
+
+func (e *endpoint) plainState(res *http.Response, latency time.Duration) State {
+	s := State{Name: e.req.URL.String()}.withLatency(latency)
+	if !e.acceptsStatus(res.StatusCode) {
+		return s.withError(errors.New("service " + s.Name + " returned unexpected http status: " + res.Status))
+	}
+	if len(e.bodyMatchers) == 0 {
+		return s.withOk()
+	}
+	var body []byte
+	if res.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(res.Body); err != nil {
+			return s.withError(err)
+		}
+	}
+	for _, match := range e.bodyMatchers {
+		if err := match(body); err != nil {
+			return s.withError(err)
+		}
+	}
+	return s.withOk()
+}
+
+func (e *endpoint) acceptsStatus(code int) bool {
+	if len(e.statuses) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, accepts := range e.statuses {
+		if accepts(code) {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneHeader(h http.Header) http.Header {
+	h2 := make(http.Header, len(h))
+	for k, vv := range h {
+		vv2 := make([]string, len(vv))
+		copy(vv2, vv)
+		h2[k] = vv2
+	}
+	return h2
 }
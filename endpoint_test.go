@@ -1,12 +1,18 @@
 package detective
 
 import (
+	"context"
 	"errors"
 	dm "github.com/sohamkamani/detective/mock"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEndpoint(t *testing.T) {
@@ -54,6 +60,47 @@ func TestEndpoint(t *testing.T) {
 				Status: "Error: service sample returned http status: 500 Internal Server Error",
 			},
 		},
+		{
+			name:       "unhealthy detective instance",
+			httpStatus: http.StatusServiceUnavailable,
+			jsonResponse: `{
+				"name":"sample",
+				"active": false,
+				"status":"Error: dependency failure",
+				"dependencies":[
+					{
+						"name":"dep1",
+						"active": false,
+						"status":"Error: failed"
+					}
+				]
+			}`,
+			expectedState: State{
+				Name:   "sample",
+				Ok:     false,
+				Status: "Error: dependency failure",
+				Dependencies: []State{
+					{
+						Name:   "dep1",
+						Ok:     false,
+						Status: "Error: failed",
+					},
+				},
+			},
+		},
+		{
+			name:       "healthy state with error status",
+			httpStatus: http.StatusInternalServerError,
+			jsonResponse: `{
+				"name":"remote",
+				"active": true
+			}`,
+			expectedState: State{
+				Name:   "sample",
+				Ok:     false,
+				Status: "Error: service sample returned http status: 500 Internal Server Error",
+			},
+		},
 		{
 			name:       "empty body",
 			httpStatus: http.StatusOK,
@@ -100,8 +147,142 @@ func TestEndpoint(t *testing.T) {
 				req:    *req,
 			}
 
-			s := e.getState("")
+			s := e.getState(context.Background(), "")
 			assertStatesEqual(t, tt.expectedState, s)
 		})
 	}
 }
+
+func TestEndpointWithOptions(t *testing.T) {
+	var gotMethod, gotHeader string
+	var gotBodies []string
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotHeader = r.Header.Get("Authorization")
+		body, _ := ioutil.ReadAll(r.Body)
+		gotBodies = append(gotBodies, string(body))
+		w.Write([]byte(`{"name":"remote","status":"Ok", "active":true}`))
+	}))
+	defer remote.Close()
+
+	d := New("sample")
+	err := d.EndpointWithOptions(remote.URL,
+		Method(http.MethodPost),
+		Header("Authorization", "Bearer token"),
+		Body([]byte(`{"ping":true}`)),
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		s := d.GetStateContext(context.Background())
+		assert.True(t, s.Ok)
+	}
+	assert.Equal(t, http.MethodPost, gotMethod)
+	assert.Equal(t, "Bearer token", gotHeader)
+	assert.Equal(t, []string{`{"ping":true}`, `{"ping":true}`}, gotBodies, "body should be sent on every check")
+
+	assert.Error(t, d.EndpointWithOptions("://invalid"))
+}
+
+func TestMaxResponseTime(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if r.URL.Path == "/failing" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			time.Sleep(50 * time.Millisecond)
+			return
+		}
+		w.Write([]byte(`{"name":"remote","status":"Ok", "active":true}`))
+	}))
+	defer remote.Close()
+
+	tests := []struct {
+		path     string
+		expected Health
+		err      string
+	}{
+		{"/fast", Healthy, ""},
+		{"/slow", Degraded, "response time of"},
+		{"/failing", Unhealthy, "service sample returned http status: 503 Service Unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			d := New("sample")
+			require.NoError(t, d.EndpointWithOptions(remote.URL+tt.path, MaxResponseTime(20*time.Millisecond)))
+			s := d.GetState().Dependencies[0]
+			assert.Equal(t, tt.expected, s.Health)
+			assert.True(t, strings.HasPrefix(s.Error, tt.err), s.Error)
+			if tt.expected == Degraded {
+				assert.True(t, strings.HasSuffix(s.Error, "exceeds 20ms"), s.Error)
+				assert.True(t, s.Ok)
+			}
+		})
+	}
+}
+
+func TestEndpointBodyGuards(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Write([]byte(strings.Repeat("a", 2048)))
+		case "/slow":
+			w.Write([]byte("ok"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer remote.Close()
+
+	tests := []struct {
+		path string
+		opts []EndpointOption
+		err  string
+	}{
+		{"/small", []EndpointOption{MaxBodySize(1024)}, ""},
+		{"/large", []EndpointOption{MaxBodySize(1024)}, "response body exceeds 1024 bytes"},
+		{"/large", []EndpointOption{MaxBodySize(2048)}, ""},
+		{"/large", []EndpointOption{MaxBodySize(0)}, ""},
+		{"/slow", []EndpointOption{BodyReadTimeout(20 * time.Millisecond)}, "reading the response body took longer than 20ms"},
+		{"/small", []EndpointOption{BodyReadTimeout(20 * time.Millisecond)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			d := New("sample")
+			require.NoError(t, d.EndpointWithOptions(remote.URL+tt.path, append(tt.opts, ExpectBodyContains(""))...))
+			s := d.GetState().Dependencies[0]
+			assert.Equal(t, tt.err, s.Error)
+			assert.Equal(t, tt.err == "", s.Ok)
+		})
+	}
+}
+
+func TestEndpointModes(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer plain.Close()
+	remote := New("remote")
+	remote.Dependency("db")
+	peer := httptest.NewServer(remote)
+	defer peer.Close()
+
+	d := New("sample")
+	require.NoError(t, d.Endpoint(plain.URL))
+	require.NoError(t, d.EndpointWithOptions(plain.URL, Raw()))
+	require.NoError(t, d.EndpointWithOptions(peer.URL, Raw()))
+	require.NoError(t, d.EndpointWithOptions(peer.URL, ExpectStatus(http.StatusNoContent), Peer()))
+	deps := d.GetState().Dependencies
+	require.Len(t, deps, 4)
+	// Without a mode, an endpoint without expectations is assumed to be a detective instance
+	assert.False(t, deps[0].Ok)
+	assertStatesEqual(t, State{Name: plain.URL, Ok: true, Status: "Ok"}, deps[1])
+	assertStatesEqual(t, State{Name: peer.URL, Ok: true, Status: "Ok"}, deps[2])
+	assertStatesEqual(t, State{Name: "remote", Ok: true, Status: "Ok", Dependencies: []State{{Name: "db", Ok: true, Status: "Ok"}}}, deps[3])
+}
@@ -0,0 +1,81 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// EtcdConfig configures the detector registered with DetectEtcd
+type EtcdConfig struct {
+	// Client is used to send requests to the etcd member. If it is nil, http.DefaultClient is used. Set a client with a TLS configuration for members that require client certificates.
+	Client Doer
+}
+
+// DetectEtcd registers a detector function that checks the health of an etcd member with its /health endpoint, at a URL like "http://localhost:2379". The dependency is unhealthy if the member reports that it is unhealthy (for example, if it has no leader), or if the cluster has active alarms (like NOSPACE).
+// The alarms are listed with the gRPC gateway of the member, and are reported in the details of the state of the dependency. Members without a gateway are only checked with their /health endpoint. The requests are bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectEtcd(memberURL string, c EtcdConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		base, err := url.Parse(strings.TrimSuffix(memberURL, "/"))
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest(http.MethodGet, base.String()+"/health", nil)
+		if err != nil {
+			return err
+		}
+		var health struct {
+			Health string `json:"health"`
+			Reason string `json:"reason"`
+		}
+		// Unhealthy members respond with a 503 status and the same body, so the body is decoded for any status
+		code, err := doJSON(c.Client, req.WithContext(ctx), &health)
+		if err != nil {
+			return err
+		}
+		if code != http.StatusOK && code != http.StatusServiceUnavailable {
+			return fmt.Errorf("etcd health endpoint returned http status: %d", code)
+		}
+
+		req, err = http.NewRequest(http.MethodPost, base.String()+"/v3/maintenance/alarm", strings.NewReader(`{"action": "GET"}`))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		var alarms struct {
+			Alarms []struct {
+				MemberID string `json:"memberID"`
+				Alarm    string `json:"alarm"`
+			} `json:"alarms"`
+		}
+		code, err = doJSON(c.Client, req.WithContext(ctx), &alarms)
+		if err != nil {
+			return err
+		}
+		var active []string
+		if code == http.StatusOK {
+			for _, a := range alarms.Alarms {
+				if a.Alarm != "" && a.Alarm != "NONE" {
+					active = append(active, a.Alarm+" (member "+a.MemberID+")")
+				}
+			}
+			SetDetail(ctx, "alarms", len(active))
+		}
+		if len(active) > 0 {
+			return errors.New("etcd alarms in effect: " + strings.Join(active, ", "))
+		}
+		if health.Health != "true" {
+			if health.Reason != "" {
+				return errors.New("etcd member is unhealthy: " + health.Reason)
+			}
+			return errors.New("etcd member is unhealthy")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,53 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectEtcd(t *testing.T) {
+	tests := []struct {
+		name    string
+		health  string
+		gateway bool
+		alarms  string
+		status  string
+		details map[string]interface{}
+	}{
+		{name: "healthy", health: `{"health": "true", "reason": ""}`, gateway: true, alarms: `{"header": {}}`, status: "Ok", details: map[string]interface{}{"alarms": 0}},
+		{name: "without gateway", health: `{"health": "true", "reason": ""}`, status: "Ok"},
+		{name: "unhealthy", health: `{"health": "false", "reason": "RAFT NO LEADER"}`, status: "Error: etcd member is unhealthy: RAFT NO LEADER"},
+		{name: "alarms", health: `{"health": "false", "reason": "ALARM NOSPACE"}`, gateway: true, alarms: `{"alarms": [{"memberID": "9372538179322589801", "alarm": "NOSPACE"}]}`, status: "Error: etcd alarms in effect: NOSPACE (member 9372538179322589801)", details: map[string]interface{}{"alarms": 1}},
+		{name: "unexpected status", status: "Error: etcd health endpoint returned http status: 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/health" && tt.health != "":
+					if tt.health != `{"health": "true", "reason": ""}` {
+						w.WriteHeader(http.StatusServiceUnavailable)
+					}
+					w.Write([]byte(tt.health))
+				case r.URL.Path == "/v3/maintenance/alarm" && tt.gateway:
+					body, _ := io.ReadAll(r.Body)
+					assert.JSONEq(t, `{"action": "GET"}`, string(body))
+					w.Write([]byte(tt.alarms))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte("404 page not found"))
+				}
+			}))
+			defer s.Close()
+			d := newDependency("etcd")
+			d.DetectEtcd(s.URL, EtcdConfig{})
+			st := d.getState(context.Background())
+			assert.Equal(t, tt.status, st.Status)
+			assert.Equal(t, tt.details, st.Details)
+		})
+	}
+}
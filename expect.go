@@ -0,0 +1,122 @@
+package detective
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type statusMatcher func(code int) bool
+
+type bodyMatcher func(body []byte) error
+
+// ExpectStatus sets the HTTP status codes that an endpoint is allowed to respond with. Endpoints that have expectations on their response are treated as plain HTTP services, instead of other detective instances. When only body expectations are set, any 2xx status code is accepted.
+// ExpectStatus can be combined with ExpectStatusRange, in which case a status code matching any of them is accepted.
+func ExpectStatus(codes ...int) EndpointOption {
+	return func(e *endpoint) {
+		e.statuses = append(e.statuses, func(code int) bool {
+			for _, c := range codes {
+				if c == code {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
+
+// ExpectStatusRange is similar to ExpectStatus, but accepts any status code between min and max (inclusive)
+func ExpectStatusRange(min, max int) EndpointOption {
+	return func(e *endpoint) {
+		e.statuses = append(e.statuses, func(code int) bool {
+			return code >= min && code <= max
+		})
+	}
+}
+
+// ExpectBodyContains requires the response body of an endpoint to contain the provided string
+func ExpectBodyContains(substr string) EndpointOption {
+	return func(e *endpoint) {
+		e.bodyMatchers = append(e.bodyMatchers, func(body []byte) error {
+			if !bytes.Contains(body, []byte(substr)) {
+				return errors.New("response body does not contain " + strconv.Quote(substr))
+			}
+			return nil
+		})
+	}
+}
+
+// ExpectBodyMatches requires the response body of an endpoint to match the provided regular expression
+func ExpectBodyMatches(re *regexp.Regexp) EndpointOption {
+	return func(e *endpoint) {
+		e.bodyMatchers = append(e.bodyMatchers, func(body []byte) error {
+			if !re.Match(body) {
+				return errors.New("response body does not match " + strconv.Quote(re.String()))
+			}
+			return nil
+		})
+	}
+}
+
+// ExpectJSONField requires the response body of an endpoint to be a JSON document, in which the value at the provided path equals the provided value. The path is a dot separated list of object keys and array indices, like "data.checks.0.status".
+func ExpectJSONField(path string, value interface{}) EndpointOption {
+	return func(e *endpoint) {
+		e.bodyMatchers = append(e.bodyMatchers, func(body []byte) error {
+			var doc interface{}
+			if err := json.Unmarshal(body, &doc); err != nil {
+				return err
+			}
+			got, ok := lookupJSONPath(doc, path)
+			if !ok {
+				return errors.New("response body has no field " + strconv.Quote(path))
+			}
+			want, err := normalizeJSON(value)
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				return errors.New("response body field " + strconv.Quote(path) + " is " + string(gotJSON))
+			}
+			return nil
+		})
+	}
+}
+
+func lookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	current := doc
+	for _, key := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			current = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// normalizeJSON converts a value into the representation produced by decoding JSON into an empty interface, so that it can be compared with decoded values
+func normalizeJSON(value interface{}) (interface{}, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(b, &v)
+	return v, err
+}
@@ -0,0 +1,118 @@
+package detective
+
+import (
+	"context"
+	dm "github.com/sohamkamani/detective/mock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestEndpointExpectations(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []EndpointOption
+		httpStatus    int
+		response      string
+		expectedState State
+	}{
+		{
+			name:          "expected status",
+			opts:          []EndpointOption{ExpectStatus(http.StatusNoContent)},
+			httpStatus:    http.StatusNoContent,
+			expectedState: State{Name: "http://mock.com/health", Ok: true, Status: "Ok"},
+		},
+		{
+			name:          "unexpected status",
+			opts:          []EndpointOption{ExpectStatus(http.StatusNoContent)},
+			httpStatus:    http.StatusOK,
+			expectedState: State{Name: "http://mock.com/health", Ok: false, Status: "Error: service http://mock.com/health returned unexpected http status: 200 OK"},
+		},
+		{
+			name:          "status range",
+			opts:          []EndpointOption{ExpectStatus(http.StatusNotFound), ExpectStatusRange(200, 299)},
+			httpStatus:    http.StatusAccepted,
+			expectedState: State{Name: "http://mock.com/health", Ok: true, Status: "Ok"},
+		},
+		{
+			name:          "error page with body expectations",
+			opts:          []EndpointOption{ExpectBodyContains("ok")},
+			httpStatus:    http.StatusInternalServerError,
+			response:      "ok",
+			expectedState: State{Name: "http://mock.com/health", Ok: false, Status: "Error: service http://mock.com/health returned unexpected http status: 500 Internal Server Error"},
+		},
+		{
+			name:          "body contains",
+			opts:          []EndpointOption{ExpectBodyContains("pong")},
+			httpStatus:    http.StatusOK,
+			response:      "ping pong",
+			expectedState: State{Name: "http://mock.com/health", Ok: true, Status: "Ok"},
+		},
+		{
+			name:          "body does not contain",
+			opts:          []EndpointOption{ExpectBodyContains("pong")},
+			httpStatus:    http.StatusOK,
+			response:      "ping",
+			expectedState: State{Name: "http://mock.com/health", Ok: false, Status: `Error: response body does not contain "pong"`},
+		},
+		{
+			name:          "body matches",
+			opts:          []EndpointOption{ExpectBodyMatches(regexp.MustCompile(`^status: (up|ok)$`))},
+			httpStatus:    http.StatusOK,
+			response:      "status: up",
+			expectedState: State{Name: "http://mock.com/health", Ok: true, Status: "Ok"},
+		},
+		{
+			name:          "body does not match",
+			opts:          []EndpointOption{ExpectBodyMatches(regexp.MustCompile(`^status: (up|ok)$`))},
+			httpStatus:    http.StatusOK,
+			response:      "status: down",
+			expectedState: State{Name: "http://mock.com/health", Ok: false, Status: `Error: response body does not match "^status: (up|ok)$"`},
+		},
+		{
+			name:          "json field equals",
+			opts:          []EndpointOption{ExpectJSONField("checks.1.status", "up"), ExpectJSONField("version", 2)},
+			httpStatus:    http.StatusOK,
+			response:      `{"version": 2, "checks": [{"status": "down"}, {"status": "up"}]}`,
+			expectedState: State{Name: "http://mock.com/health", Ok: true, Status: "Ok"},
+		},
+		{
+			name:          "json field differs",
+			opts:          []EndpointOption{ExpectJSONField("checks.0.status", "up")},
+			httpStatus:    http.StatusOK,
+			response:      `{"checks": [{"status": "down"}]}`,
+			expectedState: State{Name: "http://mock.com/health", Ok: false, Status: `Error: response body field "checks.0.status" is "down"`},
+		},
+		{
+			name:          "json field missing",
+			opts:          []EndpointOption{ExpectJSONField("checks.3.status", "up")},
+			httpStatus:    http.StatusOK,
+			response:      `{"checks": []}`,
+			expectedState: State{Name: "http://mock.com/health", Ok: false, Status: `Error: response body has no field "checks.3.status"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &dm.MockClient{}
+			mockClient.On("Do", mock.Anything).Return(dm.MockJSONResponse(tt.response, tt.httpStatus), nil)
+
+			req, err := http.NewRequest(http.MethodGet, "http://mock.com/health", nil)
+			require.NoError(t, err)
+
+			e := &endpoint{
+				name:   "sample",
+				client: mockClient,
+				req:    *req,
+			}
+			for _, opt := range tt.opts {
+				opt(e)
+			}
+
+			s := e.getState(context.Background(), "")
+			assertStatesEqual(t, tt.expectedState, s)
+		})
+	}
+}
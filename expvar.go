@@ -0,0 +1,65 @@
+package detective
+
+import (
+	"expvar"
+	"sync"
+)
+
+// expvarDetectives is published as "detective" in /debug/vars, with a key for each instance created with WithExpvar
+var expvarDetectives = expvar.NewMap("detective")
+
+type expvarStats struct {
+	mu           sync.Mutex
+	state        *State
+	dependencies map[string]*expvarCounters
+}
+
+type expvarCounters struct {
+	Successes     int64   `json:"successes"`
+	Failures      int64   `json:"failures"`
+	LastLatencyMs float64 `json:"last_latency_ms"`
+}
+
+type expvarValue struct {
+	State        *State                    `json:"state"`
+	Dependencies map[string]expvarCounters `json:"dependencies"`
+}
+
+func (e *expvarStats) observe(s State) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.state = &s
+	for _, dep := range s.Dependencies {
+		c, ok := e.dependencies[dep.Name]
+		if !ok {
+			c = &expvarCounters{}
+			e.dependencies[dep.Name] = c
+		}
+		if dep.Ok {
+			c.Successes++
+		} else {
+			c.Failures++
+		}
+		c.LastLatencyMs = dep.LatencyMs
+	}
+}
+
+func (e *expvarStats) value() interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	v := expvarValue{State: e.state, Dependencies: make(map[string]expvarCounters, len(e.dependencies))}
+	for name, c := range e.dependencies {
+		v.Dependencies[name] = *c
+	}
+	return v
+}
+
+// WithExpvar publishes the latest state of the instance, along with the number of successful and failed checks, and the last latency of each dependency in the "detective" expvar map, under the name of the instance.
+// The values are served by the /debug/vars handler of the expvar package. Publishing another instance with the same name replaces the previous one.
+func WithExpvar() Option {
+	return func(d *Detective) {
+		e := &expvarStats{dependencies: map[string]*expvarCounters{}}
+		d.observers = append(d.observers, e.observe)
+		expvarDetectives.Set(d.name, expvar.Func(e.value))
+	}
+}
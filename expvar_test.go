@@ -0,0 +1,38 @@
+package detective
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestExpvar(t *testing.T) {
+	d := New("expvar-sample", WithExpvar())
+	calls := 0
+	d.Dependency("db").Detect(func() error {
+		calls++
+		if calls == 2 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	var v expvarValue
+	require.NoError(t, json.Unmarshal([]byte(expvarDetectives.Get("expvar-sample").String()), &v))
+	assert.Nil(t, v.State)
+	assert.Empty(t, v.Dependencies)
+
+	d.GetState()
+	d.GetState()
+	d.GetState()
+
+	require.NoError(t, json.Unmarshal([]byte(expvarDetectives.Get("expvar-sample").String()), &v))
+	require.NotNil(t, v.State)
+	assert.Equal(t, "expvar-sample", v.State.Name)
+	assert.True(t, v.State.Ok)
+	assert.Equal(t, int64(2), v.Dependencies["db"].Successes)
+	assert.Equal(t, int64(1), v.Dependencies["db"].Failures)
+	assert.Equal(t, v.State.Dependencies[0].LatencyMs, v.Dependencies["db"].LastLatencyMs)
+}
@@ -0,0 +1,52 @@
+package detective
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup deduplicates concurrent computations of the same state, so that simultaneous requests to the handler share a single set of checks
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done  chan struct{}
+	state State
+}
+
+// do runs fn if no computation with the same key is in flight, and otherwise waits for the result of the running computation.
+// fn runs with a context that is not cancelled when the caller that started it goes away, since other callers may still be waiting for its result. A caller whose context is done before the result is available receives a failed state instead.
+func (g *flightGroup) do(ctx context.Context, name, key string, fn func(ctx context.Context) State) State {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*flightCall{}
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		c = &flightCall{done: make(chan struct{})}
+		g.calls[key] = c
+		// The deadline of the first caller still applies, so that checks are reported as failed in the same way as without deduplication
+		fnCtx, cancel := context.WithoutCancel(ctx), context.CancelFunc(func() {})
+		if deadline, ok := ctx.Deadline(); ok {
+			fnCtx, cancel = context.WithDeadline(fnCtx, deadline)
+		}
+		go func() {
+			defer cancel()
+			c.state = fn(fnCtx)
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(c.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.state
+	case <-ctx.Done():
+		return State{Name: name}.withError(ctx.Err())
+	}
+}
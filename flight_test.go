@@ -0,0 +1,78 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServeHTTPSharesChecks(t *testing.T) {
+	d := New("sample")
+	var calls int32
+	release := make(chan struct{})
+	d.Dependency("slow").Detect(func() error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rw := httptest.NewRecorder()
+			d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+			codes[i] = rw.Code
+		}(i)
+	}
+	// Give all requests the time to join the running checks
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, []int{200, 200, 200, 200, 200}, codes)
+
+	// Once the checks are done, the next request checks the dependencies again
+	d.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestFlightGroupCancelledCaller(t *testing.T) {
+	var g flightGroup
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan State, 1)
+	go func() {
+		results <- g.do(ctx, "sample", "", func(ctx context.Context) State {
+			close(started)
+			<-release
+			return State{Name: "sample"}.withError(ctx.Err())
+		})
+	}()
+	<-started
+	cancel()
+	s := <-results
+	assert.Equal(t, Unhealthy, s.Health)
+	assert.Equal(t, "context canceled", s.Error)
+
+	// A caller that joins the running computation receives its result, which is not affected by the cancelled caller
+	go func() {
+		results <- g.do(context.Background(), "sample", "", func(ctx context.Context) State {
+			t.Error("the running computation should be shared")
+			return State{}
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	s = <-results
+	assert.Equal(t, Healthy, s.Health)
+}
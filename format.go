@@ -0,0 +1,224 @@
+package detective
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	formatJSON   = "json"
+	formatText   = "text"
+	formatNagios = "nagios"
+	formatHealth = "health"
+	formatHTML   = "html"
+)
+
+// acceptedFormats are the formats that can be requested with the Accept header
+var acceptedFormats = map[string]string{
+	"application/json":  formatJSON,
+	"*/*":               formatJSON,
+	"text/plain":        formatText,
+	"text/html":         formatHTML,
+	healthJSONMediaType: formatHealth,
+}
+
+// responseFormat returns the format requested with the format query parameter, or with the Accept header, where the supported media type with the highest quality wins. JSON is used by default.
+func responseFormat(r *http.Request) string {
+	switch f := r.URL.Query().Get("format"); f {
+	case formatJSON, formatText, formatNagios, formatHealth, formatHTML:
+		return f
+	}
+	format, quality := formatJSON, 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		f, ok := acceptedFormats[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		// Media types with the same quality are preferred in the order in which they are listed
+		if q > quality {
+			format, quality = f, q
+		}
+	}
+	return format
+}
+
+// prettyPrinted returns true if indented JSON is requested with the pretty query parameter, like "?pretty" or "?pretty=1"
+func prettyPrinted(r *http.Request) bool {
+	values, ok := r.URL.Query()["pretty"]
+	if !ok {
+		return false
+	}
+	if values[0] == "" {
+		return true
+	}
+	pretty, _ := strconv.ParseBool(values[0])
+	return pretty
+}
+
+// writeState writes the state in the format requested by the client
+func (d *Detective) writeState(w http.ResponseWriter, r *http.Request, s State) {
+	var body []byte
+	var err error
+	format := responseFormat(r)
+	switch format {
+	case formatText:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		body = textSummary(s)
+	case formatNagios:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		body = nagiosLine(s)
+	case formatHealth:
+		w.Header().Set("Content-Type", healthJSONMediaType)
+		body, err = healthJSON(s)
+	case formatHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		body, err = renderDashboard(s)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		body, err = json.Marshal(s)
+	}
+	if err == nil && (format == formatJSON || format == formatHealth) && prettyPrinted(r) {
+		var indented bytes.Buffer
+		if err = json.Indent(&indented, body, "", "  "); err == nil {
+			body = indented.Bytes()
+		}
+	}
+	if err != nil {
+		d.log(slog.LevelError, fmt.Sprintf("detective %s: failed to encode state: %v", d.name, err), slog.Any("error", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if d.cacheControl != "" {
+		w.Header().Set("Cache-Control", d.cacheControl)
+	}
+	tag := etag(body)
+	compress := len(body) > compressionThreshold && acceptsGzip(r)
+	if len(body) > compressionThreshold {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	if compress {
+		// The compressed representation has its own tag
+		tag = strings.TrimSuffix(tag, `"`) + `-gzip"`
+	}
+	w.Header().Set("ETag", tag)
+	code := d.statusCode(s)
+	// Conditional requests only apply to successful responses, so that probes still see error statuses
+	if code >= 200 && code < 300 && etagMatches(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if compress {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(code)
+		gz := gzip.NewWriter(w)
+		gz.Write(body)
+		gz.Close()
+		return
+	}
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+// compressionThreshold is the size of the response body beyond which it is compressed for clients that accept gzip. Smaller bodies are not worth the overhead.
+const compressionThreshold = 1024
+
+// acceptsGzip returns true if the Accept-Encoding header of the request lists gzip, with a non-zero quality
+func acceptsGzip(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(accepted), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if quality, err := strconv.ParseFloat(q, 64); err != nil || quality == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// etag returns a strong entity tag of the response body
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches returns true if the If-None-Match header lists the entity tag, or is "*". Weak tags are compared by their value, as required for If-None-Match.
+func etagMatches(ifNoneMatch, tag string) bool {
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// textSummary describes the state, and all of its dependencies on one line each, indented by their depth in the tree
+func textSummary(s State) []byte {
+	var b bytes.Buffer
+	var write func(s State, depth int)
+	write = func(s State, depth int) {
+		fmt.Fprintf(&b, "%s%s: %s (%s)", strings.Repeat("  ", depth), s.Name, effectiveHealth(s), s.Latency)
+		if s.Error != "" {
+			b.WriteString(" " + s.Error)
+		}
+		b.WriteString("\n")
+		for _, dep := range s.Dependencies {
+			write(dep, depth+1)
+		}
+	}
+	write(s, 0)
+	return b.Bytes()
+}
+
+var nagiosStatus = map[Health]string{
+	Healthy:   "OK",
+	Degraded:  "WARNING",
+	Unhealthy: "CRITICAL",
+}
+
+// nagiosLine describes the state in the output format of Nagios plugins: a status word followed by a message, and the latency of each dependency as performance data
+func nagiosLine(s State) []byte {
+	h := effectiveHealth(s)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s - %s is %s", nagiosStatus[h], s.Name, h)
+	var failed []string
+	for _, dep := range s.Dependencies {
+		if effectiveHealth(dep) != Healthy {
+			failed = append(failed, dep.Name)
+		}
+	}
+	if len(failed) > 0 {
+		b.WriteString(": " + strings.Join(failed, ", "))
+	}
+	if len(s.Dependencies) > 0 {
+		b.WriteString(" |")
+		for _, dep := range s.Dependencies {
+			fmt.Fprintf(&b, " '%s'=%sms", strings.Replace(dep.Name, "'", "''", -1), formatFloat(dep.LatencyMs))
+		}
+	}
+	b.WriteString("\n")
+	return b.Bytes()
+}
@@ -0,0 +1,188 @@
+package detective
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseFormat(t *testing.T) {
+	tests := []struct {
+		url      string
+		accept   string
+		expected string
+	}{
+		{"/", "", formatJSON},
+		{"/?format=text", "", formatText},
+		{"/?format=nagios", "application/json", formatNagios},
+		{"/?format=unknown", "", formatJSON},
+		{"/", "text/plain", formatText},
+		{"/", "text/html, text/plain;q=0.9", formatHTML},
+		{"/", "text/plain;q=0.5, application/json", formatJSON},
+		{"/", "text/html;q=0, text/plain;q=0.1", formatText},
+		{"/", "image/png", formatJSON},
+		{"/?format=html", "", formatHTML},
+		{"/", "application/json, text/plain", formatJSON},
+		{"/", "*/*", formatJSON},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		assert.Equal(t, tt.expected, responseFormat(r), "%s with Accept: %s", tt.url, tt.accept)
+	}
+}
+
+func sampleFormatState() State {
+	return State{Name: "sample"}.withDependencies([]State{
+		State{Name: "db"}.withError(errors.New("connection refused")).withLatency(12 * time.Millisecond),
+		State{Name: "cache", NonCritical: true}.withOk().withLatency(1500 * time.Microsecond),
+		State{Name: "peer"}.withDependencies([]State{
+			State{Name: "queue"}.withOk().withLatency(time.Millisecond),
+		}).withLatency(3 * time.Millisecond),
+	}).withLatency(12 * time.Millisecond)
+}
+
+func TestTextSummary(t *testing.T) {
+	expected := strings.Join([]string{
+		"sample: unhealthy (12ms) dependency failure",
+		"  db: unhealthy (12ms) connection refused",
+		"  cache: healthy (1.5ms)",
+		"  peer: healthy (3ms)",
+		"    queue: healthy (1ms)",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, string(textSummary(sampleFormatState())))
+}
+
+func TestNagiosLine(t *testing.T) {
+	assert.Equal(t, "CRITICAL - sample is unhealthy: db | 'db'=12ms 'cache'=1.5ms 'peer'=3ms\n", string(nagiosLine(sampleFormatState())))
+	assert.Equal(t, "WARNING - sample is degraded: cache's | 'cache''s'=0ms\n", string(nagiosLine(State{Name: "sample"}.withDependencies([]State{
+		State{Name: "cache's", NonCritical: true}.withError(errors.New("failed")),
+	}))))
+	assert.Equal(t, "OK - sample is healthy\n", string(nagiosLine(State{Name: "sample"}.withOk())))
+}
+
+func TestServeHTTPFormats(t *testing.T) {
+	d := New("sample")
+	d.Dependency("db").Detect(func() error { return errors.New("connection refused") })
+
+	rw := httptest.NewRecorder()
+	d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?format=nagios", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rw.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rw.Body.String(), "CRITICAL - sample is unhealthy: db | 'db'="), rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/plain")
+	d.ServeHTTP(rw, r)
+	assert.True(t, strings.HasPrefix(rw.Body.String(), "sample: unhealthy ("), rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	d.ServeHTTP(rw, r)
+	assert.Equal(t, "text/html; charset=utf-8", rw.Header().Get("Content-Type"))
+	assert.Contains(t, rw.Body.String(), "<h1>sample</h1>")
+
+	rw = httptest.NewRecorder()
+	d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rw.Body.String(), `{"name":"sample",`), rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?pretty=1", nil))
+	assert.True(t, strings.HasPrefix(rw.Body.String(), "{\n  \"name\": \"sample\",\n"), rw.Body.String())
+}
+
+func TestServeHTTPConditional(t *testing.T) {
+	d := New("sample", WithCacheTTL(time.Minute), WithCacheControl("max-age=5"))
+	d.Dependency("db").Detect(func() error { return nil })
+
+	rw := httptest.NewRecorder()
+	d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "max-age=5", rw.Header().Get("Cache-Control"))
+	tag := rw.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, tag)
+
+	for _, ifNoneMatch := range []string{tag, `"other", W/` + tag, "*"} {
+		rw = httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-None-Match", ifNoneMatch)
+		d.ServeHTTP(rw, r)
+		assert.Equal(t, http.StatusNotModified, rw.Code, ifNoneMatch)
+		assert.Empty(t, rw.Body.String())
+		assert.Equal(t, tag, rw.Header().Get("ETag"))
+	}
+
+	// The text format has a different body, and a different tag
+	rw = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/?format=text", nil)
+	r.Header.Set("If-None-Match", tag)
+	d.ServeHTTP(rw, r)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.NotEqual(t, tag, rw.Header().Get("ETag"))
+
+	// Error statuses are never replaced by a 304 status
+	failing := New("failing")
+	failing.Dependency("db").Detect(func() error { return errors.New("connection refused") })
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", "*")
+	rw = httptest.NewRecorder()
+	failing.ServeHTTP(rw, r)
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Empty(t, rw.Header().Get("Cache-Control"))
+}
+
+func TestServeHTTPCompression(t *testing.T) {
+	d := New("sample")
+	for i := 0; i < 50; i++ {
+		d.Dependency(fmt.Sprintf("dependency-%d", i))
+	}
+	small := New("small")
+
+	tests := []struct {
+		name           string
+		d              *Detective
+		acceptEncoding string
+		compressed     bool
+	}{
+		{"gzip", d, "gzip, deflate, br", true},
+		{"quality", d, "gzip;q=0.5", true},
+		{"refused", d, "gzip;q=0", false},
+		{"identity", d, "", false},
+		{"small", small, "gzip", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			tt.d.ServeHTTP(rw, r)
+			assert.Equal(t, http.StatusOK, rw.Code)
+			var body io.Reader = rw.Body
+			if tt.compressed {
+				assert.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+				assert.True(t, strings.HasSuffix(rw.Header().Get("ETag"), `-gzip"`))
+				gz, err := gzip.NewReader(rw.Body)
+				assert.NoError(t, err)
+				body = gz
+			} else {
+				assert.Empty(t, rw.Header().Get("Content-Encoding"))
+			}
+			decoded, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(decoded), `{"name":"`+tt.d.name+`"`), string(decoded))
+		})
+	}
+}
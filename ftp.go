@@ -0,0 +1,113 @@
+package detective
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+)
+
+// FTPConfig configures the detector registered with DetectFTP
+type FTPConfig struct {
+	// User and Password are the credentials used to log in. If the User is empty, the anonymous user is used.
+	User     string
+	Password string
+	// Path is a file or directory that is expected to exist. If set, the dependency is unhealthy unless the path exists.
+	Path string
+	// TLSConfig is used to upgrade the connection with the AUTH TLS command (explicit FTPS), if set. If the ServerName is not set, the host of the address is used.
+	TLSConfig *tls.Config
+}
+
+// DetectFTP registers a detector function that logs in to an FTP server (for example, "ftp.example.com:21"). If a path is configured, the detector checks that it exists, as a directory or as a file. No data connection is opened.
+// The check is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectFTP(addr string, c FTPConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		stop := context.AfterFunc(ctx, func() {
+			conn.Close()
+		})
+		defer stop()
+
+		text := textproto.NewConn(conn)
+		if _, _, err := text.ReadResponse(220); err != nil {
+			return err
+		}
+		if c.TLSConfig != nil {
+			if err := ftpCommand(text, 234, "AUTH TLS"); err != nil {
+				return err
+			}
+			config := c.TLSConfig.Clone()
+			if config.ServerName == "" {
+				config.ServerName, _, _ = net.SplitHostPort(addr)
+			}
+			tlsConn := tls.Client(conn, config)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				return err
+			}
+			text = textproto.NewConn(tlsConn)
+		}
+
+		user, password := c.User, c.Password
+		if user == "" {
+			user, password = "anonymous", "anonymous@"
+		}
+		id, err := text.Cmd("USER %s", user)
+		if err != nil {
+			return err
+		}
+		text.StartResponse(id)
+		code, msg, err := text.ReadResponse(0)
+		text.EndResponse(id)
+		if err != nil {
+			return err
+		}
+		if code == 331 || code == 332 {
+			if err := ftpCommand(text, 230, "PASS %s", password); err != nil {
+				return fmt.Errorf("ftp login failed: %w", err)
+			}
+		} else if code != 230 {
+			return fmt.Errorf("ftp login failed: %d %s", code, msg)
+		}
+
+		if c.Path != "" {
+			// Directories can be changed into, and the size of files can be requested in binary mode
+			if err := ftpCommand(text, 250, "CWD %s", c.Path); err != nil {
+				if err := ftpCommand(text, 200, "TYPE I"); err != nil {
+					return err
+				}
+				if err := ftpCommand(text, 213, "SIZE %s", c.Path); err != nil {
+					return fmt.Errorf("ftp path %s not found: %w", c.Path, err)
+				}
+			}
+		}
+		return ftpCommand(text, 221, "QUIT")
+	})
+}
+
+// ftpCommand sends a command, and returns an error with the response of the server, unless the response has the expected code
+func ftpCommand(text *textproto.Conn, expectCode int, format string, args ...interface{}) error {
+	id, err := text.Cmd(format, args...)
+	if err != nil {
+		return err
+	}
+	text.StartResponse(id)
+	defer text.EndResponse(id)
+	_, _, err = text.ReadResponse(expectCode)
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return fmt.Errorf("%d %s", protoErr.Code, protoErr.Msg)
+	}
+	return err
+}
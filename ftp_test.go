@@ -0,0 +1,112 @@
+package detective
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// serveFTP runs a minimal FTP server, which accepts the anonymous user and the alice:secret credentials, and has the /upload directory with a report.csv file
+func serveFTP(t *testing.T, certificates []tls.Certificate) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		l.Close()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				text := textproto.NewConn(conn)
+				text.PrintfLine("220 Service ready")
+				var user string
+				for {
+					line, err := text.ReadLine()
+					if err != nil {
+						return
+					}
+					cmd, arg, _ := strings.Cut(line, " ")
+					switch cmd {
+					case "AUTH":
+						text.PrintfLine("234 Proceed with negotiation")
+						tlsConn := tls.Server(conn, &tls.Config{Certificates: certificates})
+						if tlsConn.Handshake() != nil {
+							return
+						}
+						text = textproto.NewConn(tlsConn)
+					case "USER":
+						user = arg
+						text.PrintfLine("331 Password required")
+					case "PASS":
+						if user == "anonymous" || (user == "alice" && arg == "secret") {
+							text.PrintfLine("230 Logged in")
+						} else {
+							text.PrintfLine("530 Login incorrect")
+						}
+					case "CWD":
+						if arg == "/upload" {
+							text.PrintfLine("250 Directory changed")
+						} else {
+							text.PrintfLine("550 Failed to change directory")
+						}
+					case "TYPE":
+						text.PrintfLine("200 Switching to binary mode")
+					case "SIZE":
+						if arg == "/upload/report.csv" {
+							text.PrintfLine("213 1024")
+						} else {
+							text.PrintfLine("550 Could not get file size")
+						}
+					case "QUIT":
+						text.PrintfLine("221 Goodbye")
+						return
+					default:
+						text.PrintfLine("502 Command not implemented")
+					}
+				}
+			}(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestDetectFTP(t *testing.T) {
+	s := httptest.NewTLSServer(http.NotFoundHandler())
+	defer s.Close()
+	addr := serveFTP(t, s.TLS.Certificates)
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate())
+
+	tests := []struct {
+		name   string
+		c      FTPConfig
+		status string
+	}{
+		{name: "anonymous", status: "Ok"},
+		{name: "credentials", c: FTPConfig{User: "alice", Password: "secret"}, status: "Ok"},
+		{name: "directory", c: FTPConfig{Path: "/upload"}, status: "Ok"},
+		{name: "file", c: FTPConfig{Path: "/upload/report.csv"}, status: "Ok"},
+		{name: "explicit tls", c: FTPConfig{User: "alice", Password: "secret", Path: "/upload", TLSConfig: &tls.Config{RootCAs: roots, ServerName: "example.com"}}, status: "Ok"},
+		{name: "invalid credentials", c: FTPConfig{User: "alice", Password: "wrong"}, status: "Error: ftp login failed: 530 Login incorrect"},
+		{name: "missing path", c: FTPConfig{Path: "/download"}, status: "Error: ftp path /download not found: 550 Could not get file size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("ftp")
+			d.DetectFTP(addr, tt.c)
+			assert.Equal(t, tt.status, d.getState(context.Background()).Status)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package detective
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// defaultGraphQLQuery is a trivial query, which every GraphQL server can answer without resolving any data
+const defaultGraphQLQuery = "{ __typename }"
+
+// GraphQL turns the endpoint into a GraphQL API, which is checked by POSTing the provided query with its variables. If the query is empty, a trivial "{ __typename }" query is sent.
+// GraphQL servers usually respond with a 200 status even if the query fails, so the endpoint is only healthy if the response has data, and no errors. It is treated as a plain HTTP service, instead of another detective instance.
+func GraphQL(query string, variables map[string]interface{}) EndpointOption {
+	if query == "" {
+		query = defaultGraphQLQuery
+	}
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	return func(e *endpoint) {
+		e.req.Method = http.MethodPost
+		e.req.Header.Set("Content-Type", "application/json")
+		e.req.Header.Set("Accept", "application/graphql-response+json, application/json")
+		e.body = body
+		e.bodyMatchers = append(e.bodyMatchers, func(res []byte) error {
+			if err != nil {
+				return err
+			}
+			return checkGraphQLResponse(res)
+		})
+	}
+}
+
+func checkGraphQLResponse(body []byte) error {
+	var res struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return errors.New("invalid graphql response: " + err.Error())
+	}
+	if len(res.Errors) > 0 {
+		messages := make([]string, len(res.Errors))
+		for i, e := range res.Errors {
+			messages[i] = e.Message
+		}
+		return errors.New("graphql response has errors: " + strings.Join(messages, "; "))
+	}
+	if len(res.Data) == 0 || string(res.Data) == "null" {
+		return errors.New("graphql response has no data")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package detective
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGraphQL(t *testing.T) {
+	var received struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	var response string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Write([]byte(response))
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		name     string
+		query    string
+		vars     map[string]interface{}
+		response string
+		status   string
+	}{
+		{"default query", "", nil, `{"data": {"__typename": "Query"}}`, "Ok"},
+		{"custom query", "query Health($id: ID!) { order(id: $id) { id } }", map[string]interface{}{"id": "1"}, `{"data": {"order": {"id": "1"}}}`, "Ok"},
+		{"errors", "", nil, `{"data": null, "errors": [{"message": "database unavailable"}, {"message": "timeout"}]}`, "Error: graphql response has errors: database unavailable; timeout"},
+		{"partial data with errors", "", nil, `{"data": {"__typename": "Query"}, "errors": [{"message": "database unavailable"}]}`, "Error: graphql response has errors: database unavailable"},
+		{"no data", "", nil, `{}`, "Error: graphql response has no data"},
+		{"not json", "", nil, `<html></html>`, "Error: invalid graphql response: invalid character '<' looking for beginning of value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response = tt.response
+			d := New("sample")
+			require.NoError(t, d.EndpointWithOptions(s.URL, GraphQL(tt.query, tt.vars)))
+			st := d.GetState().Dependencies[0]
+			assert.Equal(t, tt.status, st.Status)
+			expectedQuery := tt.query
+			if expectedQuery == "" {
+				expectedQuery = "{ __typename }"
+			}
+			assert.Equal(t, expectedQuery, received.Query)
+			assert.Equal(t, tt.vars, received.Variables)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package detective
+
+import (
+	"context"
+	"time"
+)
+
+// Group returns the group of dependencies with the provided name, and creates it if it does not exist yet. A group is a Detective instance nested within this one, so that dependencies, endpoints and other groups can be registered with it as usual:
+//
+//	storage := d.Group("storage")
+//	storage.Dependency("postgres").Detect(db.Ping)
+//	storage.Dependency("redis").DetectRedis(client)
+//
+// The aggregated state of the group is reported as a single dependency of this instance, containing the states of its members. The group uses the HTTP client, the TLS configuration, the logger, the tracer, the maximum depth and the ordering of this instance, unless they are changed with the provided options, which are applied every time Group is called.
+func (d *Detective) Group(name string, opts ...Option) *Detective {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var g *Detective
+	for _, existing := range d.groups {
+		if existing.name == name {
+			g = existing
+			break
+		}
+	}
+	if g == nil {
+		g = New(name, WithHTTPClient(d.client), WithLogger(d.logger), WithTracer(d.tracer), WithTLSConfig(d.tlsConfig), WithProxy(d.proxy), WithMaxDepth(d.maxDepth), WithOrdering(d.ordering))
+		d.groups = append(d.groups, g)
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// RemoveGroup removes the group registered with the provided name, along with all of its members. It returns false if no such group was found.
+func (d *Detective) RemoveGroup(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	groups := make([]*Detective, 0, len(d.groups))
+	for _, g := range d.groups {
+		if g.name != name {
+			groups = append(groups, g)
+		}
+	}
+	removed := len(groups) != len(d.groups)
+	d.groups = groups
+	return removed
+}
+
+// groupCheck checks the members of a group. The name of the parent instance is added to the calling chain of the group, so that endpoints within the group that depend on the parent do not cause a circular chain of requests.
+func (d *Detective) groupCheck(g *Detective, fromChain []string, sel selection) func(context.Context) State {
+	chain := make([]string, len(fromChain), len(fromChain)+1)
+	copy(chain, fromChain)
+	chain = append(chain, d.name)
+	return func(ctx context.Context) State {
+		init := time.Now()
+		s := g.checkState(ctx, chain, sel)
+		return s.withLatency(time.Now().Sub(init))
+	}
+}
+
+// hasSelected returns true if the selection contains any of the dependencies of the instance, or of its groups
+func (d *Detective) hasSelected(sel selection) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if sel.endpoints && len(d.endpoints) > 0 {
+		return true
+	}
+	for _, dep := range d.dependencies {
+		if sel.dependency == nil || sel.dependency(dep) {
+			return true
+		}
+	}
+	for _, g := range d.groups {
+		if (sel.group != nil && sel.group(g)) || g.hasSelected(sel) {
+			return true
+		}
+	}
+	return false
+}
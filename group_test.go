@@ -0,0 +1,84 @@
+package detective
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	d := New("sample")
+	d.Dependency("cache")
+	storage := d.Group("storage")
+	storage.Dependency("postgres")
+	storage.Dependency("s3").Detect(func() error { return errors.New("access denied") })
+	assert.Equal(t, storage, d.Group("storage"), "the existing group should be returned")
+
+	s := d.GetState()
+	assertStatesEqual(t, State{Name: "sample", Ok: false, Status: "Error: dependency failure", Dependencies: []State{
+		{Name: "cache", Ok: true, Status: "Ok"},
+		{Name: "storage", Ok: false, Status: "Error: dependency failure", Dependencies: []State{
+			{Name: "postgres", Ok: true, Status: "Ok"},
+			{Name: "s3", Ok: false, Status: "Error: access denied"},
+		}},
+	}}, s)
+	assert.True(t, s.Dependencies[1].Latency > 0)
+
+	assert.True(t, d.RemoveGroup("storage"))
+	assert.False(t, d.RemoveGroup("storage"))
+	assert.Equal(t, Healthy, d.GetState().Health)
+}
+
+func TestGroupHooks(t *testing.T) {
+	d := New("sample")
+	failing := false
+	g := d.Group("storage")
+	g.Dependency("postgres").Detect(func() error {
+		if failing {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	var changes [][2]Health
+	g.OnStateChange(func(old, new State) {
+		changes = append(changes, [2]Health{old.Health, new.Health})
+	})
+	ctx := context.Background()
+	d.GetStateContext(ctx)
+	failing = true
+	d.GetStateContext(ctx)
+	assert.Equal(t, [][2]Health{{Healthy, Unhealthy}}, changes)
+}
+
+func TestGroupCircularEndpoint(t *testing.T) {
+	d := New("sample")
+	s := httptest.NewServer(d)
+	defer s.Close()
+	// The group depends on the instance it belongs to
+	require.NoError(t, d.Group("peers").Endpoint(s.URL))
+
+	st := d.GetState()
+	require.Len(t, st.Dependencies, 1)
+	require.Len(t, st.Dependencies[0].Dependencies, 1)
+	assert.Equal(t, Healthy, st.Health, st.Error)
+}
+
+func TestGroupLiveness(t *testing.T) {
+	d := New("sample")
+	d.Group("process").Dependency("goroutines").DetectGoroutines(math.MaxInt32)
+	d.Group("storage").Dependency("postgres").Detect(func() error { return errors.New("connection refused") })
+
+	rw := httptest.NewRecorder()
+	d.LivenessHandler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	var s State
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &s))
+	require.Len(t, s.Dependencies, 1)
+	assert.Equal(t, "process", s.Dependencies[0].Name)
+}
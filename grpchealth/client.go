@@ -0,0 +1,27 @@
+package grpchealth
+
+import (
+	"context"
+	"errors"
+	"github.com/sohamkamani/detective"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Detector returns a detector function that queries the standard gRPC health checking service (grpc.health.v1.Health/Check) over the provided connection. The dependency is considered healthy only if the service reports SERVING. An empty service name queries the overall health of the server.
+//
+//	conn, err := grpc.NewClient("payments:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	d.Dependency("payments").DetectContext(grpchealth.Detector(conn, ""))
+func Detector(conn grpc.ClientConnInterface, service string) detective.ContextDetectorFunc {
+	client := healthpb.NewHealthClient(conn)
+	return func(ctx context.Context) error {
+		res, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return errors.New("service reported status: " + res.GetStatus().String())
+		}
+		return nil
+	}
+}
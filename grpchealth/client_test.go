@@ -0,0 +1,47 @@
+package grpchealth
+
+import (
+	"context"
+	"github.com/sohamkamani/detective"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"testing"
+)
+
+func startHealthServer(t *testing.T) (*health.Server, *grpc.ClientConn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return hs, conn
+}
+
+func TestDetector(t *testing.T) {
+	hs, conn := startHealthServer(t)
+	hs.SetServingStatus("payments", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("billing", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	d := detective.New("sample")
+	d.Dependency("payments").DetectContext(Detector(conn, "payments"))
+	d.Dependency("billing").DetectContext(Detector(conn, "billing"))
+	d.Dependency("unknown").DetectContext(Detector(conn, "unknown"))
+
+	s := d.GetStateContext(context.Background())
+	require.Len(t, s.Dependencies, 3)
+	assert.Equal(t, detective.Healthy, s.Dependencies[0].Health)
+	assert.Equal(t, detective.Unhealthy, s.Dependencies[1].Health)
+	assert.Equal(t, "service reported status: NOT_SERVING", s.Dependencies[1].Error)
+	assert.Equal(t, detective.Unhealthy, s.Dependencies[2].Health)
+}
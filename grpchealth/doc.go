@@ -0,0 +1,6 @@
+/*
+Package grpchealth integrates detective with the standard gRPC health checking protocol (https://github.com/grpc/grpc/blob/master/doc/health-checking.md).
+
+It is kept in a separate package, so that applications which do not use gRPC are not required to depend on it.
+*/
+package grpchealth
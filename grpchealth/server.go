@@ -0,0 +1,94 @@
+package grpchealth
+
+import (
+	"context"
+	"github.com/sohamkamani/detective"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+const defaultWatchInterval = 5 * time.Second
+
+var _ healthpb.HealthServer = (*Server)(nil)
+
+// Server exposes the state of a Detective instance through the standard gRPC health checking service. The overall state of the instance is reported for the empty service name, and the state of each direct dependency is reported under the name of the dependency.
+//
+//	s := grpc.NewServer()
+//	healthpb.RegisterHealthServer(s, grpchealth.NewServer(d))
+type Server struct {
+	d             *detective.Detective
+	watchInterval time.Duration
+}
+
+// NewServer creates a new Server for the provided Detective instance
+func NewServer(d *detective.Detective) *Server {
+	return &Server{
+		d:             d,
+		watchInterval: defaultWatchInterval,
+	}
+}
+
+// WithWatchInterval sets how often the state is checked for changes while serving a Watch call. The default interval is 5 seconds, which is also used if the interval is not positive.
+func (s *Server) WithWatchInterval(interval time.Duration) *Server {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	s.watchInterval = interval
+	return s
+}
+
+// Check implements the grpc.health.v1.Health/Check method. Healthy and degraded states are reported as SERVING, and unhealthy states as NOT_SERVING.
+func (s *Server) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	st, ok := s.servingStatus(ctx, req.GetService())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown service: "+req.GetService())
+	}
+	return &healthpb.HealthCheckResponse{Status: st}, nil
+}
+
+// Watch implements the grpc.health.v1.Health/Watch method. The current status is sent immediately, and then every time it changes.
+func (s *Server) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+	for {
+		st, ok := s.servingStatus(ctx, req.GetService())
+		if !ok {
+			st = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if st != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: st}); err != nil {
+				return err
+			}
+			last = st
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) servingStatus(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	state := s.d.GetStateContext(ctx)
+	if service == "" {
+		return toServingStatus(state), true
+	}
+	for _, dep := range state.Dependencies {
+		if dep.Name == service {
+			return toServingStatus(dep), true
+		}
+	}
+	return healthpb.HealthCheckResponse_UNKNOWN, false
+}
+
+func toServingStatus(s detective.State) healthpb.HealthCheckResponse_ServingStatus {
+	if s.Ok {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
@@ -0,0 +1,76 @@
+package grpchealth
+
+import (
+	"context"
+	"errors"
+	"github.com/sohamkamani/detective"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	d := detective.New("sample")
+	var failing int32
+	d.Dependency("db").Detect(func() error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	d.Dependency("cache").NonCritical().Detect(func() error {
+		return errors.New("failed")
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, NewServer(d).WithWatchInterval(10*time.Millisecond))
+	go s.Serve(l)
+	defer s.Stop()
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	ctx := context.Background()
+
+	res, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status, "degraded state should be serving")
+
+	res, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "cache"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, res.Status)
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	stream, err := client.Watch(watchCtx, &healthpb.HealthCheckRequest{Service: "db"})
+	require.NoError(t, err)
+	res, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+
+	atomic.StoreInt32(&failing, 1)
+	res, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, res.Status)
+}
+
+func TestWithWatchInterval(t *testing.T) {
+	s := NewServer(detective.New("sample"))
+	assert.Equal(t, defaultWatchInterval, s.WithWatchInterval(0).watchInterval)
+	assert.Equal(t, defaultWatchInterval, s.WithWatchInterval(-time.Second).watchInterval)
+	assert.Equal(t, time.Second, s.WithWatchInterval(time.Second).watchInterval)
+}
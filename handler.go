@@ -0,0 +1,85 @@
+package detective
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// A HandlerOption customizes the HTTP handler returned by Handler
+type HandlerOption func(*handler)
+
+type handler struct {
+	apiKeys     []string
+	credentials []basicCredentials
+}
+
+type basicCredentials struct {
+	username, password string
+}
+
+// WithAPIKey allows requests with the API key, sent as an "X-API-Key: <key>" or an "Authorization: Bearer <key>" header, to read the detailed state. The option can be used several times, so that keys can be rotated.
+func WithAPIKey(key string) HandlerOption {
+	return func(h *handler) {
+		h.apiKeys = append(h.apiKeys, key)
+	}
+}
+
+// WithBasicAuth allows requests authenticated with the username and password using HTTP basic authentication to read the detailed state
+func WithBasicAuth(username, password string) HandlerOption {
+	return func(h *handler) {
+		h.credentials = append(h.credentials, basicCredentials{username, password})
+	}
+}
+
+// Handler returns an HTTP handler for the state of the Detective instance, which protects the detailed state with the provided options. Authenticated requests are served like the instance itself, while other requests only get the aggregated state and the status code, like the SummaryHandler, so that load balancers and uptime monitors still know whether the application is working:
+//
+//	http.Handle("/health", d.Handler(detective.WithAPIKey(os.Getenv("HEALTH_API_KEY"))))
+//
+// Other detective instances can read the detailed state with an endpoint option like BearerToken. Without options, the handler serves the detailed state to everyone.
+func (d *Detective) Handler(opts ...HandlerOption) http.Handler {
+	h := &handler{}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if len(h.apiKeys) == 0 && len(h.credentials) == 0 {
+		return d
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.authenticated(r) {
+			d.ServeHTTP(w, r)
+			return
+		}
+		if d.handleCORS(w, r) {
+			return
+		}
+		d.serveState(w, r, true, nil)
+	})
+}
+
+func (h *handler) authenticated(r *http.Request) bool {
+	key := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key != "" {
+		for _, k := range h.apiKeys {
+			if k != "" && subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+				return true
+			}
+		}
+	}
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	for _, c := range h.credentials {
+		// Both comparisons are made, so that the duration does not tell whether the username is correct
+		validUsername := subtle.ConstantTimeCompare([]byte(username), []byte(c.username)) == 1
+		validPassword := subtle.ConstantTimeCompare([]byte(password), []byte(c.password)) == 1
+		if validUsername && validPassword {
+			return true
+		}
+	}
+	return false
+}
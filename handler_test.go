@@ -0,0 +1,48 @@
+package detective
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerAuthentication(t *testing.T) {
+	d := New("sample")
+	d.Dependency("db").Detect(func() error { return errors.New("connection refused") })
+	h := d.Handler(WithAPIKey("old-key"), WithAPIKey("new-key"), WithBasicAuth("admin", "secret"))
+
+	tests := []struct {
+		name     string
+		prepare  func(r *http.Request)
+		detailed bool
+	}{
+		{"anonymous", func(r *http.Request) {}, false},
+		{"api key header", func(r *http.Request) { r.Header.Set("X-API-Key", "new-key") }, true},
+		{"rotated api key", func(r *http.Request) { r.Header.Set("X-API-Key", "old-key") }, true},
+		{"bearer token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer new-key") }, true},
+		{"wrong api key", func(r *http.Request) { r.Header.Set("X-API-Key", "guess") }, false},
+		{"basic auth", func(r *http.Request) { r.SetBasicAuth("admin", "secret") }, true},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("admin", "guess") }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.prepare(r)
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, r)
+			// The status code tells whether the application is working, even without the detailed state
+			assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+			var s State
+			require.NoError(t, json.NewDecoder(rw.Body).Decode(&s))
+			assert.Equal(t, Unhealthy, s.Health)
+			assert.Equal(t, tt.detailed, len(s.Dependencies) > 0)
+		})
+	}
+
+	// Without options, the detailed state is public
+	assert.Equal(t, d, d.Handler())
+}
@@ -0,0 +1,67 @@
+package detective
+
+import (
+	"encoding/json"
+)
+
+// healthJSONMediaType is the media type of the Health Check Response Format for HTTP APIs (draft-inadarei-api-health-check)
+const healthJSONMediaType = "application/health+json"
+
+// healthResponse is the root object of the health check response format
+type healthResponse struct {
+	Status      string                   `json:"status"`
+	Version     string                   `json:"version,omitempty"`
+	ReleaseID   string                   `json:"releaseId,omitempty"`
+	Description string                   `json:"description,omitempty"`
+	Output      string                   `json:"output,omitempty"`
+	Checks      map[string][]healthCheck `json:"checks,omitempty"`
+}
+
+// healthCheck describes a single measurement of a component in the health check response format
+type healthCheck struct {
+	ComponentID   string  `json:"componentId"`
+	ComponentType string  `json:"componentType,omitempty"`
+	ObservedValue float64 `json:"observedValue"`
+	ObservedUnit  string  `json:"observedUnit"`
+	Status        string  `json:"status"`
+	Output        string  `json:"output,omitempty"`
+}
+
+var healthJSONStatus = map[Health]string{
+	Healthy:   "pass",
+	Degraded:  "warn",
+	Unhealthy: "fail",
+}
+
+// healthJSON encodes the state in the health check response format, with the version and release ID of the build information, if it is set. Each dependency is mapped to a response time measurement in the checks object, keyed by its name.
+func healthJSON(s State) ([]byte, error) {
+	res := healthResponse{
+		Status:      healthJSONStatus[effectiveHealth(s)],
+		Description: "health of " + s.Name,
+		Output:      s.Error,
+	}
+	// The release of the build is identified by its commit
+	if s.Build != nil {
+		res.Version = s.Build.Version
+		res.ReleaseID = s.Build.Commit
+	}
+	if len(s.Dependencies) > 0 {
+		res.Checks = make(map[string][]healthCheck, len(s.Dependencies))
+	}
+	for _, dep := range s.Dependencies {
+		componentType := "component"
+		if len(dep.Dependencies) > 0 {
+			componentType = "system"
+		}
+		key := dep.Name + ":responseTime"
+		res.Checks[key] = append(res.Checks[key], healthCheck{
+			ComponentID:   dep.Name,
+			ComponentType: componentType,
+			ObservedValue: dep.LatencyMs,
+			ObservedUnit:  "ms",
+			Status:        healthJSONStatus[effectiveHealth(dep)],
+			Output:        dep.Error,
+		})
+	}
+	return json.Marshal(res)
+}
@@ -0,0 +1,57 @@
+package detective
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthJSON(t *testing.T) {
+	b, err := healthJSON(sampleFormatState())
+	require.NoError(t, err)
+	expected := `{
+		"status": "fail",
+		"description": "health of sample",
+		"output": "dependency failure",
+		"checks": {
+			"db:responseTime": [{"componentId": "db", "componentType": "component", "observedValue": 12, "observedUnit": "ms", "status": "fail", "output": "connection refused"}],
+			"cache:responseTime": [{"componentId": "cache", "componentType": "component", "observedValue": 1.5, "observedUnit": "ms", "status": "pass"}],
+			"peer:responseTime": [{"componentId": "peer", "componentType": "system", "observedValue": 3, "observedUnit": "ms", "status": "pass"}]
+		}
+	}`
+	assert.JSONEq(t, expected, string(b))
+
+	b, err = healthJSON(State{Name: "sample"}.withOk())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status": "pass", "description": "health of sample"}`, string(b))
+
+	b, err = healthJSON(State{Name: "sample", Build: &BuildInfo{Version: "v1.2.3", Commit: "abc"}}.withOk())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status": "pass", "version": "v1.2.3", "releaseId": "abc", "description": "health of sample"}`, string(b))
+}
+
+func TestServeHTTPHealthJSON(t *testing.T) {
+	d := New("sample")
+	d.Dependency("db")
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/?format=health", nil),
+		func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", "application/health+json")
+			return r
+		}(),
+	} {
+		rw := httptest.NewRecorder()
+		d.ServeHTTP(rw, r)
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, "application/health+json", rw.Header().Get("Content-Type"))
+		var res healthResponse
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &res))
+		assert.Equal(t, "pass", res.Status)
+		assert.Contains(t, res.Checks, "db:responseTime")
+	}
+}
@@ -0,0 +1,51 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// A Heartbeat is reported by the code it monitors, instead of being checked by a detector function. It is useful for background workers, and loops that cannot be checked from outside.
+type Heartbeat struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	last time.Time
+}
+
+// DetectHeartbeat registers a detector function that only considers the dependency healthy if the ReportHealthy method of the returned heartbeat was called within the provided TTL:
+//
+//	hb := d.Dependency("worker").DetectHeartbeat(time.Minute)
+//	for job := range jobs {
+//		process(job)
+//		hb.ReportHealthy()
+//	}
+//
+// The dependency is unhealthy until the first heartbeat is reported.
+func (d *Dependency) DetectHeartbeat(ttl time.Duration) *Heartbeat {
+	h := &Heartbeat{ttl: ttl}
+	d.DetectContext(h.check)
+	return h
+}
+
+// ReportHealthy records that the monitored code is working
+func (h *Heartbeat) ReportHealthy() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = time.Now()
+}
+
+func (h *Heartbeat) check(ctx context.Context) error {
+	h.mu.Lock()
+	last := h.last
+	h.mu.Unlock()
+	if last.IsZero() {
+		return errors.New("no heartbeat was reported")
+	}
+	SetDetail(ctx, "last_heartbeat_at", last.UTC())
+	if since := time.Now().Sub(last); since > h.ttl {
+		return errors.New("the last heartbeat was reported " + since.Round(time.Millisecond).String() + " ago, which exceeds " + h.ttl.String())
+	}
+	return nil
+}
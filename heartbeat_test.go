@@ -0,0 +1,26 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectHeartbeat(t *testing.T) {
+	d := newDependency("worker")
+	h := d.DetectHeartbeat(20 * time.Millisecond)
+	assertStatesEqual(t, State{Name: "worker", Ok: false, Status: "Error: no heartbeat was reported"}, d.getState(context.Background()))
+
+	h.ReportHealthy()
+	s := d.getState(context.Background())
+	assertStatesEqual(t, State{Name: "worker", Ok: true, Status: "Ok"}, s)
+	assert.Contains(t, s.Details, "last_heartbeat_at")
+
+	time.Sleep(30 * time.Millisecond)
+	s = d.getState(context.Background())
+	assert.Equal(t, Unhealthy, s.Health)
+	assert.True(t, strings.HasPrefix(s.Error, "the last heartbeat was reported "), s.Error)
+	assert.True(t, strings.HasSuffix(s.Error, " ago, which exceeds 20ms"), s.Error)
+}
@@ -0,0 +1,237 @@
+package detective
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A HistoryEntry is the result of a check of the instance, or of one of its dependencies, recorded by WithHistory
+type HistoryEntry struct {
+	Time      time.Time `json:"time"`
+	Health    Health    `json:"health"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs float64   `json:"latency_ms"`
+	Disabled  bool      `json:"disabled,omitempty"`
+}
+
+// A HistoryStore keeps the results of the checks of an instance, and of its nested dependencies, so that they can be read with the HistoryHandler. Entities are named after their ancestors below the instance and their own name, separated by slashes, like "storage/s3", and the instance itself has an empty name.
+// The package detectivebolt provides a store that persists the results in a Bolt database, so that they survive restarts.
+type HistoryStore interface {
+	// Record stores the results of a check of all entities, keyed by their names
+	Record(ctx context.Context, entries map[string]HistoryEntry) error
+	// Entries returns the results of the entity recorded at or after since, oldest first. If limit is positive, only the limit most recent results are returned.
+	Entries(ctx context.Context, name string, since time.Time, limit int) ([]HistoryEntry, error)
+	// Names returns the names of the entities that have results, sorted
+	Names(ctx context.Context) ([]string, error)
+}
+
+// history keeps the most recent results of each checked entity in ring buffers
+type history struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*historyRing
+}
+
+type historyRing struct {
+	entries []HistoryEntry
+	// next is the index at which the next entry is written, once the ring is full
+	next int
+}
+
+func (r *historyRing) add(e HistoryEntry, size int) {
+	if len(r.entries) < size {
+		r.entries = append(r.entries, e)
+		return
+	}
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % size
+}
+
+// last returns the n most recent entries, oldest first. All entries are returned if n is not positive.
+func (r *historyRing) last(n int) []HistoryEntry {
+	ordered := make([]HistoryEntry, 0, len(r.entries))
+	ordered = append(ordered, r.entries[r.next:]...)
+	ordered = append(ordered, r.entries[:r.next]...)
+	if n > 0 && n < len(ordered) {
+		ordered = ordered[len(ordered)-n:]
+	}
+	return ordered
+}
+
+// historyEntries returns the result of the instance, and of each of its nested dependencies, keyed by their names
+func historyEntries(s State, at time.Time) map[string]HistoryEntry {
+	entries := map[string]HistoryEntry{}
+	var add func(name string, s State)
+	add = func(name string, s State) {
+		entries[name] = HistoryEntry{Time: at, Health: effectiveHealth(s), Error: s.Error, LatencyMs: s.LatencyMs, Disabled: s.Disabled}
+		for _, dep := range s.Dependencies {
+			depName := dep.Name
+			if name != "" {
+				depName = name + "/" + dep.Name
+			}
+			add(depName, dep)
+		}
+	}
+	add("", s)
+	return entries
+}
+
+func (h *history) Record(ctx context.Context, entries map[string]HistoryEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.entries == nil {
+		h.entries = map[string]*historyRing{}
+	}
+	for name, e := range entries {
+		r, ok := h.entries[name]
+		if !ok {
+			r = &historyRing{}
+			h.entries[name] = r
+		}
+		r.add(e, h.size)
+	}
+	return nil
+}
+
+func (h *history) Entries(ctx context.Context, name string, since time.Time, limit int) ([]HistoryEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.entries[name]
+	if !ok {
+		return nil, nil
+	}
+	entries := r.last(0)
+	first := sort.Search(len(entries), func(i int) bool { return !entries[i].Time.Before(since) })
+	entries = entries[first:]
+	if limit > 0 && limit < len(entries) {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+func (h *history) Names(ctx context.Context) ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	names := make([]string, 0, len(h.entries))
+	for name := range h.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// WithHistory keeps the results of the n most recent checks of the instance, and of each of its dependencies in memory, so that they can be read with the HistoryHandler
+func WithHistory(n int) Option {
+	return func(d *Detective) {
+		if n <= 0 {
+			d.history = nil
+			return
+		}
+		d.history = &history{size: n}
+	}
+}
+
+// WithHistoryStore records the results of every check of the instance, and of each of its dependencies in the store, which can persist them across restarts. It replaces the in-memory history of WithHistory.
+func WithHistoryStore(s HistoryStore) Option {
+	return func(d *Detective) {
+		d.history = s
+	}
+}
+
+// historyTimeout is the maximum duration a history store is given to record the results of a check
+const historyTimeout = 5 * time.Second
+
+// recordHistory records the state in the history of the instance, if it is enabled with WithHistory or WithHistoryStore
+func (d *Detective) recordHistory(s State) {
+	if d.history == nil {
+		return
+	}
+	at := time.Now().UTC()
+	if s.CheckedAt != nil {
+		at = *s.CheckedAt
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+	if err := d.history.Record(ctx, historyEntries(s, at)); err != nil {
+		d.log(slog.LevelError, fmt.Sprintf("detective %s: failed to record history: %v", d.name, err), slog.Any("error", err))
+	}
+}
+
+// HistoryHandler returns an HTTP handler that responds with the results of the most recent checks recorded with WithHistory or WithHistoryStore, so that on-call engineers can see when a dependency started failing. The check query parameter selects a single entity, whose results are returned as a JSON array, with the oldest first. Nested dependencies are named after their ancestors, like "storage/s3", and the instance itself has an empty name. Without it, the results of all entities are returned as a JSON object keyed by their names.
+// The n query parameter limits the number of results of each entity, and the since query parameter, an RFC 3339 timestamp, skips older results:
+//
+//	GET /health/history?check=db&n=50
+func (d *Detective) HistoryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.history == nil {
+			http.Error(w, "history is not enabled", http.StatusNotFound)
+			return
+		}
+		query := r.URL.Query()
+		n := 0
+		if v := query.Get("n"); v != "" {
+			var err error
+			if n, err = strconv.Atoi(v); err != nil || n < 0 {
+				http.Error(w, "invalid n: "+v, http.StatusBadRequest)
+				return
+			}
+		}
+		var since time.Time
+		if v := query.Get("since"); v != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339, v); err != nil {
+				http.Error(w, "invalid since: "+v, http.StatusBadRequest)
+				return
+			}
+		}
+		names, err := d.history.Names(r.Context())
+		if err != nil {
+			d.historyError(w, err)
+			return
+		}
+		if checks, ok := query["check"]; ok {
+			if !contains(names, checks[0]) {
+				http.Error(w, "no history for "+checks[0], http.StatusNotFound)
+				return
+			}
+			entries, err := d.history.Entries(r.Context(), checks[0], since, n)
+			if err != nil {
+				d.historyError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, d.redactHistory(entries))
+			return
+		}
+		all := make(map[string][]HistoryEntry, len(names))
+		for _, name := range names {
+			entries, err := d.history.Entries(r.Context(), name, since, n)
+			if err != nil {
+				d.historyError(w, err)
+				return
+			}
+			all[name] = d.redactHistory(entries)
+		}
+		writeJSON(w, http.StatusOK, all)
+	})
+}
+
+func (d *Detective) historyError(w http.ResponseWriter, err error) {
+	d.log(slog.LevelError, fmt.Sprintf("detective %s: failed to read history: %v", d.name, err), slog.Any("error", err))
+	http.Error(w, "failed to read history", http.StatusInternalServerError)
+}
+
+func (d *Detective) redactHistory(entries []HistoryEntry) []HistoryEntry {
+	if !d.redactErrors {
+		return entries
+	}
+	for i := range entries {
+		entries[i].Error = ""
+	}
+	return entries
+}
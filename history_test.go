@@ -0,0 +1,84 @@
+package detective
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHistoryRing(t *testing.T) {
+	var r historyRing
+	for i := 0; i < 5; i++ {
+		r.add(HistoryEntry{LatencyMs: float64(i)}, 3)
+	}
+	latencies := func(entries []HistoryEntry) []float64 {
+		var l []float64
+		for _, e := range entries {
+			l = append(l, e.LatencyMs)
+		}
+		return l
+	}
+	assert.Equal(t, []float64{2, 3, 4}, latencies(r.last(0)))
+	assert.Equal(t, []float64{3, 4}, latencies(r.last(2)))
+	assert.Equal(t, []float64{2, 3, 4}, latencies(r.last(10)))
+}
+
+func TestHistoryHandler(t *testing.T) {
+	failures := 0
+	d := New("sample", WithHistory(3))
+	d.Dependency("db").Detect(func() error {
+		failures++
+		if failures > 2 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	d.Group("storage").Dependency("s3")
+	for i := 0; i < 4; i++ {
+		d.GetState()
+	}
+	h := d.HistoryHandler()
+
+	get := func(url string, v interface{}) int {
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, url, nil))
+		if rw.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rw.Body).Decode(v))
+		}
+		return rw.Code
+	}
+
+	var entries []HistoryEntry
+	require.Equal(t, http.StatusOK, get("/?check=db", &entries))
+	require.Len(t, entries, 3)
+	assert.Equal(t, Healthy, entries[0].Health)
+	assert.Equal(t, Unhealthy, entries[1].Health)
+	assert.Equal(t, "connection refused", entries[2].Error)
+	assert.False(t, entries[2].Time.Before(entries[1].Time))
+
+	require.Equal(t, http.StatusOK, get("/?check=db&n=1", &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, Unhealthy, entries[0].Health)
+
+	var all map[string][]HistoryEntry
+	require.Equal(t, http.StatusOK, get("/?n=2", &all))
+	assert.Len(t, all, 4)
+	assert.Len(t, all[""], 2)
+	assert.Len(t, all["storage/s3"], 2)
+
+	require.Equal(t, http.StatusOK, get("/?check=db&since="+time.Now().Add(time.Hour).Format(time.RFC3339), &entries))
+	assert.Empty(t, entries)
+
+	assert.Equal(t, http.StatusNotFound, get("/?check=redis", nil))
+	assert.Equal(t, http.StatusBadRequest, get("/?since=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, get("/?n=many", nil))
+
+	rw := httptest.NewRecorder()
+	New("sample").HistoryHandler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
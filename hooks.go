@@ -0,0 +1,87 @@
+package detective
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// A StateChangeFunc is called with the previous, and the current state of an entity whenever its health changes
+type StateChangeFunc func(old, new State)
+
+// stateTracker remembers the last observed state of an entity, and calls the registered hooks when its health changes
+type stateTracker struct {
+	mu       sync.Mutex
+	hooks    []StateChangeFunc
+	last     State
+	observed bool
+}
+
+func (t *stateTracker) onChange(fn StateChangeFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hooks = append(t.hooks, fn)
+}
+
+// observe records the state, and calls the hooks if its health is different from the previously observed state. The first observed state is not considered a change. The previous state is returned, along with whether the health changed.
+func (t *stateTracker) observe(s State) (State, bool) {
+	t.mu.Lock()
+	old, observed := t.last, t.observed
+	t.last, t.observed = s, true
+	hooks := make([]StateChangeFunc, len(t.hooks))
+	copy(hooks, t.hooks)
+	t.mu.Unlock()
+	if !observed || effectiveHealth(old) == effectiveHealth(s) {
+		return old, false
+	}
+	// Hooks are called without holding the lock, so that they can check the state of the instance again
+	for _, hook := range hooks {
+		hook(old, s)
+	}
+	return old, true
+}
+
+// OnStateChange registers a function that is called whenever the health of the Detective instance changes, once its dependencies have been checked. Hooks are called synchronously, in the order they are registered, so they should return quickly.
+func (d *Detective) OnStateChange(fn StateChangeFunc) {
+	d.tracker.onChange(fn)
+}
+
+// OnStateChange registers a function that is called whenever the health of the dependency changes. Hooks are called synchronously, in the order they are registered, so they should return quickly.
+func (d *Dependency) OnStateChange(fn StateChangeFunc) {
+	d.tracker.onChange(fn)
+}
+
+// recordState notifies the hooks and notifiers of the Detective instance, the hooks of each of its dependencies, and its groups about the latest state
+func (d *Detective) recordState(s State) {
+	d.mu.RLock()
+	dependencies := d.dependencies
+	groups := d.groups
+	d.mu.RUnlock()
+
+	for _, dep := range dependencies {
+		for _, depState := range s.Dependencies {
+			if depState.Name == dep.name {
+				if old, changed := dep.tracker.observe(depState); changed {
+					d.log(slog.LevelInfo, fmt.Sprintf("detective %s: dependency %s changed from %s to %s", d.name, dep.name, effectiveHealth(old), effectiveHealth(depState)),
+						slog.String("dependency", dep.name), slog.String("old_health", string(effectiveHealth(old))), slog.String("health", string(effectiveHealth(depState))))
+				}
+				break
+			}
+		}
+	}
+	for _, g := range groups {
+		for _, groupState := range s.Dependencies {
+			if groupState.Name == g.name {
+				g.recordState(groupState)
+				break
+			}
+		}
+	}
+	if old, changed := d.tracker.observe(s); changed {
+		d.log(slog.LevelInfo, fmt.Sprintf("detective %s: changed from %s to %s", d.name, effectiveHealth(old), effectiveHealth(s)),
+			slog.String("old_health", string(effectiveHealth(old))), slog.String("health", string(effectiveHealth(s))))
+	}
+	d.notify(s)
+	d.recordHistory(s)
+	d.stream.publish(s)
+}
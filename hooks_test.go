@@ -0,0 +1,47 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestOnStateChange(t *testing.T) {
+	d := New("sample")
+	failing := false
+	dep := d.Dependency("sampledep")
+	dep.Detect(func() error {
+		if failing {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	d.Dependency("otherdep")
+
+	var changes, depChanges [][2]Health
+	d.OnStateChange(func(old, new State) {
+		changes = append(changes, [2]Health{old.Health, new.Health})
+		// Hooks should be able to read the state of the instance
+		d.GetStateContext(context.Background())
+	})
+	dep.OnStateChange(func(old, new State) {
+		require.Equal(t, "sampledep", new.Name)
+		depChanges = append(depChanges, [2]Health{old.Health, new.Health})
+	})
+
+	ctx := context.Background()
+	d.GetStateContext(ctx)
+	assert.Empty(t, changes, "the first state should not be reported as a change")
+
+	failing = true
+	d.GetStateContext(ctx)
+	d.GetStateContext(ctx)
+	failing = false
+	d.refreshCachedState(ctx)
+
+	expected := [][2]Health{{Healthy, Unhealthy}, {Unhealthy, Healthy}}
+	assert.Equal(t, expected, changes)
+	assert.Equal(t, expected, depChanges)
+}
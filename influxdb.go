@@ -0,0 +1,88 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// InfluxDBConfig configures the detector registered with DetectInfluxDB
+type InfluxDBConfig struct {
+	// Token is sent in the Authorization header of the requests, if set
+	Token string
+	// Client is used to send the requests. If it is nil, http.DefaultClient is used.
+	Client Doer
+}
+
+// DetectInfluxDB registers a detector function that checks the health of an InfluxDB server, at a URL like "http://localhost:8086". Servers that implement the /health endpoint (InfluxDB 1.8 and later) are unhealthy unless they report that they pass their checks, and older servers are checked with their /ping endpoint. The version of the server is reported in the details of the state of the dependency.
+// The requests are bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectInfluxDB(serverURL string, c InfluxDBConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		base, err := url.Parse(strings.TrimSuffix(serverURL, "/"))
+		if err != nil {
+			return err
+		}
+		newRequest := func(path string) (*http.Request, error) {
+			req, err := http.NewRequest(http.MethodGet, base.String()+path, nil)
+			if err != nil {
+				return nil, err
+			}
+			if c.Token != "" {
+				req.Header.Set("Authorization", "Token "+c.Token)
+			}
+			return req.WithContext(ctx), nil
+		}
+
+		req, err := newRequest("/health")
+		if err != nil {
+			return err
+		}
+		var health struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Version string `json:"version"`
+		}
+		code, err := doJSON(c.Client, req, &health)
+		if err != nil {
+			return err
+		}
+		if code != http.StatusNotFound {
+			if health.Version != "" {
+				SetDetail(ctx, "version", health.Version)
+			}
+			if health.Status == "pass" {
+				return nil
+			}
+			if health.Message != "" {
+				return errors.New("influxdb is unhealthy: " + health.Message)
+			}
+			return fmt.Errorf("influxdb health endpoint returned http status: %d", code)
+		}
+
+		req, err = newRequest("/ping")
+		if err != nil {
+			return err
+		}
+		client := c.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		if version := res.Header.Get("X-Influxdb-Version"); version != "" {
+			SetDetail(ctx, "version", version)
+		}
+		if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+			return fmt.Errorf("influxdb ping endpoint returned http status: %d", res.StatusCode)
+		}
+		return nil
+	})
+}
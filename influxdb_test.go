@@ -0,0 +1,66 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectInfluxDB(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  string
+		details map[string]interface{}
+	}{
+		{
+			name: "pass",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+				w.Write([]byte(`{"name": "influxdb", "message": "ready for queries and writes", "status": "pass", "version": "v2.7.1"}`))
+			},
+			status:  "Ok",
+			details: map[string]interface{}{"version": "v2.7.1"},
+		},
+		{
+			name: "fail",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"name": "influxdb", "message": "database is not ready", "status": "fail", "version": "v2.7.1"}`))
+			},
+			status:  "Error: influxdb is unhealthy: database is not ready",
+			details: map[string]interface{}{"version": "v2.7.1"},
+		},
+		{
+			name: "ping",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/ping" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("X-Influxdb-Version", "1.7.11")
+				w.WriteHeader(http.StatusNoContent)
+			},
+			status:  "Ok",
+			details: map[string]interface{}{"version": "1.7.11"},
+		},
+		{
+			name:    "unavailable",
+			handler: http.NotFound,
+			status:  "Error: influxdb ping endpoint returned http status: 404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(tt.handler)
+			defer s.Close()
+			d := newDependency("influxdb")
+			d.DetectInfluxDB(s.URL, InfluxDBConfig{Token: "secret"})
+			st := d.getState(context.Background())
+			assert.Equal(t, tt.status, st.Status)
+			assert.Equal(t, tt.details, st.Details)
+		})
+	}
+}
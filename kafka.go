@@ -0,0 +1,78 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// KafkaMetadata describes a Kafka cluster, as reported by a KafkaMetadataFetcher
+type KafkaMetadata struct {
+	// Brokers are the addresses of the brokers of the cluster
+	Brokers []string
+	// Topics are the names of the topics of the cluster
+	Topics []string
+}
+
+// KafkaMetadataFetcher is the minimal interface required to check the health of a Kafka cluster. Most Kafka clients (like sarama, franz-go, or confluent-kafka-go) can be adapted to this interface with a small wrapper, or with the KafkaMetadataFetcherFunc type.
+// The topics are the ones the dependency is expected to have, so that clients can request the metadata of these topics only. If no topics are provided, clients may return the metadata of all topics, or no topics at all.
+type KafkaMetadataFetcher interface {
+	Metadata(ctx context.Context, topics []string) (KafkaMetadata, error)
+}
+
+// The KafkaMetadataFetcherFunc type is an adapter to allow the use of ordinary functions as a KafkaMetadataFetcher
+type KafkaMetadataFetcherFunc func(ctx context.Context, topics []string) (KafkaMetadata, error)
+
+// Metadata calls f(ctx, topics)
+func (f KafkaMetadataFetcherFunc) Metadata(ctx context.Context, topics []string) (KafkaMetadata, error) {
+	return f(ctx, topics)
+}
+
+// DetectKafka registers a detector function that fetches the metadata of a Kafka cluster, which requires at least one broker to be reachable. If topics are provided, the dependency is unhealthy unless all of them exist. The number of brokers is reported in the details of the state of the dependency. The request is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+//
+// For example, with the github.com/twmb/franz-go client:
+//
+//	d.Dependency("kafka").DetectKafka(detective.KafkaMetadataFetcherFunc(func(ctx context.Context, topics []string) (detective.KafkaMetadata, error) {
+//		m, err := kadm.NewClient(client).Metadata(ctx, topics...)
+//		if err != nil {
+//			return detective.KafkaMetadata{}, err
+//		}
+//		var md detective.KafkaMetadata
+//		for _, b := range m.Brokers {
+//			md.Brokers = append(md.Brokers, net.JoinHostPort(b.Host, strconv.Itoa(int(b.Port))))
+//		}
+//		for _, t := range m.Topics {
+//			if t.Err == nil {
+//				md.Topics = append(md.Topics, t.Topic)
+//			}
+//		}
+//		return md, nil
+//	}), "orders", "payments")
+func (d *Dependency) DetectKafka(f KafkaMetadataFetcher, topics ...string) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		m, err := f.Metadata(ctx, topics)
+		if err != nil {
+			return err
+		}
+		SetDetail(ctx, "brokers", len(m.Brokers))
+		if len(m.Brokers) == 0 {
+			return errors.New("kafka cluster has no brokers")
+		}
+		found := make(map[string]bool, len(m.Topics))
+		for _, topic := range m.Topics {
+			found[topic] = true
+		}
+		var missing []string
+		for _, topic := range topics {
+			if !found[topic] {
+				missing = append(missing, topic)
+			}
+		}
+		if len(missing) > 0 {
+			return errors.New("kafka topics not found: " + strings.Join(missing, ", "))
+		}
+		return nil
+	})
+}
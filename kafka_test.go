@@ -0,0 +1,42 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDetectKafka(t *testing.T) {
+	cluster := KafkaMetadata{Brokers: []string{"kafka-1:9092", "kafka-2:9092"}, Topics: []string{"orders", "payments"}}
+	tests := []struct {
+		name     string
+		metadata KafkaMetadata
+		err      error
+		topics   []string
+		expected State
+	}{
+		{name: "connected", metadata: cluster, expected: State{Name: "kafka", Ok: true, Status: "Ok", Details: map[string]interface{}{"brokers": 2}}},
+		{name: "topics", metadata: cluster, topics: []string{"orders", "payments"}, expected: State{Name: "kafka", Ok: true, Status: "Ok", Details: map[string]interface{}{"brokers": 2}}},
+		{name: "missing topics", metadata: cluster, topics: []string{"orders", "refunds", "users"}, expected: State{Name: "kafka", Status: "Error: kafka topics not found: refunds, users", Details: map[string]interface{}{"brokers": 2}}},
+		{name: "no brokers", expected: State{Name: "kafka", Status: "Error: kafka cluster has no brokers", Details: map[string]interface{}{"brokers": 0}}},
+		{name: "error", err: errors.New("connection refused"), expected: State{Name: "kafka", Status: "Error: connection refused"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested []string
+			var hasDeadline bool
+			d := newDependency("kafka")
+			d.DetectKafka(KafkaMetadataFetcherFunc(func(ctx context.Context, topics []string) (KafkaMetadata, error) {
+				requested = topics
+				_, hasDeadline = ctx.Deadline()
+				return tt.metadata, tt.err
+			}), tt.topics...)
+			s := d.getState(context.Background())
+			assertStatesEqual(t, tt.expected, s)
+			assert.Equal(t, tt.expected.Details, s.Details)
+			assert.Equal(t, tt.topics, requested)
+			assert.True(t, hasDeadline, "metadata request should have a deadline")
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package detective
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// kubernetesServiceAccountDir is the directory in which the credentials of the service account of a pod are mounted
+var kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// KubernetesConfig configures the detector registered with DetectKubernetes
+type KubernetesConfig struct {
+	// Token is the bearer token sent to the API server. If both the Token and the TokenFile are empty, the request is not authenticated.
+	Token string
+	// TokenFile is a file containing the bearer token sent to the API server. It is read before every request, so that rotated tokens are picked up.
+	TokenFile string
+	// TLSConfig is used to connect to the API server, for example to trust the CA of the cluster
+	TLSConfig *tls.Config
+}
+
+// DetectKubernetes registers a detector function that requests the /version endpoint of a Kubernetes API server, at a URL like "https://kubernetes.example.com:6443". The git version of the server is reported in the details of the state of the dependency.
+// If the URL is empty, the API server of the cluster the process runs in is used, with the credentials and CA of the service account of its pod. The request is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectKubernetes(serverURL string, c KubernetesConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		serverURL, c := serverURL, c
+		if serverURL == "" {
+			var err error
+			if serverURL, c, err = inClusterKubernetesConfig(); err != nil {
+				return err
+			}
+		}
+		transport := &http.Transport{TLSClientConfig: c.TLSConfig, Proxy: http.ProxyFromEnvironment}
+		defer transport.CloseIdleConnections()
+		client := &http.Client{Transport: transport}
+		req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(serverURL, "/")+"/version", nil)
+		if err != nil {
+			return err
+		}
+		token := c.Token
+		if c.TokenFile != "" {
+			b, err := os.ReadFile(c.TokenFile)
+			if err != nil {
+				return err
+			}
+			token = strings.TrimSpace(string(b))
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		var version struct {
+			GitVersion string `json:"gitVersion"`
+		}
+		code, err := doJSON(client, req.WithContext(ctx), &version)
+		if err != nil {
+			return err
+		}
+		switch code {
+		case http.StatusOK:
+			SetDetail(ctx, "git_version", version.GitVersion)
+			return nil
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("kubernetes api server refused the credentials with http status: %d", code)
+		default:
+			return fmt.Errorf("kubernetes api server returned http status: %d", code)
+		}
+	})
+}
+
+// inClusterKubernetesConfig returns the URL of the API server of the cluster, and the credentials of the service account of the pod
+func inClusterKubernetesConfig() (string, KubernetesConfig, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return "", KubernetesConfig{}, errors.New("not running in a kubernetes cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+	}
+	ca, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return "", KubernetesConfig{}, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return "", KubernetesConfig{}, errors.New("invalid kubernetes service account ca certificate")
+	}
+	c := KubernetesConfig{TokenFile: filepath.Join(kubernetesServiceAccountDir, "token"), TLSConfig: &tls.Config{RootCAs: roots}}
+	return "https://" + net.JoinHostPort(host, port), c, nil
+}
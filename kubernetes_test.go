@@ -0,0 +1,69 @@
+package detective
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectKubernetes(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer service-account-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"kind": "Status", "status": "Failure", "reason": "Unauthorized", "code": 401}`))
+			return
+		}
+		assert.Equal(t, "/version", r.URL.Path)
+		w.Write([]byte(`{"major": "1", "minor": "29", "gitVersion": "v1.29.2"}`))
+	}))
+	defer s.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate())
+
+	t.Run("token", func(t *testing.T) {
+		d := newDependency("kubernetes")
+		d.DetectKubernetes(s.URL, KubernetesConfig{Token: "service-account-token", TLSConfig: &tls.Config{RootCAs: roots}})
+		st := d.getState(context.Background())
+		assertStatesEqual(t, State{Name: "kubernetes", Ok: true, Status: "Ok"}, st)
+		assert.Equal(t, map[string]interface{}{"git_version": "v1.29.2"}, st.Details)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		d := newDependency("kubernetes")
+		d.DetectKubernetes(s.URL, KubernetesConfig{TLSConfig: &tls.Config{RootCAs: roots}})
+		assertStatesEqual(t, State{Name: "kubernetes", Ok: false, Status: "Error: kubernetes api server refused the credentials with http status: 401"}, d.getState(context.Background()))
+	})
+
+	t.Run("in cluster", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("service-account-token\n"), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600))
+		defer func(previous string) {
+			kubernetesServiceAccountDir = previous
+		}(kubernetesServiceAccountDir)
+		kubernetesServiceAccountDir = dir
+		host, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+		t.Setenv("KUBERNETES_SERVICE_HOST", host)
+		t.Setenv("KUBERNETES_SERVICE_PORT", port)
+
+		d := newDependency("kubernetes")
+		d.DetectKubernetes("", KubernetesConfig{})
+		assertStatesEqual(t, State{Name: "kubernetes", Ok: true, Status: "Ok"}, d.getState(context.Background()))
+	})
+
+	t.Run("not in a cluster", func(t *testing.T) {
+		t.Setenv("KUBERNETES_SERVICE_HOST", "")
+		d := newDependency("kubernetes")
+		d.DetectKubernetes("", KubernetesConfig{})
+		assertStatesEqual(t, State{Name: "kubernetes", Ok: false, Status: "Error: not running in a kubernetes cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set"}, d.getState(context.Background()))
+	})
+}
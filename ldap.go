@@ -0,0 +1,270 @@
+package detective
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+)
+
+// LDAPConfig configures the detector registered with DetectLDAP
+type LDAPConfig struct {
+	// BindDN and Password are the credentials of the service account used to bind. If the BindDN is empty, the bind is anonymous.
+	BindDN   string
+	Password string
+	// BaseDN is an entry that is expected to exist, like "dc=example,dc=com". If set, the entry is searched once bound, and the dependency is unhealthy if it does not exist, or cannot be read with the credentials.
+	BaseDN string
+	// TLSConfig is used to connect to ldaps:// URLs. If the ServerName is not set, the host of the URL is used.
+	TLSConfig *tls.Config
+}
+
+// DetectLDAP registers a detector function that binds to an LDAP server, like OpenLDAP or Active Directory, with a URL like "ldap://localhost" or "ldaps://ldap.example.com". The bind is a simple bind, anonymous or with the credentials of a service account, and is followed by a search for the base DN if one is configured.
+// The default port is 389 (636 for ldaps:// URLs). The check is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectLDAP(ldapURL string, c LDAPConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		u, err := url.Parse(ldapURL)
+		if err != nil {
+			return err
+		}
+		conn, err := dialLDAP(ctx, u, c.TLSConfig)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		stop := context.AfterFunc(ctx, func() {
+			conn.Close()
+		})
+		defer stop()
+		r := bufio.NewReader(conn)
+
+		// BindRequest ::= [APPLICATION 0] SEQUENCE { version INTEGER, name LDAPDN, authentication [0] simple }
+		bind := berElement(0x60, berInt(0x02, 3), berElement(0x04, []byte(c.BindDN)), berElement(0x80, []byte(c.Password)))
+		if err := writeLDAPMessage(conn, 1, bind); err != nil {
+			return err
+		}
+		if err := readLDAPResult(r, 0x61, "bind"); err != nil {
+			return err
+		}
+
+		if c.BaseDN != "" {
+			// SearchRequest ::= [APPLICATION 3] SEQUENCE { baseObject, scope baseObject, derefAliases neverDerefAliases, sizeLimit 1, timeLimit 0, typesOnly FALSE, filter (objectClass=*), attributes "1.1" }
+			// The "1.1" attribute requests no attributes, so that the entry is only checked for existence
+			search := berElement(0x63,
+				berElement(0x04, []byte(c.BaseDN)),
+				berInt(0x0a, 0),
+				berInt(0x0a, 0),
+				berInt(0x02, 1),
+				berInt(0x02, 0),
+				berElement(0x01, []byte{0}),
+				berElement(0x87, []byte("objectClass")),
+				berElement(0x30, berElement(0x04, []byte("1.1"))),
+			)
+			if err := writeLDAPMessage(conn, 2, search); err != nil {
+				return err
+			}
+			if err := readLDAPResult(r, 0x65, "search"); err != nil {
+				return err
+			}
+		}
+
+		// UnbindRequest ::= [APPLICATION 2] NULL
+		return writeLDAPMessage(conn, 3, berElement(0x42))
+	})
+}
+
+func dialLDAP(ctx context.Context, u *url.URL, config *tls.Config) (net.Conn, error) {
+	host, port := u.Hostname(), u.Port()
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+		if config == nil {
+			config = &tls.Config{}
+		} else {
+			config = config.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+		dialer := tls.Dialer{Config: config}
+		return dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	}
+	return nil, fmt.Errorf("unsupported ldap url scheme %q", u.Scheme)
+}
+
+// ldapResultCodes are the names of the result codes that a health check is likely to run into
+var ldapResultCodes = map[int]string{
+	32: "no such object",
+	48: "inappropriate authentication",
+	49: "invalid credentials",
+	50: "insufficient access rights",
+	51: "busy",
+	52: "unavailable",
+	53: "unwilling to perform",
+}
+
+// writeLDAPMessage writes an LDAPMessage ::= SEQUENCE { messageID INTEGER, protocolOp }
+func writeLDAPMessage(w io.Writer, id int, op []byte) error {
+	_, err := w.Write(berElement(0x30, berInt(0x02, id), op))
+	return err
+}
+
+// readLDAPResult reads messages until one with the tag of the expected response, and returns an error unless its LDAPResult is successful. Other responses, like the entries of a search, are skipped.
+func readLDAPResult(r *bufio.Reader, tag byte, operation string) error {
+	for {
+		msgTag, msg, err := readBER(r)
+		if err != nil {
+			return err
+		}
+		if msgTag != 0x30 {
+			return fmt.Errorf("invalid ldap message with tag 0x%02x", msgTag)
+		}
+		elements, err := parseBER(msg)
+		if err != nil {
+			return err
+		}
+		if len(elements) < 2 {
+			return errors.New("invalid ldap message")
+		}
+		op := elements[1]
+		if op.tag == 0x78 {
+			// An ExtendedResponse without a request is a notice of disconnection
+			tag = 0x78
+		}
+		if op.tag != tag {
+			continue
+		}
+		// LDAPResult ::= SEQUENCE { resultCode ENUMERATED, matchedDN LDAPDN, diagnosticMessage LDAPString, ... }
+		result, err := parseBER(op.content)
+		if err != nil {
+			return err
+		}
+		if len(result) < 3 || result[0].tag != 0x0a {
+			return errors.New("invalid ldap result")
+		}
+		code := 0
+		for _, b := range result[0].content {
+			code = code<<8 | int(b)
+		}
+		if code == 0 {
+			return nil
+		}
+		name := ldapResultCodes[code]
+		if name == "" {
+			name = "error"
+		}
+		e := fmt.Sprintf("ldap %s failed: %s (%d)", operation, name, code)
+		if diagnostic := string(result[2].content); diagnostic != "" {
+			e += ": " + diagnostic
+		}
+		return errors.New(e)
+	}
+}
+
+// berElement encodes an element of the Basic Encoding Rules, with the concatenated contents
+func berElement(tag byte, contents ...[]byte) []byte {
+	var content []byte
+	for _, c := range contents {
+		content = append(content, c...)
+	}
+	b := []byte{tag}
+	switch n := len(content); {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n <= 0xff:
+		b = append(b, 0x81, byte(n))
+	case n <= 0xffff:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, content...)
+}
+
+// berInt encodes a non negative integer (or enumerated) element, with the minimal number of bytes
+func berInt(tag byte, v int) []byte {
+	content := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		content = append([]byte{byte(v)}, content...)
+	}
+	if content[0]&0x80 != 0 {
+		content = append([]byte{0}, content...)
+	}
+	return berElement(tag, content)
+}
+
+// maxLDAPMessageSize bounds the size of the messages read from the server
+const maxLDAPMessageSize = 1 << 20
+
+// readBER reads an element of the Basic Encoding Rules with a definite length, as they are used by LDAP
+func readBER(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	length := int(n)
+	if n&0x80 != 0 {
+		size := int(n & 0x7f)
+		if size == 0 || size > 3 {
+			return 0, nil, errors.New("invalid ber length")
+		}
+		length = 0
+		for i := 0; i < size; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return 0, nil, io.ErrUnexpectedEOF
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxLDAPMessageSize {
+		return 0, nil, fmt.Errorf("ldap message of %d bytes is too large", length)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return 0, nil, err
+	}
+	return tag, content, nil
+}
+
+type berValue struct {
+	tag     byte
+	content []byte
+}
+
+// parseBER parses the elements of a constructed element
+func parseBER(content []byte) ([]berValue, error) {
+	var values []berValue
+	r := bufio.NewReader(bytes.NewReader(content))
+	for {
+		tag, c, err := readBER(r)
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return nil, errors.New("invalid ber encoding")
+		}
+		values = append(values, berValue{tag: tag, content: c})
+	}
+}
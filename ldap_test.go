@@ -0,0 +1,102 @@
+package detective
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+// serveLDAP runs a minimal LDAP server, which accepts anonymous binds and the credentials of cn=admin,dc=example,dc=com, and has the dc=example,dc=com entry
+func serveLDAP(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		l.Close()
+	})
+	result := func(id int, tag byte, code int, diagnostic string) []byte {
+		return berElement(0x30, berInt(0x02, id), berElement(tag, berInt(0x0a, code), berElement(0x04), berElement(0x04, []byte(diagnostic))))
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					_, msg, err := readBER(r)
+					if err != nil {
+						return
+					}
+					elements, _ := parseBER(msg)
+					id := int(elements[0].content[0])
+					op := elements[1]
+					fields, _ := parseBER(op.content)
+					switch op.tag {
+					case 0x60:
+						dn, password := string(fields[1].content), string(fields[2].content)
+						if dn != "" && (dn != "cn=admin,dc=example,dc=com" || password != "secret") {
+							conn.Write(result(id, 0x61, 49, "80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error"))
+							continue
+						}
+						conn.Write(result(id, 0x61, 0, ""))
+					case 0x63:
+						if string(fields[0].content) != "dc=example,dc=com" {
+							conn.Write(result(id, 0x65, 32, ""))
+							continue
+						}
+						conn.Write(berElement(0x30, berInt(0x02, id), berElement(0x64, berElement(0x04, fields[0].content), berElement(0x30))))
+						conn.Write(result(id, 0x65, 0, ""))
+					case 0x42:
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestDetectLDAP(t *testing.T) {
+	addr := serveLDAP(t)
+	tests := []struct {
+		name   string
+		url    string
+		c      LDAPConfig
+		status string
+	}{
+		{name: "anonymous", url: "ldap://" + addr, status: "Ok"},
+		{name: "service account", url: "ldap://" + addr, c: LDAPConfig{BindDN: "cn=admin,dc=example,dc=com", Password: "secret", BaseDN: "dc=example,dc=com"}, status: "Ok"},
+		{name: "invalid credentials", url: "ldap://" + addr, c: LDAPConfig{BindDN: "cn=admin,dc=example,dc=com", Password: "wrong"}, status: "Error: ldap bind failed: invalid credentials (49): 80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error"},
+		{name: "missing base dn", url: "ldap://" + addr, c: LDAPConfig{BaseDN: "dc=example,dc=org"}, status: "Error: ldap search failed: no such object (32)"},
+		{name: "unsupported scheme", url: "http://" + addr, status: `Error: unsupported ldap url scheme "http"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("ldap")
+			d.DetectLDAP(tt.url, tt.c)
+			assert.Equal(t, tt.status, d.getState(context.Background()).Status)
+		})
+	}
+}
+
+func TestBER(t *testing.T) {
+	assert.Equal(t, []byte{0x02, 0x01, 0x03}, berInt(0x02, 3))
+	assert.Equal(t, []byte{0x02, 0x02, 0x00, 0x80}, berInt(0x02, 128))
+	assert.Equal(t, []byte{0x02, 0x02, 0x01, 0x00}, berInt(0x02, 256))
+
+	long := make([]byte, 300)
+	element := berElement(0x04, long)
+	assert.Equal(t, []byte{0x04, 0x82, 0x01, 0x2c}, element[:4])
+	values, err := parseBER(append(element, berInt(0x02, 1)...))
+	require.NoError(t, err)
+	assert.Equal(t, []berValue{{tag: 0x04, content: long}, {tag: 0x02, content: []byte{1}}}, values)
+
+	_, err = parseBER([]byte{0x04, 0x05, 0x01})
+	assert.EqualError(t, err, "invalid ber encoding")
+}
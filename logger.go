@@ -0,0 +1,60 @@
+package detective
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Logger is the minimal leveled logging interface used by detective. Adapters for most logging libraries can be written in a few lines.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Debugf(string, ...interface{}) {}
+
+func (noopLogger) Infof(string, ...interface{}) {}
+
+func (noopLogger) Errorf(string, ...interface{}) {}
+
+// SlogLogger adapts a *slog.Logger to the Logger interface. When it is used as the logger of a Detective instance, messages also carry structured attributes, like the names of the instance and of the dependency they are about.
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Debugf(format string, args ...interface{}) {
+	s.l.Debug(fmt.Sprintf(format, args...))
+}
+
+func (s slogLogger) Infof(format string, args ...interface{}) {
+	s.l.Info(fmt.Sprintf(format, args...))
+}
+
+func (s slogLogger) Errorf(format string, args ...interface{}) {
+	s.l.Error(fmt.Sprintf(format, args...))
+}
+
+// log writes the message with the logger of the instance. Structured loggers also receive the attributes, along with the name of the instance.
+func (d *Detective) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	switch l := d.logger.(type) {
+	case slogLogger:
+		l.l.LogAttrs(context.Background(), level, msg, append([]slog.Attr{slog.String("detective", d.name)}, attrs...)...)
+	default:
+		switch {
+		case level >= slog.LevelError:
+			l.Errorf("%s", msg)
+		case level >= slog.LevelInfo:
+			l.Infof("%s", msg)
+		default:
+			l.Debugf("%s", msg)
+		}
+	}
+}
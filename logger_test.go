@@ -0,0 +1,68 @@
+package detective
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	d := New("sample", WithLogger(SlogLogger(l)))
+	failing := false
+	d.Dependency("db").Detect(func() error {
+		if failing {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	d.GetState()
+	failing = true
+	d.GetState()
+
+	var records []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r map[string]interface{}
+		require.NoError(t, dec.Decode(&r))
+		records = append(records, r)
+	}
+	find := func(msg string) map[string]interface{} {
+		for _, r := range records {
+			if r["msg"] == msg {
+				return r
+			}
+		}
+		t.Fatalf("no record with message %q in %v", msg, records)
+		return nil
+	}
+
+	r := find("detective sample: dependency db failed: connection refused")
+	assert.Equal(t, "ERROR", r["level"])
+	assert.Equal(t, "sample", r["detective"])
+	assert.Equal(t, "db", r["dependency"])
+	assert.Equal(t, "connection refused", r["error"])
+
+	r = find("detective sample: dependency db changed from healthy to unhealthy")
+	assert.Equal(t, "INFO", r["level"])
+	assert.Equal(t, "healthy", r["old_health"])
+	assert.Equal(t, "unhealthy", r["health"])
+
+	r = find("detective sample: changed from healthy to unhealthy")
+	assert.Equal(t, "sample", r["detective"])
+
+	r = find("detective sample: checking 1 dependencies")
+	assert.Equal(t, "DEBUG", r["level"])
+}
+
+func TestSlogLoggerAdapter(t *testing.T) {
+	var buf bytes.Buffer
+	l := SlogLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	l.Infof("checked %d dependencies", 2)
+	assert.Contains(t, buf.String(), `level=INFO msg="checked 2 dependencies"`)
+}
@@ -0,0 +1,96 @@
+package detective
+
+import (
+	"time"
+)
+
+// A MaintenanceWindow is a recurring period of planned downtime, during which a dependency is not checked
+type MaintenanceWindow struct {
+	// Days are the days of the week on which the window starts. If empty, the window starts every day.
+	Days []time.Weekday
+	// Start is the time of day at which the window starts, as a duration since midnight (for example, 2*time.Hour for 02:00)
+	Start time.Duration
+	// Duration is the length of the window. A window can extend past midnight.
+	Duration time.Duration
+	// Location is the time zone of the start time. If nil, UTC is used.
+	Location *time.Location
+}
+
+// contains returns true if the time is within an occurrence of the window
+func (w MaintenanceWindow) contains(t time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	// An occurrence that started on the previous days can still be running
+	for days := 0; days <= int((w.Start+w.Duration)/(24*time.Hour)); days++ {
+		y, m, d := t.AddDate(0, 0, -days).Date()
+		start := time.Date(y, m, d, 0, 0, 0, 0, loc).Add(w.Start)
+		if w.startsOn(start.Weekday()) && !t.Before(start) && t.Before(start.Add(w.Duration)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w MaintenanceWindow) startsOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Disable stops checking the dependency until Enable is called. A disabled dependency is reported with a Disabled state, and does not affect the health of the Detective instance, or trigger notifications.
+func (d *Dependency) Disable() *Dependency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.disabled = true
+	return d
+}
+
+// DisableUntil stops checking the dependency until the provided time, or until Enable is called
+func (d *Dependency) DisableUntil(t time.Time) *Dependency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.disabledUntil = t
+	return d
+}
+
+// Enable resumes checking a dependency disabled with Disable or DisableUntil. Maintenance windows still apply.
+func (d *Dependency) Enable() *Dependency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.disabled = false
+	d.disabledUntil = time.Time{}
+	return d
+}
+
+// WithMaintenanceWindow adds a recurring window of planned downtime, during which the dependency is disabled:
+//
+//	// every Sunday, from 02:00 to 04:00 in New York
+//	dep.WithMaintenanceWindow(detective.MaintenanceWindow{Days: []time.Weekday{time.Sunday}, Start: 2 * time.Hour, Duration: 2 * time.Hour, Location: newYork})
+func (d *Dependency) WithMaintenanceWindow(w MaintenanceWindow) *Dependency {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.maintenance = append(d.maintenance, w)
+	return d
+}
+
+// isDisabled must be called with mu held
+func (d *Dependency) isDisabled(now time.Time) bool {
+	if d.disabled || now.Before(d.disabledUntil) {
+		return true
+	}
+	for _, w := range d.maintenance {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
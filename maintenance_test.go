@@ -0,0 +1,98 @@
+package detective
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	// 2024-01-07 is a Sunday
+	sunday := func(hour, min int) time.Time {
+		return time.Date(2024, 1, 7, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		window   MaintenanceWindow
+		t        time.Time
+		expected bool
+	}{
+		{name: "every day, inside", window: MaintenanceWindow{Start: 2 * time.Hour, Duration: time.Hour}, t: sunday(2, 30), expected: true},
+		{name: "every day, at start", window: MaintenanceWindow{Start: 2 * time.Hour, Duration: time.Hour}, t: sunday(2, 0), expected: true},
+		{name: "every day, at end", window: MaintenanceWindow{Start: 2 * time.Hour, Duration: time.Hour}, t: sunday(3, 0), expected: false},
+		{name: "every day, before", window: MaintenanceWindow{Start: 2 * time.Hour, Duration: time.Hour}, t: sunday(1, 59), expected: false},
+		{name: "matching day", window: MaintenanceWindow{Days: []time.Weekday{time.Saturday, time.Sunday}, Start: 2 * time.Hour, Duration: time.Hour}, t: sunday(2, 30), expected: true},
+		{name: "other day", window: MaintenanceWindow{Days: []time.Weekday{time.Monday}, Start: 2 * time.Hour, Duration: time.Hour}, t: sunday(2, 30), expected: false},
+		{name: "past midnight", window: MaintenanceWindow{Days: []time.Weekday{time.Saturday}, Start: 23 * time.Hour, Duration: 2 * time.Hour}, t: sunday(0, 30), expected: true},
+		{name: "past midnight, ended", window: MaintenanceWindow{Days: []time.Weekday{time.Saturday}, Start: 23 * time.Hour, Duration: 2 * time.Hour}, t: sunday(1, 0), expected: false},
+		{name: "several days", window: MaintenanceWindow{Days: []time.Weekday{time.Friday}, Start: 0, Duration: 72 * time.Hour}, t: sunday(12, 0), expected: true},
+		// 07:30 UTC is 02:30 in New York
+		{name: "location", window: MaintenanceWindow{Start: 2 * time.Hour, Duration: time.Hour, Location: newYork}, t: sunday(7, 30), expected: true},
+		{name: "location, outside", window: MaintenanceWindow{Start: 2 * time.Hour, Duration: time.Hour, Location: newYork}, t: sunday(2, 30), expected: false},
+		{name: "empty", window: MaintenanceWindow{}, t: sunday(0, 0), expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.window.contains(tt.t))
+		})
+	}
+}
+
+func TestDependencyDisable(t *testing.T) {
+	d := New("sample")
+	calls := 0
+	db := d.Dependency("db")
+	db.Detect(func() error {
+		calls++
+		return errors.New("failed")
+	})
+	assert.Equal(t, Unhealthy, d.GetState().Health)
+	assert.Equal(t, 1, calls)
+
+	db.Disable()
+	s := d.GetState()
+	assert.Equal(t, Healthy, s.Health)
+	assert.Equal(t, State{Name: "db", Ok: true, Status: "Disabled", Health: Healthy, Disabled: true}, s.Dependencies[0])
+	assert.Equal(t, 1, calls)
+
+	db.Enable()
+	assert.Equal(t, Unhealthy, d.GetState().Health)
+	assert.Equal(t, 2, calls)
+
+	db.DisableUntil(time.Now().Add(time.Hour))
+	assert.Equal(t, Healthy, d.GetState().Health)
+	db.DisableUntil(time.Now().Add(-time.Second))
+	assert.Equal(t, Unhealthy, d.GetState().Health)
+	db.DisableUntil(time.Now().Add(time.Hour)).Enable()
+	assert.Equal(t, Unhealthy, d.GetState().Health)
+
+	db.WithMaintenanceWindow(MaintenanceWindow{Duration: 24 * time.Hour})
+	assert.Equal(t, Healthy, d.GetState().Health)
+	db.Enable()
+	assert.Equal(t, Healthy, d.GetState().Health)
+}
+
+func TestDisabledDependencyNotifications(t *testing.T) {
+	n := &notifications{notifiers: []Notifier{NotifierFunc(nil)}}
+	ok := State{Name: "sample"}.withDependencies([]State{State{Name: "db"}.withOk()})
+	disabled := State{Name: "sample"}.withDependencies([]State{State{Name: "db"}.withDisabled()})
+	failed := State{Name: "sample"}.withDependencies([]State{State{Name: "db"}.withError(errors.New("failed"))})
+
+	events := n.observe("sample", ok)
+	assert.Empty(t, events)
+	events = n.observe("sample", disabled)
+	assert.Empty(t, events)
+	events = n.observe("sample", ok)
+	assert.Empty(t, events)
+	events = n.observe("sample", disabled)
+	assert.Empty(t, events)
+	events = n.observe("sample", failed)
+	require.Len(t, events, 2)
+	assert.Equal(t, "db", events[1].Dependency)
+	assert.Equal(t, Healthy, events[1].Old.Health)
+	assert.False(t, events[1].Old.Disabled)
+}
@@ -0,0 +1,41 @@
+package detective
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DetectMemcached registers a detector function that sends a version command to a memcached server (for example, "localhost:11211"), and reports the version of the server in the details of the state of the dependency. Caches are usually optional, so the dependency can be marked as NonCritical to report the instance as degraded instead of unhealthy when the cache is not available.
+// The check is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectMemcached(addr string) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		if _, err := conn.Write([]byte("version\r\n")); err != nil {
+			return err
+		}
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		version, ok := strings.CutPrefix(line, "VERSION ")
+		if !ok {
+			return fmt.Errorf("unexpected memcached response: %q", line)
+		}
+		SetDetail(ctx, "version", version)
+		return nil
+	})
+}
@@ -0,0 +1,45 @@
+package detective
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestDetectMemcached(t *testing.T) {
+	serve := func(t *testing.T, response string) string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			l.Close()
+		})
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			if line, _ := bufio.NewReader(conn).ReadString('\n'); line == "version\r\n" {
+				conn.Write([]byte(response))
+			}
+		}()
+		return l.Addr().String()
+	}
+
+	t.Run("version", func(t *testing.T) {
+		d := newDependency("memcached")
+		d.DetectMemcached(serve(t, "VERSION 1.6.21\r\n"))
+		st := d.getState(context.Background())
+		assertStatesEqual(t, State{Name: "memcached", Ok: true, Status: "Ok"}, st)
+		assert.Equal(t, map[string]interface{}{"version": "1.6.21"}, st.Details)
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		d := newDependency("memcached")
+		d.DetectMemcached(serve(t, "SERVER_ERROR out of memory\r\n"))
+		assertStatesEqual(t, State{Name: "memcached", Ok: false, Status: `Error: unexpected memcached response: "SERVER_ERROR out of memory"`}, d.getState(context.Background()))
+	})
+}
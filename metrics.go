@@ -0,0 +1,172 @@
+package detective
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// latencyBuckets are the upper bounds (in seconds) of the check latency histogram buckets
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range latencyBuckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// metrics keeps track of the results of health checks, so that they can be exposed in the Prometheus text format
+type metrics struct {
+	mu        sync.Mutex
+	up        float64
+	depUp     map[string]float64
+	depLabels map[string]string
+	durations map[string]*histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		depUp:     map[string]float64{},
+		durations: map[string]*histogram{},
+	}
+}
+
+func (m *metrics) observe(s State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.up = boolToFloat(s.Ok)
+	// The gauges are rebuilt on every observation, so that removed dependencies are no longer exported
+	m.depUp = make(map[string]float64, len(s.Dependencies))
+	m.depLabels = make(map[string]string, len(s.Dependencies))
+	for _, dep := range s.Dependencies {
+		m.depUp[dep.Name] = boolToFloat(dep.Ok)
+		m.depLabels[dep.Name] = metadataLabels(dep.Metadata)
+	}
+	for name := range m.durations {
+		if _, ok := m.depUp[name]; !ok {
+			delete(m.durations, name)
+		}
+	}
+	for _, dep := range s.Dependencies {
+		h, ok := m.durations[dep.Name]
+		if !ok {
+			h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+			m.durations[dep.Name] = h
+		}
+		h.observe(dep.Latency.Seconds())
+	}
+}
+
+func (m *metrics) write(w *bytes.Buffer, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	detectiveLabel := "detective=" + quoteLabel(name)
+
+	w.WriteString("# HELP detective_up Whether the detective instance is healthy (1) or not (0).\n")
+	w.WriteString("# TYPE detective_up gauge\n")
+	fmt.Fprintf(w, "detective_up{%s} %s\n", detectiveLabel, formatFloat(m.up))
+
+	deps := make([]string, 0, len(m.depUp))
+	for dep := range m.depUp {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+
+	w.WriteString("# HELP detective_dependency_up Whether the dependency is healthy (1) or not (0).\n")
+	w.WriteString("# TYPE detective_dependency_up gauge\n")
+	for _, dep := range deps {
+		fmt.Fprintf(w, "detective_dependency_up{%s,dependency=%s%s} %s\n", detectiveLabel, quoteLabel(dep), m.depLabels[dep], formatFloat(m.depUp[dep]))
+	}
+
+	w.WriteString("# HELP detective_check_duration_seconds The time taken to check the health of the dependency.\n")
+	w.WriteString("# TYPE detective_check_duration_seconds histogram\n")
+	for _, dep := range deps {
+		labels := detectiveLabel + ",dependency=" + quoteLabel(dep)
+		h := m.durations[dep]
+		for i, b := range latencyBuckets {
+			fmt.Fprintf(w, "detective_check_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, formatFloat(b), h.counts[i])
+		}
+		fmt.Fprintf(w, "detective_check_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(w, "detective_check_duration_seconds_sum{%s} %s\n", labels, formatFloat(h.sum))
+		fmt.Fprintf(w, "detective_check_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+}
+
+// MetricsHandler returns an HTTP handler that exposes the results of health checks in the Prometheus text format. If periodic checking is not running, the dependencies are checked on every request to the handler.
+func (d *Detective) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := d.getCachedState(); !ok {
+			d.getState(r.Context(), nil)
+		}
+		var body bytes.Buffer
+		d.metrics.write(&body, d.name)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(body.Bytes())
+	})
+}
+
+// reservedLabels are the labels set by detective, which cannot be overridden by metadata
+var reservedLabels = map[string]bool{"detective": true, "dependency": true, "le": true}
+
+// metadataLabels formats the metadata of a dependency as additional labels, sorted by key, with a leading comma. Keys are turned into valid label names, and keys that clash with reserved labels, or with the label of a previous key are skipped.
+func metadataLabels(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	seen := map[string]bool{}
+	for _, key := range keys {
+		name := labelName(key)
+		if name == "" || reservedLabels[name] || strings.HasPrefix(name, "__") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		b.WriteString("," + name + "=" + quoteLabel(metadata[key]))
+	}
+	return b.String()
+}
+
+// labelName replaces the characters that are not allowed in Prometheus label names with underscores
+func labelName(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(v string) string {
+	return `"` + labelReplacer.Replace(v) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package detective
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	d := New("sample")
+	d.Dependency("db").Detect(func() error {
+		return nil
+	})
+	d.Dependency("cache").Detect(func() error {
+		return errors.New("failed")
+	})
+
+	rw := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	require.NoError(t, err)
+	d.MetricsHandler().ServeHTTP(rw, req)
+	res := rw.Result()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	metrics := string(body)
+	assert.Contains(t, metrics, `detective_up{detective="sample"} 0`)
+	assert.Contains(t, metrics, `detective_dependency_up{detective="sample",dependency="db"} 1`)
+	assert.Contains(t, metrics, `detective_dependency_up{detective="sample",dependency="cache"} 0`)
+	assert.Contains(t, metrics, `detective_check_duration_seconds_bucket{detective="sample",dependency="db",le="+Inf"} 1`)
+	assert.Contains(t, metrics, `detective_check_duration_seconds_count{detective="sample",dependency="cache"} 1`)
+}
+
+func TestMetricsRemovedDependency(t *testing.T) {
+	d := New("sample")
+	d.Dependency("db")
+	d.Dependency("cache")
+	d.GetState()
+	d.RemoveDependency("db")
+	d.GetState()
+
+	var body bytes.Buffer
+	d.metrics.write(&body, d.name)
+	assert.NotContains(t, body.String(), `dependency="db"`)
+	assert.Contains(t, body.String(), `detective_dependency_up{detective="sample",dependency="cache"} 1`)
+}
+
+func TestQuoteLabel(t *testing.T) {
+	assert.Equal(t, `"a\"b\\c\nd"`, quoteLabel("a\"b\\c\nd"))
+}
+
+func TestMetricsMetadataLabels(t *testing.T) {
+	d := New("sample")
+	d.Dependency("db").WithMetadata("team", "payments").WithMetadata("runbook-url", "https://runbooks.example.com/db").WithMetadata("dependency", "ignored")
+	s := d.GetState()
+	assert.Equal(t, map[string]string{"team": "payments", "runbook-url": "https://runbooks.example.com/db", "dependency": "ignored"}, s.Dependencies[0].Metadata)
+
+	var body bytes.Buffer
+	d.metrics.write(&body, d.name)
+	assert.Contains(t, body.String(), `detective_dependency_up{detective="sample",dependency="db",runbook_url="https://runbooks.example.com/db",team="payments"} 1`)
+}
+
+func TestMetadataLabels(t *testing.T) {
+	assert.Equal(t, "", metadataLabels(nil))
+	assert.Equal(t, `,_tier="gold",team_name="a"`, metadataLabels(map[string]string{"1tier": "gold", "team-name": "a", "team_name": "b", "le": "x", "__name__": "y"}))
+}
@@ -26,6 +26,8 @@ func MockJSONResponse(body string, status int) *http.Response {
 	if body != "" {
 		r.Body = bytes.NewBuffer([]byte(body))
 	}
-	r.WriteHeader(status)
+	if status != 0 {
+		r.WriteHeader(status)
+	}
 	return r.Result()
 }
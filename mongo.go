@@ -0,0 +1,43 @@
+package detective
+
+import (
+	"context"
+	"time"
+)
+
+// MongoPinger is the minimal interface required to check the health of a MongoDB deployment. The *mongo.Client type of the official driver can be adapted to this interface with a small wrapper, or with the MongoPingerFunc type.
+type MongoPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// The MongoPingerFunc type is an adapter to allow the use of ordinary functions as a MongoPinger
+type MongoPingerFunc func(ctx context.Context) error
+
+// Ping calls f(ctx)
+func (f MongoPingerFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+// DetectMongo registers a detector function that pings a MongoDB deployment. The driver has to select a server before it can run the command, so the time taken by the ping is reported as the server selection latency in the details of the state of the dependency. The ping is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set, which also bounds the server selection.
+//
+// For example, with the go.mongodb.org/mongo-driver client:
+//
+//	d.Dependency("mongo").DetectMongo(detective.MongoPingerFunc(func(ctx context.Context) error {
+//		return client.Ping(ctx, readpref.Primary())
+//	}))
+//
+// or, to run the command on a specific database:
+//
+//	d.Dependency("mongo").DetectMongo(detective.MongoPingerFunc(func(ctx context.Context) error {
+//		return client.Database("orders").RunCommand(ctx, bson.D{{Key: "ping", Value: 1}}).Err()
+//	}))
+func (d *Dependency) DetectMongo(p MongoPinger) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		start := time.Now()
+		err := p.Ping(ctx)
+		SetDetail(ctx, "server_selection_latency_ms", durationMs(time.Since(start)))
+		return err
+	})
+}
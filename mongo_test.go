@@ -0,0 +1,35 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDetectMongo(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var hasDeadline bool
+		d := newDependency("mongo")
+		d.DetectMongo(MongoPingerFunc(func(ctx context.Context) error {
+			_, hasDeadline = ctx.Deadline()
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}))
+		s := d.getState(context.Background())
+		assertStatesEqual(t, State{Name: "mongo", Ok: true, Status: "Ok"}, s)
+		assert.True(t, hasDeadline, "ping should have a deadline")
+		assert.True(t, s.Details["server_selection_latency_ms"].(float64) >= 10, "latency should include the ping")
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		d := newDependency("mongo")
+		d.DetectMongo(MongoPingerFunc(func(ctx context.Context) error {
+			return errors.New("server selection error: context deadline exceeded")
+		}))
+		s := d.getState(context.Background())
+		assertStatesEqual(t, State{Name: "mongo", Ok: false, Status: "Error: server selection error: context deadline exceeded"}, s)
+		assert.Contains(t, s.Details, "server_selection_latency_ms")
+	})
+}
@@ -0,0 +1,136 @@
+package detective
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveMQTT runs a minimal MQTT broker, which accepts anonymous clients and the iot:secret credentials. Subscriptions to topics under "denied/" are refused, and messages published to the "sensors/dropped" topic are not delivered.
+func serveMQTT(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		l.Close()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				header, connect, err := readMQTTPacket(r)
+				if err != nil || header != 0x10 {
+					return
+				}
+				flags := connect[7]
+				rest := connect[10:]
+				next := func() string {
+					n := int(binary.BigEndian.Uint16(rest))
+					s := string(rest[2 : 2+n])
+					rest = rest[2+n:]
+					return s
+				}
+				if next() == "" {
+					writeMQTTPacket(conn, 0x20, []byte{0, 2})
+					return
+				}
+				if flags&0x80 != 0 {
+					user, password := next(), ""
+					if flags&0x40 != 0 {
+						password = next()
+					}
+					if user != "iot" || password != "secret" {
+						writeMQTTPacket(conn, 0x20, []byte{0, 4})
+						return
+					}
+				}
+				writeMQTTPacket(conn, 0x20, []byte{0, 0})
+				var subscribed string
+				for {
+					header, body, err := readMQTTPacket(r)
+					if err != nil {
+						return
+					}
+					switch header >> 4 {
+					case 8:
+						rest = body[2:]
+						subscribed = next()
+						code := byte(0)
+						if strings.HasPrefix(subscribed, "denied/") {
+							code = 0x80
+						}
+						writeMQTTPacket(conn, 0x90, []byte{body[0], body[1], code})
+					case 3:
+						rest = body
+						if topic := next(); topic == subscribed && topic != "sensors/dropped" {
+							// A retained message is delivered before the canary
+							writeMQTTPacket(conn, 0x31, append(mqttString(nil, topic), "retained"...))
+							writeMQTTPacket(conn, 0x30, body)
+						}
+					case 14:
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestDetectMQTT(t *testing.T) {
+	addr := serveMQTT(t)
+	tests := []struct {
+		name   string
+		url    string
+		c      MQTTConfig
+		err    string
+		canary bool
+	}{
+		{name: "anonymous", url: "mqtt://" + addr},
+		{name: "credentials", url: "mqtt://iot:secret@" + addr},
+		{name: "canary", url: "tcp://" + addr, c: MQTTConfig{ClientID: "health", CanaryTopic: "health/canary"}, canary: true},
+		{name: "bad credentials", url: "mqtt://iot:wrong@" + addr, err: "mqtt connection refused: bad user name or password"},
+		{name: "refused subscription", url: "mqtt://" + addr, c: MQTTConfig{CanaryTopic: "denied/canary"}, err: "mqtt broker refused the subscription to denied/canary"},
+		{name: "unsupported scheme", url: "http://" + addr, err: `unsupported mqtt url scheme "http"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("mqtt")
+			d.DetectMQTT(tt.url, tt.c)
+			st := d.getState(context.Background())
+			if tt.err != "" {
+				assert.False(t, st.Ok)
+				assert.Equal(t, tt.err, st.Error)
+				return
+			}
+			assert.True(t, st.Ok, st.Error)
+			assert.Equal(t, tt.canary, st.Details["canary_rtt_ms"] != nil)
+		})
+	}
+
+	t.Run("undelivered canary", func(t *testing.T) {
+		d := newDependency("mqtt").WithTimeout(50 * time.Millisecond)
+		d.DetectMQTT("mqtt://"+addr, MQTTConfig{CanaryTopic: "sensors/dropped"})
+		assert.False(t, d.getState(context.Background()).Ok)
+	})
+}
+
+func TestMQTTRemainingLength(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, writeMQTTPacket(&b, 0x30, make([]byte, 321)))
+	assert.Equal(t, "\x30\xc1\x02", b.String()[:3])
+	header, body, err := readMQTTPacket(bufio.NewReader(strings.NewReader(b.String())))
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x30), header)
+	assert.Len(t, body, 321)
+}
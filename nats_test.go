@@ -0,0 +1,131 @@
+package detective
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveNATS runs a minimal NATS server, which accepts the "secret" token, or the nats:nats credentials. If certificates are provided, clients are required to upgrade the connection to TLS.
+func serveNATS(t *testing.T, certificates []tls.Certificate) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		l.Close()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				tlsRequired := len(certificates) > 0
+				info, _ := json.Marshal(map[string]interface{}{"server_id": "N1", "server_name": "nats-1", "version": "2.10.7", "tls_required": tlsRequired})
+				conn.Write([]byte("INFO " + string(info) + "\r\n"))
+				if tlsRequired {
+					tlsConn := tls.Server(conn, &tls.Config{Certificates: certificates})
+					if tlsConn.Handshake() != nil {
+						return
+					}
+					conn = tlsConn
+				}
+				r := bufio.NewReader(conn)
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				var connect struct {
+					User      string `json:"user"`
+					Pass      string `json:"pass"`
+					AuthToken string `json:"auth_token"`
+				}
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &connect)
+				if connect.AuthToken != "secret" && (connect.User != "nats" || connect.Pass != "nats") {
+					conn.Write([]byte("-ERR 'Authorization Violation'\r\n"))
+					return
+				}
+				if _, err := r.ReadString('\n'); err != nil {
+					return
+				}
+				// The client has to answer pings from the server while it waits for its own pong
+				conn.Write([]byte("PING\r\n"))
+				if line, _ := r.ReadString('\n'); line != "PONG\r\n" {
+					return
+				}
+				conn.Write([]byte("PONG\r\n"))
+			}(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestDetectNATS(t *testing.T) {
+	addr := serveNATS(t, nil)
+	s := httptest.NewTLSServer(http.NotFoundHandler())
+	defer s.Close()
+	tlsAddr := serveNATS(t, s.TLS.Certificates)
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate())
+
+	tests := []struct {
+		name string
+		url  string
+		c    NATSConfig
+		err  string
+	}{
+		{name: "token", url: "nats://secret@" + addr},
+		{name: "credentials", url: "nats://nats:nats@" + addr},
+		{name: "tls", url: "nats://secret@" + tlsAddr, c: NATSConfig{TLSConfig: &tls.Config{RootCAs: roots, ServerName: "example.com"}}},
+		{name: "untrusted certificate", url: "nats://secret@" + tlsAddr, err: "certificate"},
+		{name: "authorization violation", url: "nats://nats:wrong@" + addr, err: "nats server error: Authorization Violation"},
+		{name: "unsupported scheme", url: "http://" + addr, err: `unsupported nats url scheme "http"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("nats")
+			d.DetectNATS(tt.url, tt.c)
+			st := d.getState(context.Background())
+			if tt.err != "" {
+				assert.False(t, st.Ok)
+				assert.Contains(t, st.Error, tt.err)
+				return
+			}
+			assert.True(t, st.Ok, st.Error)
+			assert.Equal(t, "nats-1", st.Details["server_name"])
+			assert.Equal(t, "2.10.7", st.Details["version"])
+			assert.Contains(t, st.Details, "rtt_ms")
+		})
+	}
+}
+
+func TestDetectNATSMonitoring(t *testing.T) {
+	healthy := true
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/healthz", r.URL.Path)
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status": "unavailable", "error": "JetStream stream 'orders' is not current"}`))
+			return
+		}
+		w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer s.Close()
+
+	d := newDependency("nats")
+	d.DetectNATSMonitoring(s.URL, NATSMonitoringConfig{})
+	assertStatesEqual(t, State{Name: "nats", Ok: true, Status: "Ok"}, d.getState(context.Background()))
+
+	healthy = false
+	assertStatesEqual(t, State{Name: "nats", Ok: false, Status: "Error: nats server is unhealthy: JetStream stream 'orders' is not current"}, d.getState(context.Background()))
+}
@@ -0,0 +1,228 @@
+package detective
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// An Event describes a change in the health of a Detective instance, or of one of its dependencies
+type Event struct {
+	// Detective is the name of the Detective instance that observed the change
+	Detective string `json:"detective"`
+	// Dependency is the name of the dependency that changed, and is empty if the change is in the aggregated state of the instance
+	Dependency string    `json:"dependency,omitempty"`
+	Old        State     `json:"old"`
+	New        State     `json:"new"`
+	Time       time.Time `json:"time"`
+}
+
+// A Notifier delivers events about health transitions to an external system
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// NotifierFunc is an adapter to allow the use of an ordinary function as a Notifier
+type NotifierFunc func(ctx context.Context, e Event) error
+
+// Notify calls f(ctx, e)
+func (f NotifierFunc) Notify(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// WebhookNotifier is a Notifier that POSTs each event as a JSON payload to a URL
+type WebhookNotifier struct {
+	URL string
+	// Client is used to send the request. If it is nil, http.DefaultClient is used.
+	Client Doer
+}
+
+// NewWebhookNotifier creates a WebhookNotifier that posts events to the provided URL
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url}
+}
+
+// Notify posts the event to the URL of the webhook, and returns an error if the receiver does not respond with a 2xx status
+func (n *WebhookNotifier) Notify(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, n.Client, n.URL, body)
+}
+
+func postJSON(ctx context.Context, client Doer, url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned http status: %d", url, res.StatusCode)
+	}
+	return nil
+}
+
+// doJSON sends the request with the client, or http.DefaultClient if it is nil, and decodes the JSON body of the response into v. The status code of the response is returned, so that callers can interpret other statuses, whose bodies may not be JSON.
+func doJSON(client Doer, req *http.Request, v interface{}) (int, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil && res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return res.StatusCode, fmt.Errorf("invalid response from %s: %w", req.URL.Redacted(), err)
+	}
+	return res.StatusCode, nil
+}
+
+// notificationTimeout is the maximum duration a notifier is given to deliver a single event
+const notificationTimeout = 10 * time.Second
+
+// notifications keeps track of the last notified state of each entity, so that notifiers are only called when the health of an entity actually changes
+type notifications struct {
+	mu        sync.Mutex
+	notifiers []Notifier
+	queues    []*notificationQueue
+	throttle  time.Duration
+	sent      map[string]notifiedState
+	now       func() time.Time
+}
+
+type notifiedState struct {
+	state State
+	at    time.Time
+}
+
+func (n *notifications) add(notifier Notifier) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifiers = append(n.notifiers, notifier)
+	n.queues = append(n.queues, &notificationQueue{notifier: notifier})
+}
+
+func (n *notifications) setThrottle(d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.throttle = d
+}
+
+// dispatch queues the events caused by the state for delivery by each notifier. Events are queued while the state is observed, so that concurrent checks cannot reorder them.
+func (n *notifications) dispatch(name string, s State, deliver func(Notifier, Event)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	events := n.observeLocked(name, s)
+	if len(events) == 0 {
+		return
+	}
+	for _, q := range n.queues {
+		q.push(events, deliver)
+	}
+}
+
+// observe compares the aggregated state, and the state of each dependency with the state that was last notified, and returns the events that should be delivered.
+// An event is not emitted if the entity was notified less than the throttle duration ago. If the entity keeps changing, the next event once the throttle duration has passed reports the change from the last notified state, so that receivers never miss a transition.
+func (n *notifications) observe(name string, s State) []Event {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.observeLocked(name, s)
+}
+
+func (n *notifications) observeLocked(name string, s State) []Event {
+	if len(n.notifiers) == 0 {
+		return nil
+	}
+	if n.sent == nil {
+		n.sent = map[string]notifiedState{}
+	}
+	now := time.Now()
+	if n.now != nil {
+		now = n.now()
+	}
+
+	var events []Event
+	check := func(key, dependency string, s State) {
+		// Planned downtime is not a change, and the state once the entity is enabled again is compared with the state before it was disabled
+		if s.Disabled {
+			return
+		}
+		last, ok := n.sent[key]
+		if !ok {
+			// The first observed state is not a change
+			n.sent[key] = notifiedState{state: s}
+			return
+		}
+		if effectiveHealth(last.state) == effectiveHealth(s) || now.Sub(last.at) < n.throttle {
+			return
+		}
+		n.sent[key] = notifiedState{state: s, at: now}
+		events = append(events, Event{Detective: name, Dependency: dependency, Old: last.state, New: s, Time: now})
+	}
+	check("", "", s)
+	for _, dep := range s.Dependencies {
+		check("dependency:"+dep.Name, dep.Name, dep)
+	}
+	return events
+}
+
+// notificationQueue delivers the events of a notifier one at a time, in the order in which they were queued, so that a receiver never sees a resolved outage after its resolution. A worker is only running while events are pending.
+type notificationQueue struct {
+	notifier Notifier
+	mu       sync.Mutex
+	pending  []Event
+	running  bool
+}
+
+func (q *notificationQueue) push(events []Event, deliver func(Notifier, Event)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, events...)
+	if q.running {
+		return
+	}
+	q.running = true
+	go q.run(deliver)
+}
+
+func (q *notificationQueue) run(deliver func(Notifier, Event)) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		e := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+		deliver(q.notifier, e)
+	}
+}
+
+// notify delivers the events caused by the latest state to all notifiers in the background, so that slow receivers do not delay health checks
+func (d *Detective) notify(s State) {
+	d.notifications.dispatch(d.name, s, func(notifier Notifier, e Event) {
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		defer cancel()
+		if err := notifier.Notify(ctx, e); err != nil {
+			d.log(slog.LevelError, fmt.Sprintf("detective %s: failed to send notification: %v", d.name, err), slog.String("dependency", e.Dependency), slog.Any("error", err))
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package detective
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	events := make(chan Event, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var e Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		events <- e
+	}))
+	defer s.Close()
+
+	e := Event{
+		Detective:  "sample",
+		Dependency: "sampledep",
+		Old:        State{Name: "sampledep"}.withOk(),
+		New:        State{Name: "sampledep"}.withError(errors.New("failed")),
+		Time:       time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, NewWebhookNotifier(s.URL).Notify(context.Background(), e))
+	received := <-events
+	assert.Equal(t, e.Dependency, received.Dependency)
+	assert.Equal(t, Healthy, received.Old.Health)
+	assert.Equal(t, Unhealthy, received.New.Health)
+	assert.Equal(t, "failed", received.New.Error)
+	assert.True(t, e.Time.Equal(received.Time))
+}
+
+func TestWebhookNotifierError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer s.Close()
+	err := NewWebhookNotifier(s.URL).Notify(context.Background(), Event{})
+	assert.EqualError(t, err, "webhook "+s.URL+" returned http status: 500")
+}
+
+func TestNotifications(t *testing.T) {
+	events := make(chan Event, 10)
+	d := New("sample", WithNotifier(NotifierFunc(func(ctx context.Context, e Event) error {
+		events <- e
+		return nil
+	})))
+	failing := false
+	d.Dependency("sampledep").Detect(func() error {
+		if failing {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	d.Dependency("otherdep").Detect(func() error { return nil })
+
+	ctx := context.Background()
+	d.GetStateContext(ctx)
+	failing = true
+	d.GetStateContext(ctx)
+
+	received := map[string]Event{}
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-events:
+			received[e.Dependency] = e
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for notifications")
+		}
+	}
+	require.Contains(t, received, "")
+	require.Contains(t, received, "sampledep")
+	assert.Equal(t, "sample", received[""].Detective)
+	assert.Equal(t, Healthy, received[""].Old.Health)
+	assert.Equal(t, Unhealthy, received[""].New.Health)
+	assert.Equal(t, "failed", received["sampledep"].New.Error)
+
+	// An unchanged state should not be notified again
+	d.GetStateContext(ctx)
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected notification: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotificationThrottle(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	n := &notifications{now: func() time.Time { return now }}
+	n.add(NotifierFunc(func(ctx context.Context, e Event) error { return nil }))
+	n.setThrottle(time.Minute)
+
+	healthy := State{Name: "sample"}.withOk()
+	unhealthy := State{Name: "sample"}.withError(errors.New("failed"))
+	steps := []struct {
+		after    time.Duration
+		state    State
+		expected []Health
+	}{
+		{0, healthy, nil},
+		{time.Second, unhealthy, []Health{Healthy, Unhealthy}},
+		// Flapping within the throttle duration is not notified
+		{time.Second, healthy, nil},
+		{time.Second, unhealthy, nil},
+		{time.Second, healthy, nil},
+		// Once the duration has passed, the change since the last notification is reported
+		{time.Minute, healthy, []Health{Unhealthy, Healthy}},
+		{time.Minute, healthy, nil},
+	}
+	for i, step := range steps {
+		now = now.Add(step.after)
+		events := n.observe("sample", step.state)
+		if step.expected == nil {
+			assert.Empty(t, events, "step %d", i)
+			continue
+		}
+		require.Len(t, events, 1, "step %d", i)
+		assert.Equal(t, step.expected, []Health{events[0].Old.Health, events[0].New.Health}, "step %d", i)
+	}
+}
+
+func TestNotificationOrder(t *testing.T) {
+	n := &notifications{}
+	n.add(nil)
+	received := make(chan Health, 10)
+	deliver := func(notifier Notifier, e Event) {
+		// A slow delivery of the first event must not let the next one overtake it
+		if e.New.Health == Unhealthy {
+			time.Sleep(20 * time.Millisecond)
+		}
+		received <- e.New.Health
+	}
+	healthy := State{Name: "sample"}.withOk()
+	unhealthy := State{Name: "sample"}.withError(errors.New("failed"))
+	n.dispatch("sample", healthy, deliver)
+	n.dispatch("sample", unhealthy, deliver)
+	n.dispatch("sample", healthy, deliver)
+
+	for _, expected := range []Health{Unhealthy, Healthy} {
+		select {
+		case h := <-received:
+			assert.Equal(t, expected, h)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for notifications")
+		}
+	}
+}
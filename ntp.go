@@ -0,0 +1,93 @@
+package detective
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and the Unix epoch (1970)
+const ntpEpochOffset = 2208988800
+
+// DetectNTP registers a detector function that queries an NTP server (for example, "pool.ntp.org", or "time.example.com:123") with the SNTP protocol, and fails when the offset of the local clock exceeds maxSkew. Skewed clocks silently break the validation of TLS certificates and tokens, and the ordering of traces.
+// The offset and round trip time, and the stratum of the server are reported in the details of the state of the dependency. The default port is 123. The query is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectNTP(server string, maxSkew time.Duration) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "123")
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "udp", server)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+
+		// A client request with leap indicator 0, version 4, and mode 3, which carries its transmit time
+		req := make([]byte, 48)
+		req[0] = 0x23
+		t1 := time.Now()
+		putNTPTime(req[40:], t1)
+		if _, err := conn.Write(req); err != nil {
+			return err
+		}
+		res := make([]byte, 48)
+		for {
+			n, err := conn.Read(res)
+			if err != nil {
+				return err
+			}
+			// Responses to other requests are ignored, since they do not echo the transmit time of this request
+			if n >= 48 && string(res[24:32]) == string(req[40:48]) {
+				break
+			}
+		}
+		t4 := time.Now()
+
+		if mode := res[0] & 0x07; mode != 4 {
+			return fmt.Errorf("unexpected ntp response mode: %d", mode)
+		}
+		stratum := res[1]
+		if stratum == 0 {
+			// A kiss-of-death packet, whose reference identifier is an ASCII code like RATE or DENY
+			return fmt.Errorf("ntp server refused the request: %s", res[12:16])
+		}
+		if res[0]>>6 == 3 {
+			return errors.New("ntp server clock is not synchronized")
+		}
+		t2, t3 := ntpTime(res[32:]), ntpTime(res[40:])
+		offset := (t2.Sub(t1) + t3.Sub(t4)) / 2
+		rtt := t4.Sub(t1) - t3.Sub(t2)
+		SetDetail(ctx, "offset_ms", durationMs(offset))
+		SetDetail(ctx, "rtt_ms", durationMs(rtt))
+		SetDetail(ctx, "stratum", int(stratum))
+		if offset > maxSkew || offset < -maxSkew {
+			return fmt.Errorf("clock offset of %s exceeds %s", offset.Round(time.Millisecond), maxSkew)
+		}
+		return nil
+	})
+}
+
+// putNTPTime encodes a time as an NTP timestamp, with 32 bits of seconds and 32 bits of fraction
+func putNTPTime(b []byte, t time.Time) {
+	nanos := uint64(t.UnixNano()) + ntpEpochOffset*uint64(time.Second)
+	seconds := nanos / uint64(time.Second)
+	fraction := (nanos % uint64(time.Second)) << 32 / uint64(time.Second)
+	binary.BigEndian.PutUint32(b, uint32(seconds))
+	binary.BigEndian.PutUint32(b[4:], uint32(fraction))
+}
+
+func ntpTime(b []byte) time.Time {
+	seconds := uint64(binary.BigEndian.Uint32(b))
+	fraction := uint64(binary.BigEndian.Uint32(b[4:]))
+	nanos := (seconds-ntpEpochOffset)*uint64(time.Second) + fraction*uint64(time.Second)>>32
+	return time.Unix(0, int64(nanos))
+}
@@ -0,0 +1,72 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveNTP runs an NTP server on UDP, whose clock is skewed by the provided offset. A stratum of 0 sends kiss-of-death packets.
+func serveNTP(t *testing.T, skew time.Duration, stratum byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	go func() {
+		req := make([]byte, 48)
+		for {
+			_, addr, err := conn.ReadFrom(req)
+			if err != nil {
+				return
+			}
+			res := make([]byte, 48)
+			res[0] = 0x24
+			res[1] = stratum
+			if stratum == 0 {
+				copy(res[12:], "RATE")
+			}
+			copy(res[24:32], req[40:48])
+			putNTPTime(res[32:], time.Now().Add(skew))
+			putNTPTime(res[40:], time.Now().Add(skew))
+			conn.WriteTo(res, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDetectNTP(t *testing.T) {
+	t.Run("synchronized", func(t *testing.T) {
+		d := newDependency("ntp")
+		d.DetectNTP(serveNTP(t, 0, 2), time.Second)
+		st := d.getState(context.Background())
+		assertStatesEqual(t, State{Name: "ntp", Ok: true, Status: "Ok"}, st)
+		assert.Equal(t, 2, st.Details["stratum"])
+		assert.InDelta(t, 0, st.Details["offset_ms"], 100)
+	})
+
+	t.Run("skewed", func(t *testing.T) {
+		d := newDependency("ntp")
+		d.DetectNTP(serveNTP(t, -3*time.Second, 2), time.Second)
+		st := d.getState(context.Background())
+		assert.False(t, st.Ok)
+		assert.Contains(t, st.Error, "exceeds 1s")
+		assert.InDelta(t, 3000, -st.Details["offset_ms"].(float64), 100)
+	})
+
+	t.Run("kiss of death", func(t *testing.T) {
+		d := newDependency("ntp")
+		d.DetectNTP(serveNTP(t, 0, 0), time.Second)
+		assertStatesEqual(t, State{Name: "ntp", Ok: false, Status: "Error: ntp server refused the request: RATE"}, d.getState(context.Background()))
+	})
+}
+
+func TestNTPTime(t *testing.T) {
+	now := time.Date(2024, 2, 29, 12, 30, 15, 123456789, time.UTC)
+	b := make([]byte, 8)
+	putNTPTime(b, now)
+	assert.WithinDuration(t, now, ntpTime(b), time.Microsecond)
+}
@@ -0,0 +1,123 @@
+package detective
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauth2ExpiryDelta is how long before the expiry of a token a new one is requested, so that tokens do not expire while a request is in flight
+const oauth2ExpiryDelta = 10 * time.Second
+
+// OAuth2Config configures the OAuth2 client credentials grant (RFC 6749, section 4.4) used by the OAuth2ClientCredentials option
+type OAuth2Config struct {
+	ClientID     string
+	ClientSecret string
+	// TokenURL is the token endpoint of the authorization server
+	TokenURL string
+	// Scopes are the optional scopes requested for the token
+	Scopes []string
+	// EndpointParams are additional parameters sent to the token endpoint, like an audience
+	EndpointParams url.Values
+	// Client is used to request tokens. If it is nil, http.DefaultClient is used.
+	Client Doer
+}
+
+// OAuth2ClientCredentials obtains the bearer token sent to the endpoint from an OAuth2 authorization server, with the client credentials grant. The token is cached, and a new one is requested shortly before it expires.
+func OAuth2ClientCredentials(c OAuth2Config) EndpointOption {
+	src := &oauth2TokenSource{config: c, now: time.Now}
+	return TokenProvider(src.token)
+}
+
+type oauth2TokenSource struct {
+	config OAuth2Config
+	now    func() time.Time
+
+	// mu is held while a token is requested, so that concurrent checks share the same token
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+type oauth2TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (s *oauth2TokenSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && (s.expiry.IsZero() || s.now().Before(s.expiry.Add(-oauth2ExpiryDelta))) {
+		return s.accessToken, nil
+	}
+	t, err := s.requestToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.accessToken = t.AccessToken
+	s.expiry = time.Time{}
+	if t.ExpiresIn > 0 {
+		s.expiry = s.now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return s.accessToken, nil
+}
+
+func (s *oauth2TokenSource) requestToken(ctx context.Context) (oauth2TokenResponse, error) {
+	params := url.Values{}
+	for key, values := range s.config.EndpointParams {
+		params[key] = values
+	}
+	params.Set("grant_type", "client_credentials")
+	if len(s.config.Scopes) > 0 {
+		params.Set("scope", strings.Join(s.config.Scopes, " "))
+	}
+	req, err := http.NewRequest(http.MethodPost, s.config.TokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return oauth2TokenResponse{}, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// The credentials are form encoded before being sent with basic authentication (RFC 6749, section 2.3.1)
+	req.SetBasicAuth(url.QueryEscape(s.config.ClientID), url.QueryEscape(s.config.ClientSecret))
+	client := s.config.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return oauth2TokenResponse{}, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return oauth2TokenResponse{}, err
+	}
+	var t oauth2TokenResponse
+	decodeErr := json.Unmarshal(body, &t)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		if decodeErr == nil && t.Error != "" {
+			return t, fmt.Errorf("token endpoint returned http status: %d (%s: %s)", res.StatusCode, t.Error, t.ErrorDescription)
+		}
+		return t, fmt.Errorf("token endpoint returned http status: %d", res.StatusCode)
+	}
+	if decodeErr != nil {
+		return t, fmt.Errorf("invalid token response: %w", decodeErr)
+	}
+	if t.AccessToken == "" {
+		return t, fmt.Errorf("token endpoint returned no access token")
+	}
+	if t.TokenType != "" && !strings.EqualFold(t.TokenType, "bearer") {
+		return t, fmt.Errorf("token endpoint returned unsupported token type %q", t.TokenType)
+	}
+	return t, nil
+}
@@ -0,0 +1,98 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var issued int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "client%3Aid", id)
+		assert.Equal(t, "secret", secret)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "read write", r.PostForm.Get("scope"))
+		assert.Equal(t, "api", r.PostForm.Get("audience"))
+		atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "token", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer s.Close()
+
+	d := New("sample")
+	require.NoError(t, d.EndpointWithOptions(s.URL, ExpectStatus(http.StatusOK), OAuth2ClientCredentials(OAuth2Config{
+		ClientID:       "client:id",
+		ClientSecret:   "secret",
+		TokenURL:       tokenServer.URL,
+		Scopes:         []string{"read", "write"},
+		EndpointParams: url.Values{"audience": {"api"}},
+	})))
+	assert.True(t, d.GetState().Ok)
+	assert.True(t, d.GetState().Ok)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&issued))
+}
+
+func TestOAuth2TokenExpiry(t *testing.T) {
+	issued := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issued++
+		w.Write([]byte(`{"access_token": "token", "expires_in": 60}`))
+	}))
+	defer tokenServer.Close()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := &oauth2TokenSource{config: OAuth2Config{TokenURL: tokenServer.URL}, now: func() time.Time {
+		return now
+	}}
+	for _, elapsed := range []time.Duration{0, 49 * time.Second, 51 * time.Second} {
+		now = now.Add(elapsed)
+		token, err := src.token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "token", token)
+	}
+	// The token is renewed 10 seconds before it expires
+	assert.Equal(t, 2, issued)
+}
+
+func TestOAuth2TokenErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		err      string
+	}{
+		{name: "oauth2 error", status: http.StatusUnauthorized, response: `{"error": "invalid_client", "error_description": "unknown client"}`, err: "token endpoint returned http status: 401 (invalid_client: unknown client)"},
+		{name: "http error", status: http.StatusBadGateway, response: "bad gateway", err: "token endpoint returned http status: 502"},
+		{name: "invalid response", status: http.StatusOK, response: "token", err: "invalid token response: invalid character 'o' in literal true (expecting 'r')"},
+		{name: "missing token", status: http.StatusOK, response: `{}`, err: "token endpoint returned no access token"},
+		{name: "unsupported type", status: http.StatusOK, response: `{"access_token": "token", "token_type": "mac"}`, err: `token endpoint returned unsupported token type "mac"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer tokenServer.Close()
+			src := &oauth2TokenSource{config: OAuth2Config{TokenURL: tokenServer.URL}, now: time.Now}
+			_, err := src.token(context.Background())
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package detective
+
+import (
+	"crypto/tls"
+	"net/url"
+	"time"
+)
+
+// An Option configures a Detective instance created with New
+type Option func(*Detective)
+
+// WithHTTPClient sets the HTTP Client to be used while hitting the endpoint of another detective HTTP ping handler. By default, a zero value http.Client is used.
+func WithHTTPClient(c Doer) Option {
+	return func(d *Detective) {
+		d.client = c
+	}
+}
+
+// WithTimeout sets the maximum duration for checking the health of all dependencies and endpoints. Checks that are still running once the timeout expires are reported as failed. Individual dependencies can have shorter timeouts, set with the Dependency.WithTimeout method.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Detective) {
+		d.timeout = timeout
+	}
+}
+
+// WithMaxConcurrentChecks limits the number of dependencies and endpoints that are checked at the same time, by running the checks with a pool of at most n workers. By default, all of them are checked at once.
+func WithMaxConcurrentChecks(n int) Option {
+	return func(d *Detective) {
+		d.concurrency = n
+	}
+}
+
+// WithLogger sets the logger used to report failed dependencies, changes in health, and the progress of health checks. Use SlogLogger to log with a *slog.Logger, including structured attributes. By default, nothing is logged.
+func WithLogger(l Logger) Option {
+	return func(d *Detective) {
+		d.logger = l
+	}
+}
+
+// WithStatusCode sets the HTTP status code that the HTTP handler responds with when the Detective instance has the given health. By default, the handler responds with 200 when healthy or degraded, and 503 when unhealthy.
+func WithStatusCode(h Health, code int) Option {
+	return func(d *Detective) {
+		d.statusCodes[h] = code
+	}
+}
+
+// WithRedactedErrors removes error details from the response of the HTTP handler. Use this option if the handler is publicly accessible, and errors may leak information about your infrastructure.
+func WithRedactedErrors() Option {
+	return func(d *Detective) {
+		d.redactErrors = true
+	}
+}
+
+// WithFailureThreshold sets the number of consecutive failed checks after which a dependency is reported as unhealthy. Until then, the previously reported state of the dependency continues to be reported. The threshold only applies to checks run in the background with StartPeriodic.
+func WithFailureThreshold(n int) Option {
+	return func(d *Detective) {
+		d.failureThreshold = n
+	}
+}
+
+// WithNotifier registers a notifier that is called whenever the health of the Detective instance, or of one of its dependencies or endpoints changes. Notifications are delivered in the background, and failures to deliver them are logged.
+func WithNotifier(n Notifier) Option {
+	return func(d *Detective) {
+		d.notifications.add(n)
+	}
+}
+
+// WithNotificationThrottle sets the minimum duration between two notifications about the same entity, so that flapping checks do not flood receivers. Changes within the duration are not notified individually, and the next notification about the entity reports the change since the last one. By default, every change is notified.
+func WithNotificationThrottle(t time.Duration) Option {
+	return func(d *Detective) {
+		d.notifications.setThrottle(t)
+	}
+}
+
+// WithCacheTTL caches the state computed by the HTTP handler for the provided duration, so that requests within the duration do not check the dependencies again. Once the cached state is older than the duration, it continues to be served while it is refreshed in the background, which keeps the latency of the handler low even if checks are slow.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(d *Detective) {
+		d.cache.ttl = ttl
+	}
+}
+
+// WithAggregation sets how the states of the dependencies of the Detective instance, or of a group, roll up to its own health. By default, AllMustPass is used.
+//
+//	d.Group("replicas", detective.WithAggregation(detective.Quorum(2)))
+func WithAggregation(a Aggregation) Option {
+	return func(d *Detective) {
+		d.aggregation = a
+	}
+}
+
+// WithTracer sets the tracer used to record spans around health checks. Requests to endpoints carry the trace context of their span, so that traces continue in other detective instances. By default, checks are not traced.
+func WithTracer(t Tracer) Option {
+	return func(d *Detective) {
+		d.tracer = t
+	}
+}
+
+// WithProxy sets the proxy used to connect to all endpoints registered after this option is applied, unless an endpoint has its own proxy set with the Proxy option. HTTP, HTTPS and SOCKS5 proxies are supported, with URLs like "http://proxy.example.com:3128" or "socks5://localhost:1080". The proxy is applied to a copy of the transport of the HTTP client.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(d *Detective) {
+		d.proxy = proxyURL
+	}
+}
+
+// WithMaxDepth limits the depth of the dependency tree reported by the instance. The states of remote detective instances are embedded with their own dependencies, which are dropped beyond n levels below this instance, so that long chains of instances do not inflate the payload. Requests that have already passed through n instances do not check the endpoints of this instance either, so that chains of instances stop fanning out, in addition to the calling chain preventing cycles.
+func WithMaxDepth(n int) Option {
+	return func(d *Detective) {
+		d.maxDepth = n
+	}
+}
+
+// WithCacheControl sets the Cache-Control header of the responses of the HTTP handlers, like "max-age=5" to let external probes and proxies reuse a recent state. Responses always have an ETag computed from their body, so that clients sending it back with If-None-Match get a 304 status instead of an identical payload.
+func WithCacheControl(value string) Option {
+	return func(d *Detective) {
+		d.cacheControl = value
+	}
+}
+
+// WithCORS adds Cross-Origin Resource Sharing headers to the responses of the handler of the instance, and of its SummaryHandler, for requests from the allowed origins, and answers their preflight requests:
+//
+//	d := detective.New("application", detective.WithCORS(detective.CORSConfig{AllowedOrigins: []string{"https://status.example.com"}}))
+func WithCORS(c CORSConfig) Option {
+	return func(d *Detective) {
+		d.cors = &c
+	}
+}
+
+// An Ordering decides the order of the dependencies in the state of an instance
+type Ordering int
+
+const (
+	// RegistrationOrder lists the dependencies first, then the groups, and then the endpoints, each in the order in which they were registered. It is the default ordering.
+	RegistrationOrder Ordering = iota
+	// AlphabeticalOrder sorts the dependencies, groups and endpoints by name
+	AlphabeticalOrder
+)
+
+// WithOrdering sets the order of the dependencies in the state of the instance, and of its groups. Either way, the order does not depend on which check completes first, so that consumers diffing consecutive states do not see spurious changes.
+func WithOrdering(o Ordering) Option {
+	return func(d *Detective) {
+		d.ordering = o
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used to connect to all endpoints registered after this option is applied (for example, to trust an internal CA, or to present a client certificate), unless an endpoint has its own configuration set with the TLSConfig option. The configuration is applied to a copy of the transport of the HTTP client.
+func WithTLSConfig(c *tls.Config) Option {
+	return func(d *Detective) {
+		d.tlsConfig = c
+	}
+}
@@ -0,0 +1,95 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOptions(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		d := New("sample", WithTimeout(10*time.Millisecond))
+		block := make(chan struct{})
+		defer close(block)
+		d.Dependency("blocking").Detect(func() error {
+			<-block
+			return nil
+		})
+		s := d.GetStateContext(context.Background())
+		assert.Equal(t, Unhealthy, s.Health)
+		assert.Equal(t, context.DeadlineExceeded.Error(), s.Dependencies[0].Error)
+	})
+
+	t.Run("max concurrent checks", func(t *testing.T) {
+		d := New("sample", WithMaxConcurrentChecks(2))
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		for i := 0; i < 10; i++ {
+			d.Dependency("dep").Detect(func() error {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+				time.Sleep(5 * time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			})
+		}
+		s := d.GetStateContext(context.Background())
+		assert.Equal(t, Healthy, s.Health)
+		assert.Len(t, s.Dependencies, 10)
+		assert.Equal(t, 2, maxRunning)
+	})
+
+	t.Run("logger", func(t *testing.T) {
+		l := &testLogger{}
+		d := New("sample", WithLogger(l))
+		d.Dependency("db").Detect(func() error {
+			return errors.New("connection refused")
+		})
+		d.GetState()
+		require.NoError(t, d.StartPeriodic(time.Hour))
+		d.Stop()
+
+		messages := l.snapshot()
+		require.True(t, len(messages) >= 5, "%v", messages)
+		assert.Equal(t, "debug: detective sample: checking 1 dependencies", messages[0])
+		assert.True(t, strings.HasPrefix(messages[1], "debug: detective sample: checked 1 dependencies in "), messages[1])
+		assert.Equal(t, "error: detective sample: dependency db failed: connection refused", messages[2])
+		assert.Contains(t, messages, "info: detective sample: checking dependencies every 1h0m0s")
+		assert.Contains(t, messages, "info: detective sample: stopped periodic checks")
+	})
+}
+
+type testLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *testLogger) log(level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, level+": "+fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) snapshot() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.messages...)
+}
+
+func (l *testLogger) Debugf(format string, args ...interface{}) { l.log("debug", format, args...) }
+
+func (l *testLogger) Infof(format string, args ...interface{}) { l.log("info", format, args...) }
+
+func (l *testLogger) Errorf(format string, args ...interface{}) { l.log("error", format, args...) }
@@ -0,0 +1,112 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// StartPeriodic starts checking the health of all registered dependencies and endpoints in the background, once every interval. While periodic checking is running, the HTTP handler responds with the most recently collected state instead of checking every dependency on each request.
+// Calling StartPeriodic again replaces the previous schedule. Call Stop to stop checking in the background. An error is returned if the interval is not positive.
+func (d *Detective) StartPeriodic(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("detective: periodic check interval must be positive, got " + interval.String())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.periodicMu.Lock()
+	if d.stopPeriodic != nil {
+		d.stopPeriodic()
+	}
+	d.stopPeriodic = cancel
+	d.periodicMu.Unlock()
+	d.log(slog.LevelInfo, fmt.Sprintf("detective %s: checking dependencies every %s", d.name, interval), slog.Duration("interval", interval))
+	go d.runPeriodic(ctx, interval)
+	return nil
+}
+
+// Stop stops the background checks started by StartPeriodic. Once stopped, the HTTP handler goes back to checking every dependency on each request.
+func (d *Detective) Stop() {
+	d.periodicMu.Lock()
+	defer d.periodicMu.Unlock()
+	if d.stopPeriodic != nil {
+		d.stopPeriodic()
+		d.stopPeriodic = nil
+		d.log(slog.LevelInfo, fmt.Sprintf("detective %s: stopped periodic checks", d.name))
+	}
+	d.cachedState = nil
+	d.failures = nil
+}
+
+func (d *Detective) runPeriodic(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.refreshCachedState(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Detective) refreshCachedState(ctx context.Context) {
+	s := d.getState(ctx, nil)
+	d.periodicMu.Lock()
+	// A cycle that was interrupted by Stop, or by a newer schedule should not overwrite the cache
+	if ctx.Err() != nil {
+		d.periodicMu.Unlock()
+		return
+	}
+	if d.failureThreshold > 1 {
+		s = d.debounce(s)
+	}
+	d.cachedState = &s
+	d.periodicMu.Unlock()
+	// Hooks are called without holding the lock, so that they can read the state of the instance
+	d.recordState(s)
+}
+
+// debounce replaces the states of dependencies that have failed fewer than d.failureThreshold consecutive times with their previously reported states. It must be called with periodicMu held.
+func (d *Detective) debounce(s State) State {
+	if d.failures == nil {
+		d.failures = map[string]int{}
+	}
+	previous := map[string]State{}
+	if d.cachedState != nil {
+		for _, dep := range d.cachedState.Dependencies {
+			previous[dep.Name] = dep
+		}
+	}
+	dependencies := make([]State, len(s.Dependencies))
+	for i, dep := range s.Dependencies {
+		dependencies[i] = dep
+		if effectiveHealth(dep) != Unhealthy {
+			delete(d.failures, dep.Name)
+			continue
+		}
+		d.failures[dep.Name]++
+		if prev, ok := previous[dep.Name]; ok && d.failures[dep.Name] < d.failureThreshold {
+			dependencies[i] = prev
+		}
+	}
+	aggregate := d.aggregation
+	if aggregate == nil {
+		aggregate = aggregateHealth
+	}
+	// The rest of the state, like its build and the times at which it was checked, is kept, and only its health is aggregated again
+	ns := s
+	return ns.withAggregatedDependencies(dependencies, aggregate)
+}
+
+// getCachedState returns the most recent state collected in the background, if periodic checking is running
+func (d *Detective) getCachedState() (State, bool) {
+	d.periodicMu.RLock()
+	defer d.periodicMu.RUnlock()
+	if d.cachedState == nil {
+		return State{}, false
+	}
+	return *d.cachedState, true
+}
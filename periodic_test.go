@@ -0,0 +1,160 @@
+package detective
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPeriodic(t *testing.T) {
+	d := New("sample")
+	var calls int32
+	d.Dependency("sampledep").Detect(func() error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+
+	require.NoError(t, d.StartPeriodic(time.Hour))
+	defer d.Stop()
+	require.True(t, waitFor(func() bool {
+		_, ok := d.getCachedState()
+		return ok
+	}), "cached state should be populated")
+
+	serve := func() {
+		rw := &httptest.ResponseRecorder{Body: bytes.NewBuffer([]byte{})}
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		d.ServeHTTP(rw, req)
+		assert.Equal(t, http.StatusOK, rw.Result().StatusCode)
+	}
+
+	serve()
+	serve()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "handler should serve the cached state")
+
+	d.Stop()
+	serve()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "handler should check dependencies once stopped")
+}
+
+func TestStartPeriodicInvalidInterval(t *testing.T) {
+	d := New("sample")
+	assert.Error(t, d.StartPeriodic(0))
+	assert.Error(t, d.StartPeriodic(-time.Second))
+	_, ok := d.getCachedState()
+	assert.False(t, ok)
+}
+
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+func TestGetStateWithPeriodic(t *testing.T) {
+	d := New("sample")
+	var calls int32
+	d.Dependency("sampledep").Detect(func() error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+
+	s := d.GetState()
+	assert.Equal(t, Healthy, s.Health)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	require.NoError(t, d.StartPeriodic(time.Hour))
+	defer d.Stop()
+	require.True(t, waitFor(func() bool {
+		_, ok := d.getCachedState()
+		return ok
+	}), "cached state should be populated")
+	s = d.GetState()
+	assert.Equal(t, Healthy, s.Health)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "cached state should be returned")
+}
+
+func TestFailureThreshold(t *testing.T) {
+	d := New("sample", WithFailureThreshold(3))
+	var failing int32
+	d.Dependency("sampledep").Detect(func() error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	ctx := context.Background()
+	d.refreshCachedState(ctx)
+	s, _ := d.getCachedState()
+	assert.Equal(t, Healthy, s.Health)
+
+	atomic.StoreInt32(&failing, 1)
+	for i := 0; i < 2; i++ {
+		d.refreshCachedState(ctx)
+		s, _ = d.getCachedState()
+		assert.Equal(t, Healthy, s.Health, "dependency should not be reported unhealthy before the threshold")
+	}
+	d.refreshCachedState(ctx)
+	s, _ = d.getCachedState()
+	assert.Equal(t, Unhealthy, s.Health)
+	assert.Equal(t, "failed", s.Dependencies[0].Error)
+
+	// A single success resets the count
+	atomic.StoreInt32(&failing, 0)
+	d.refreshCachedState(ctx)
+	atomic.StoreInt32(&failing, 1)
+	d.refreshCachedState(ctx)
+	s, _ = d.getCachedState()
+	assert.Equal(t, Healthy, s.Health)
+}
+
+func TestFailureThresholdWithAggregation(t *testing.T) {
+	d := New("sample", WithFailureThreshold(2), WithAggregation(AnyPass()))
+	d.Dependency("primary").Detect(func() error { return errors.New("failed") })
+	d.Dependency("replica")
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		d.refreshCachedState(ctx)
+	}
+	s, _ := d.getCachedState()
+	assert.Equal(t, Unhealthy, s.Dependencies[0].Health)
+	// The debounced state is aggregated in the same way as a state computed directly
+	assert.Equal(t, Degraded, s.Health)
+	assert.Equal(t, d.GetState().Health, s.Health)
+}
+
+func TestFailureThresholdKeepsBuildInfo(t *testing.T) {
+	d := New("sample", WithFailureThreshold(2), WithBuildInfo("1.2.3", "abc123", "2024-05-31T12:00:00Z"))
+	d.Dependency("db")
+
+	d.refreshCachedState(context.Background())
+	s, _ := d.getCachedState()
+	require.NotNil(t, s.Build)
+	assert.Equal(t, "1.2.3", s.Build.Version)
+}
+
+func TestFailureThresholdKeepsTimestamps(t *testing.T) {
+	d := New("sample", WithFailureThreshold(2))
+	d.Dependency("db")
+
+	d.refreshCachedState(context.Background())
+	s, _ := d.getCachedState()
+	require.NotNil(t, s.CheckedAt)
+	require.NotNil(t, s.LastSuccessAt)
+	assert.Equal(t, *s.CheckedAt, *s.LastSuccessAt)
+}
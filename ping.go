@@ -0,0 +1,245 @@
+package detective
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// PingConfig configures the detector registered with DetectPing
+type PingConfig struct {
+	// Count is the number of echo requests sent on every check. If zero, 3 requests are sent.
+	Count int
+	// Interval is the time to wait between echo requests. If zero, 100 milliseconds are waited.
+	Interval time.Duration
+	// Timeout is the maximum time to wait for each echo reply. If zero, 1 second is waited.
+	Timeout time.Duration
+	// MaxPacketLoss is the percentage (between 0 and 100) of echo requests that can be lost before the dependency is considered unhealthy. By default, the dependency is unhealthy if any request is lost.
+	MaxPacketLoss float64
+	// MaxRTT is the maximum average round trip time. If zero, the round trip time is not checked.
+	MaxRTT time.Duration
+}
+
+// pingStats describes the replies received by ping
+type pingStats struct {
+	sent, received  int
+	min, max, total time.Duration
+}
+
+func (s pingStats) loss() float64 {
+	if s.sent == 0 {
+		return 0
+	}
+	return float64(s.sent-s.received) / float64(s.sent) * 100
+}
+
+func (s pingStats) avg() time.Duration {
+	if s.received == 0 {
+		return 0
+	}
+	return s.total / time.Duration(s.received)
+}
+
+// DetectPing registers a detector function that sends ICMP echo requests to the provided host, and reports the packet loss and round trip times in the details of the state of the dependency.
+// Raw ICMP sockets require elevated privileges (like root, or the CAP_NET_RAW capability on linux). Without them, unprivileged ICMP datagram sockets are used on linux and darwin, which on linux have to be allowed with the net.ipv4.ping_group_range sysctl.
+func (d *Dependency) DetectPing(host string, c PingConfig) {
+	count := c.Count
+	if count <= 0 {
+		count = 3
+	}
+	interval := c.Interval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		stats, err := ping(ctx, host, count, interval, timeout)
+		if err != nil {
+			return err
+		}
+		SetDetail(ctx, "packets_sent", stats.sent)
+		SetDetail(ctx, "packets_received", stats.received)
+		SetDetail(ctx, "packet_loss_percent", stats.loss())
+		if stats.received > 0 {
+			SetDetail(ctx, "rtt_min_ms", durationMs(stats.min))
+			SetDetail(ctx, "rtt_avg_ms", durationMs(stats.avg()))
+			SetDetail(ctx, "rtt_max_ms", durationMs(stats.max))
+		}
+		if stats.received == 0 {
+			return fmt.Errorf("no echo replies received from %s", host)
+		}
+		if stats.loss() > c.MaxPacketLoss {
+			return fmt.Errorf("%.1f%% packet loss to %s, which exceeds %.1f%%", stats.loss(), host, c.MaxPacketLoss)
+		}
+		if c.MaxRTT > 0 && stats.avg() > c.MaxRTT {
+			return fmt.Errorf("average round trip time to %s is %s, which exceeds %s", host, stats.avg(), c.MaxRTT)
+		}
+		return nil
+	})
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+const (
+	icmpv4EchoRequest = 8
+	icmpv4EchoReply   = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// ping sends count echo requests to the host one after the other, and waits for the reply to each of them
+func ping(ctx context.Context, host string, count int, interval, timeout time.Duration) (pingStats, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return pingStats{}, err
+	}
+	if len(addrs) == 0 {
+		return pingStats{}, errors.New("no addresses found for " + host)
+	}
+	ip := addrs[0].IP
+	v4 := ip.To4() != nil
+	conn, privileged, err := listenICMP(v4)
+	if err != nil {
+		return pingStats{}, err
+	}
+	defer conn.Close()
+	return pingConn(ctx, conn, privileged, ip, count, interval, timeout)
+}
+
+// pingConn sends the echo requests with a raw socket if privileged is true, and with a datagram socket otherwise
+func pingConn(ctx context.Context, conn net.PacketConn, privileged bool, ip net.IP, count int, interval, timeout time.Duration) (pingStats, error) {
+	v4 := ip.To4() != nil
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	if !privileged {
+		dst = &net.UDPAddr{IP: ip}
+	}
+
+	stats := pingStats{}
+	id := uint16(rand.Intn(1 << 16))
+	buf := make([]byte, 1500)
+	for seq := 0; seq < count; seq++ {
+		if seq > 0 && !sleep(ctx, interval) {
+			break
+		}
+		sent := time.Now()
+		if _, err := conn.WriteTo(echoRequest(v4, id, uint16(seq)), dst); err != nil {
+			return stats, err
+		}
+		stats.sent++
+		deadline := sent.Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				// A lost packet is not an error, but a check that ran out of time stops sending more
+				if ctx.Err() != nil {
+					return stats, nil
+				}
+				break
+			}
+			// Raw sockets receive all ICMP messages of the host, while the kernel sets the identifier of the echo requests sent with datagram sockets
+			if !sameIP(from, ip) || !isEchoReply(v4, buf[:n], id, uint16(seq), privileged) {
+				continue
+			}
+			rtt := time.Now().Sub(sent)
+			stats.received++
+			stats.total += rtt
+			if stats.min == 0 || rtt < stats.min {
+				stats.min = rtt
+			}
+			if rtt > stats.max {
+				stats.max = rtt
+			}
+			break
+		}
+	}
+	return stats, nil
+}
+
+// listenICMP opens a raw ICMP socket, or an unprivileged ICMP datagram socket if the process is not allowed to open raw sockets
+func listenICMP(v4 bool) (net.PacketConn, bool, error) {
+	network := "ip6:ipv6-icmp"
+	if v4 {
+		network = "ip4:icmp"
+	}
+	conn, err := net.ListenPacket(network, "")
+	if err == nil {
+		return conn, true, nil
+	}
+	if udpConn, udpErr := listenUnprivilegedICMP(v4); udpErr == nil {
+		return udpConn, false, nil
+	}
+	return nil, false, err
+}
+
+func echoRequest(v4 bool, id, seq uint16) []byte {
+	msg := make([]byte, 16)
+	msg[0] = icmpv6EchoRequest
+	if v4 {
+		msg[0] = icmpv4EchoRequest
+	}
+	binary.BigEndian.PutUint16(msg[4:], id)
+	binary.BigEndian.PutUint16(msg[6:], seq)
+	copy(msg[8:], "detectiv")
+	// The checksum of ICMPv6 messages is computed by the kernel, since it covers the IPv6 pseudo header
+	if v4 {
+		binary.BigEndian.PutUint16(msg[2:], icmpChecksum(msg))
+	}
+	return msg
+}
+
+func isEchoReply(v4 bool, msg []byte, id, seq uint16, checkID bool) bool {
+	// Datagram sockets on darwin include the IPv4 header in received messages
+	if v4 && len(msg) >= 20 && msg[0]>>4 == 4 {
+		msg = msg[int(msg[0]&0x0f)*4:]
+	}
+	if len(msg) < 8 {
+		return false
+	}
+	reply := byte(icmpv6EchoReply)
+	if v4 {
+		reply = icmpv4EchoReply
+	}
+	if msg[0] != reply || binary.BigEndian.Uint16(msg[6:]) != seq {
+		return false
+	}
+	return !checkID || binary.BigEndian.Uint16(msg[4:]) == id
+}
+
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+func sameIP(addr net.Addr, ip net.IP) bool {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	}
+	return false
+}
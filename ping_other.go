@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package detective
+
+import (
+	"errors"
+	"net"
+)
+
+func listenUnprivilegedICMP(v4 bool) (net.PacketConn, error) {
+	return nil, errors.New("unprivileged ICMP sockets are only supported on linux and darwin")
+}
@@ -0,0 +1,75 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestEchoRequest(t *testing.T) {
+	msg := echoRequest(true, 0x1234, 7)
+	assert.Equal(t, byte(icmpv4EchoRequest), msg[0])
+	// The checksum of a message including its checksum is zero
+	assert.Equal(t, uint16(0), icmpChecksum(msg))
+
+	reply := append([]byte{}, msg...)
+	reply[0] = icmpv4EchoReply
+	assert.True(t, isEchoReply(true, reply, 0x1234, 7, true))
+	assert.False(t, isEchoReply(true, reply, 0x1234, 8, true))
+	assert.False(t, isEchoReply(true, reply, 0x4321, 7, true))
+	// The identifier of datagram sockets is set by the kernel
+	assert.True(t, isEchoReply(true, reply, 0x4321, 7, false))
+	assert.False(t, isEchoReply(true, msg, 0x1234, 7, true))
+
+	withHeader := append(make([]byte, 20), reply...)
+	withHeader[0] = 0x45
+	assert.True(t, isEchoReply(true, withHeader, 0x1234, 7, true))
+
+	v6 := echoRequest(false, 0x1234, 7)
+	assert.Equal(t, byte(icmpv6EchoRequest), v6[0])
+	v6[0] = icmpv6EchoReply
+	assert.True(t, isEchoReply(false, v6, 0x1234, 7, true))
+}
+
+func TestPingStats(t *testing.T) {
+	s := pingStats{sent: 4, received: 3, total: 30 * time.Millisecond}
+	assert.Equal(t, 25.0, s.loss())
+	assert.Equal(t, 10*time.Millisecond, s.avg())
+	assert.Equal(t, 0.0, pingStats{}.loss())
+	assert.Equal(t, time.Duration(0), pingStats{}.avg())
+}
+
+func TestDetectPing(t *testing.T) {
+	conn, _, err := listenICMP(true)
+	if err != nil {
+		t.Skip("ICMP sockets are not available:", err)
+	}
+	conn.Close()
+
+	d := New("sample")
+	d.Dependency("localhost").DetectPing("127.0.0.1", PingConfig{Count: 2, Interval: time.Millisecond})
+	d.Dependency("slow").DetectPing("127.0.0.1", PingConfig{Count: 1, MaxRTT: time.Nanosecond})
+	s := d.GetState()
+	require.Len(t, s.Dependencies, 2)
+	local := s.Dependencies[0]
+	assert.True(t, local.Ok, local.Error)
+	assert.Equal(t, 2, local.Details["packets_sent"])
+	assert.Equal(t, 2, local.Details["packets_received"])
+	assert.Equal(t, 0.0, local.Details["packet_loss_percent"])
+	assert.Contains(t, local.Details, "rtt_avg_ms")
+	assert.Contains(t, s.Dependencies[1].Error, "average round trip time to 127.0.0.1")
+}
+
+func TestPingUnprivileged(t *testing.T) {
+	conn, err := listenUnprivilegedICMP(true)
+	if err != nil {
+		t.Skip("unprivileged ICMP sockets are not available:", err)
+	}
+	defer conn.Close()
+	stats, err := pingConn(context.Background(), conn, false, net.ParseIP("127.0.0.1"), 1, time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.received)
+}
@@ -0,0 +1,24 @@
+//go:build linux || darwin
+
+package detective
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenUnprivilegedICMP opens an ICMP datagram socket, which can send echo requests without elevated privileges
+func listenUnprivilegedICMP(v4 bool) (net.PacketConn, error) {
+	family, proto := syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+	if v4 {
+		family, proto = syscall.AF_INET, syscall.IPPROTO_ICMP
+	}
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM, proto)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+	return net.FilePacketConn(f)
+}
@@ -0,0 +1,21 @@
+package detective
+
+import (
+	"net/http"
+)
+
+// LivenessHandler returns an HTTP handler that only checks the dependencies marked as liveness checks, which should be in-process self checks (see Dependency.Liveness). Use it for liveness probes, so that a failing external dependency does not cause the process to be restarted. If no dependencies are marked, the handler always reports the instance as healthy.
+func (d *Detective) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := d.checkState(r.Context(), nil, selection{dependency: (*Dependency).isLiveness})
+		if d.redactErrors {
+			s = s.withoutErrors()
+		}
+		d.writeState(w, r, s)
+	})
+}
+
+// ReadinessHandler returns an HTTP handler that checks all dependencies and endpoints, like the Detective instance itself. Use it for readiness probes, so that traffic is not routed to the process while its dependencies are failing.
+func (d *Detective) ReadinessHandler() http.Handler {
+	return d
+}
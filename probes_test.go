@@ -0,0 +1,48 @@
+package detective
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeHandlers(t *testing.T) {
+	d := New("sample")
+	d.Dependency("db").Detect(func() error { return errors.New("connection refused") })
+	d.Dependency("goroutines").DetectGoroutines(math.MaxInt32)
+	d.Dependency("self").Liveness()
+	d.Endpoint("http://localhost:1/does-not-exist")
+
+	getState := func(h http.Handler) (int, State) {
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+		var s State
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &s))
+		return rw.Code, s
+	}
+
+	code, s := getState(d.LivenessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Healthy, s.Health)
+	require.Len(t, s.Dependencies, 2)
+	assert.Equal(t, "goroutines", s.Dependencies[0].Name)
+	assert.Equal(t, "self", s.Dependencies[1].Name)
+
+	code, s = getState(d.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, Unhealthy, s.Health)
+	assert.Len(t, s.Dependencies, 4)
+}
+
+func TestLivenessHandlerWithoutChecks(t *testing.T) {
+	d := New("sample")
+	d.Dependency("db").Detect(func() error { return errors.New("connection refused") })
+	rw := httptest.NewRecorder()
+	d.LivenessHandler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+}
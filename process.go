@@ -0,0 +1,50 @@
+package detective
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+// DetectHeapSize registers a detector function that fails when the heap memory allocated by the process exceeds the provided number of bytes. The current heap size is reported in the details of the state of the dependency, which is marked as a liveness check.
+func (d *Dependency) DetectHeapSize(maxBytes uint64) {
+	d.Liveness()
+	d.DetectContext(func(ctx context.Context) error {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		SetDetail(ctx, "heap_bytes", m.HeapAlloc)
+		if m.HeapAlloc > maxBytes {
+			return fmt.Errorf("heap size of %d bytes exceeds %d", m.HeapAlloc, maxBytes)
+		}
+		return nil
+	})
+}
+
+// DetectRSS registers a detector function that fails when the resident set size of the process exceeds the provided number of bytes. The current resident set size is reported in the details of the state of the dependency, which is marked as a liveness check. It is only supported on Linux.
+func (d *Dependency) DetectRSS(maxBytes uint64) {
+	d.Liveness()
+	d.DetectContext(func(ctx context.Context) error {
+		rss, err := residentSetSize()
+		if err != nil {
+			return err
+		}
+		SetDetail(ctx, "rss_bytes", rss)
+		if rss > maxBytes {
+			return fmt.Errorf("resident set size of %d bytes exceeds %d", rss, maxBytes)
+		}
+		return nil
+	})
+}
+
+// DetectGoroutines registers a detector function that fails when the number of goroutines exceeds the provided maximum, which usually indicates a goroutine leak. The current number of goroutines is reported in the details of the state of the dependency, which is marked as a liveness check.
+func (d *Dependency) DetectGoroutines(max int) {
+	d.Liveness()
+	d.DetectContext(func(ctx context.Context) error {
+		n := runtime.NumGoroutine()
+		SetDetail(ctx, "goroutines", n)
+		if n > max {
+			return fmt.Errorf("%d goroutines are running, which exceeds %d", n, max)
+		}
+		return nil
+	})
+}
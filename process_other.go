@@ -0,0 +1,11 @@
+//go:build !linux
+
+package detective
+
+import (
+	"errors"
+)
+
+func residentSetSize() (uint64, error) {
+	return 0, errors.New("resident set size checks are only supported on linux")
+}
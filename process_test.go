@@ -0,0 +1,47 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"runtime"
+	"testing"
+)
+
+func TestDetectHeapSize(t *testing.T) {
+	d := newDependency("heap")
+	d.DetectHeapSize(math.MaxUint64)
+	s := d.getState(context.Background())
+	assert.Equal(t, Healthy, s.Health)
+	assert.Contains(t, s.Details, "heap_bytes")
+
+	d.DetectHeapSize(1)
+	s = d.getState(context.Background())
+	assert.Equal(t, Unhealthy, s.Health)
+	assert.Contains(t, s.Error, "exceeds 1")
+}
+
+func TestDetectGoroutines(t *testing.T) {
+	d := newDependency("goroutines")
+	d.DetectGoroutines(math.MaxInt32)
+	s := d.getState(context.Background())
+	assert.Equal(t, Healthy, s.Health)
+	assert.Contains(t, s.Details, "goroutines")
+
+	d.DetectGoroutines(0)
+	assert.Equal(t, Unhealthy, d.getState(context.Background()).Health)
+}
+
+func TestDetectRSS(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resident set size checks are only supported on linux")
+	}
+	d := newDependency("rss")
+	d.DetectRSS(math.MaxUint64)
+	s := d.getState(context.Background())
+	assert.Equal(t, Healthy, s.Health, s.Error)
+	assert.True(t, s.Details["rss_bytes"].(uint64) > 0)
+
+	d.DetectRSS(1)
+	assert.Equal(t, Unhealthy, d.getState(context.Background()).Health)
+}
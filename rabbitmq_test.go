@@ -0,0 +1,68 @@
+package detective
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDetectRabbitMQ(t *testing.T) {
+	alarms := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "guest" || pass != "guest" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/api/health/checks/alarms":
+			if alarms {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"status": "failed", "reason": "resource alarm(s) in effect: memory"}`))
+				return
+			}
+			w.Write([]byte(`{"status": "ok"}`))
+		case "/api/queues/%2F/orders":
+			w.Write([]byte(`{"name": "orders", "messages": 5, "consumers": 1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "Object Not Found", "reason": "Not Found"}`))
+		}
+	}))
+	defer s.Close()
+	withCredentials := strings.Replace(s.URL, "http://", "http://guest:guest@", 1)
+
+	tests := []struct {
+		name    string
+		url     string
+		alarms  bool
+		c       RabbitMQConfig
+		err     string
+		details map[string]interface{}
+	}{
+		{name: "healthy", url: s.URL},
+		{name: "queues", url: withCredentials + "/", c: RabbitMQConfig{Queues: []string{"orders"}}, details: map[string]interface{}{
+			"queues": map[string]interface{}{"orders": map[string]interface{}{"messages": 5, "consumers": 1}},
+		}},
+		{name: "missing queues", url: s.URL, c: RabbitMQConfig{Queues: []string{"orders", "refunds"}}, err: "rabbitmq queues not found: refunds", details: map[string]interface{}{
+			"queues": map[string]interface{}{"orders": map[string]interface{}{"messages": 5, "consumers": 1}},
+		}},
+		{name: "alarms", url: s.URL, alarms: true, err: "rabbitmq alarms in effect: resource alarm(s) in effect: memory"},
+		{name: "invalid credentials", url: strings.Replace(s.URL, "http://", "http://guest:wrong@", 1), err: "rabbitmq management api refused the credentials"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alarms = tt.alarms
+			d := New("sample")
+			d.Dependency("rabbitmq").DetectRabbitMQ(tt.url, tt.c)
+			dep := d.GetState().Dependencies[0]
+			if tt.err == "" {
+				assert.True(t, dep.Ok, dep.Error)
+			} else {
+				assert.Equal(t, tt.err, dep.Error)
+			}
+			assert.Equal(t, tt.details, dep.Details)
+		})
+	}
+}
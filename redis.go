@@ -0,0 +1,33 @@
+package detective
+
+import (
+	"context"
+)
+
+// RedisPinger is the minimal interface required to check the health of a Redis server. Most Redis clients can be adapted to this interface with a small wrapper, or with the RedisPingerFunc type.
+type RedisPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// The RedisPingerFunc type is an adapter to allow the use of ordinary functions as a RedisPinger
+type RedisPingerFunc func(ctx context.Context) error
+
+// Ping calls f(ctx)
+func (f RedisPingerFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+// DetectRedis registers a detector function that sends a PING command to a Redis server. The round trip time of the command is reported as the latency of the dependency. The command is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+//
+// For example, with the github.com/go-redis/redis client:
+//
+//	d.Dependency("redis").DetectRedis(detective.RedisPingerFunc(func(ctx context.Context) error {
+//		return client.Ping(ctx).Err()
+//	}))
+func (d *Dependency) DetectRedis(p RedisPinger) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		return p.Ping(ctx)
+	})
+}
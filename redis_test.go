@@ -0,0 +1,29 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDetectRedis(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var hasDeadline bool
+		d := newDependency("redis")
+		d.DetectRedis(RedisPingerFunc(func(ctx context.Context) error {
+			_, hasDeadline = ctx.Deadline()
+			return nil
+		}))
+		assertStatesEqual(t, State{Name: "redis", Ok: true, Status: "Ok"}, d.getState(context.Background()))
+		assert.True(t, hasDeadline, "ping should have a deadline")
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		d := newDependency("redis")
+		d.DetectRedis(RedisPingerFunc(func(ctx context.Context) error {
+			return errors.New("connection refused")
+		}))
+		assertStatesEqual(t, State{Name: "redis", Ok: false, Status: "Error: connection refused"}, d.getState(context.Background()))
+	})
+}
@@ -0,0 +1,142 @@
+package detective
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An UptimeReport is the availability of the instance, or of one of its dependencies, over a rolling window, computed from the results recorded in its history
+type UptimeReport struct {
+	Name   string `json:"name"`
+	Window string `json:"window"`
+	// Checks is the number of results recorded in the window, and Failures the number of them that were unhealthy. Disabled entities are not counted.
+	Checks   int `json:"checks"`
+	Failures int `json:"failures"`
+	// UptimePercent is the percentage of the checks that were not unhealthy, and is nil if there were no checks in the window
+	UptimePercent *float64 `json:"uptime_percent"`
+}
+
+// reportWindow is a rolling window of an uptime report
+type reportWindow struct {
+	label    string
+	duration time.Duration
+}
+
+var defaultReportWindows = []reportWindow{
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// parseReportWindow parses a window like "90m", "12h" or "7d"
+func parseReportWindow(s string) (reportWindow, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return reportWindow{}, fmt.Errorf("invalid window: %s", s)
+		}
+		return reportWindow{s, time.Duration(n) * 24 * time.Hour}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return reportWindow{}, fmt.Errorf("invalid window: %s", s)
+	}
+	return reportWindow{s, d}, nil
+}
+
+// uptimeReports computes the uptime of the entity over each window, ending at now, from its results, which are ordered from the oldest
+func uptimeReports(name string, entries []HistoryEntry, windows []reportWindow, now time.Time) []UptimeReport {
+	reports := make([]UptimeReport, 0, len(windows))
+	for _, w := range windows {
+		r := UptimeReport{Name: name, Window: w.label}
+		start := now.Add(-w.duration)
+		for _, e := range entries {
+			if e.Time.Before(start) || e.Disabled {
+				continue
+			}
+			r.Checks++
+			if e.Health == Unhealthy {
+				r.Failures++
+			}
+		}
+		if r.Checks > 0 {
+			uptime := 100 * float64(r.Checks-r.Failures) / float64(r.Checks)
+			r.UptimePercent = &uptime
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+// ReportHandler returns an HTTP handler that reports the uptime of the instance, and of each of its dependencies, over the last hour, day and 30 days, computed from the results recorded with WithHistory or WithHistoryStore. A store that persists the results, like the one of the detectivebolt package, is needed to report on longer windows than the in-memory history covers.
+// The report is a JSON array of UptimeReport, or a CSV file if it is requested with format=csv, or with an "Accept: text/csv" header. The check query parameter selects a single entity, and the window query parameter, which can be repeated, replaces the default windows, like "?window=7d&window=12h":
+//
+//	GET /health/report?check=db&window=30d&format=csv
+func (d *Detective) ReportHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.history == nil {
+			http.Error(w, "history is not enabled", http.StatusNotFound)
+			return
+		}
+		query := r.URL.Query()
+		windows := defaultReportWindows
+		if values, ok := query["window"]; ok {
+			windows = make([]reportWindow, 0, len(values))
+			for _, v := range values {
+				window, err := parseReportWindow(v)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				windows = append(windows, window)
+			}
+		}
+		var longest time.Duration
+		for _, window := range windows {
+			longest = max(longest, window.duration)
+		}
+
+		names, err := d.history.Names(r.Context())
+		if err != nil {
+			d.historyError(w, err)
+			return
+		}
+		if checks, ok := query["check"]; ok {
+			if !contains(names, checks[0]) {
+				http.Error(w, "no history for "+checks[0], http.StatusNotFound)
+				return
+			}
+			names = checks[:1]
+		}
+		now := time.Now()
+		reports := []UptimeReport{}
+		for _, name := range names {
+			entries, err := d.history.Entries(r.Context(), name, now.Add(-longest), 0)
+			if err != nil {
+				d.historyError(w, err)
+				return
+			}
+			reports = append(reports, uptimeReports(name, entries, windows, now)...)
+		}
+
+		if query.Get("format") != "csv" && !strings.Contains(r.Header.Get("Accept"), "text/csv") {
+			writeJSON(w, http.StatusOK, reports)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"name", "window", "checks", "failures", "uptime_percent"})
+		for _, report := range reports {
+			uptime := ""
+			if report.UptimePercent != nil {
+				uptime = strconv.FormatFloat(*report.UptimePercent, 'f', 3, 64)
+			}
+			cw.Write([]string{report.Name, report.Window, strconv.Itoa(report.Checks), strconv.Itoa(report.Failures), uptime})
+		}
+		cw.Flush()
+	})
+}
@@ -0,0 +1,86 @@
+package detective
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUptimeReports(t *testing.T) {
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	entries := []HistoryEntry{
+		{Time: now.Add(-10 * 24 * time.Hour), Health: Unhealthy},
+		{Time: now.Add(-10 * time.Hour), Health: Unhealthy},
+		{Time: now.Add(-5 * time.Hour), Health: Healthy, Disabled: true},
+		{Time: now.Add(-30 * time.Minute), Health: Degraded},
+		{Time: now.Add(-10 * time.Minute), Health: Healthy},
+	}
+	reports := uptimeReports("db", entries, defaultReportWindows, now)
+	require.Len(t, reports, 3)
+	assert.Equal(t, "1h", reports[0].Window)
+	assert.Equal(t, 2, reports[0].Checks)
+	assert.Equal(t, 100.0, *reports[0].UptimePercent)
+	assert.Equal(t, 3, reports[1].Checks)
+	assert.InDelta(t, 66.667, *reports[1].UptimePercent, 0.001)
+	assert.Equal(t, 4, reports[2].Checks)
+	assert.Equal(t, 2, reports[2].Failures)
+	assert.Equal(t, 50.0, *reports[2].UptimePercent)
+
+	reports = uptimeReports("db", nil, defaultReportWindows[:1], now)
+	assert.Nil(t, reports[0].UptimePercent)
+}
+
+func TestParseReportWindow(t *testing.T) {
+	w, err := parseReportWindow("7d")
+	require.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, w.duration)
+	w, err = parseReportWindow("90m")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, w.duration)
+	for _, invalid := range []string{"d", "-1d", "0s", "week"} {
+		_, err = parseReportWindow(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestReportHandler(t *testing.T) {
+	store := &history{size: 10}
+	now := time.Now().UTC()
+	for i, health := range []Health{Healthy, Unhealthy, Healthy, Healthy} {
+		at := now.Add(time.Duration(i-4) * time.Minute)
+		require.NoError(t, store.Record(context.Background(), map[string]HistoryEntry{
+			"":   {Time: at, Health: health},
+			"db": {Time: at, Health: health},
+		}))
+	}
+	h := New("sample", WithHistoryStore(store)).ReportHandler()
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?check=db&window=1h", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	var reports []UptimeReport
+	require.NoError(t, json.NewDecoder(rw.Body).Decode(&reports))
+	require.Len(t, reports, 1)
+	assert.Equal(t, 75.0, *reports[0].UptimePercent)
+
+	rw = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/?window=1h&window=7d", nil)
+	r.Header.Set("Accept", "text/csv")
+	h.ServeHTTP(rw, r)
+	assert.Equal(t, "text/csv; charset=utf-8", rw.Header().Get("Content-Type"))
+	assert.Equal(t, "name,window,checks,failures,uptime_percent\n,1h,4,1,75.000\n,7d,4,1,75.000\ndb,1h,4,1,75.000\ndb,7d,4,1,75.000\n", rw.Body.String())
+
+	for url, code := range map[string]int{
+		"/?check=redis":  http.StatusNotFound,
+		"/?window=month": http.StatusBadRequest,
+	} {
+		rw = httptest.NewRecorder()
+		h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, code, rw.Code, url)
+	}
+}
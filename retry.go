@@ -0,0 +1,61 @@
+package detective
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures how a failing dependency is retried before it is considered unhealthy
+type RetryPolicy struct {
+	// Attempts is the maximum number of times the detector function is called, including the first attempt
+	Attempts int
+	// Backoff is the duration to wait before the first retry. The duration is doubled after every retry.
+	Backoff time.Duration
+	// MaxBackoff bounds the doubled backoff, and defaults to 30 seconds
+	MaxBackoff time.Duration
+	// Jitter is the maximum random duration added to every backoff, so that retries of many instances are spread out
+	Jitter time.Duration
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.Attempts < 1 {
+		return 1
+	}
+	return p.Attempts
+}
+
+// defaultMaxBackoff is the maximum backoff of a retry policy without a MaxBackoff
+const defaultMaxBackoff = 30 * time.Second
+
+// backoff returns the duration to wait after the given number of failed attempts
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	// The backoff is doubled until it reaches the maximum, so that many attempts do not overflow it. Without a backoff, the detector is retried at once.
+	b := p.Backoff
+	for i := 1; i < attempt && b > 0 && b < maxBackoff; i++ {
+		b *= 2
+	}
+	if b > maxBackoff || (b <= 0 && p.Backoff > 0) {
+		b = maxBackoff
+	}
+	if p.Jitter > 0 {
+		b += time.Duration(rand.Int63n(int64(p.Jitter)))
+	}
+	return b
+}
+
+// sleep waits for the provided duration, and returns false if the context is done before that
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,125 @@
+package detective
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config configures the detector registered with DetectS3
+type S3Config struct {
+	// Endpoint is the URL of the S3 compatible service, like "http://localhost:9000" for MinIO, or "https://storage.googleapis.com" for the interoperability API of Google Cloud Storage. Buckets are addressed with path-style URLs on custom endpoints.
+	// If empty, the bucket is addressed with a virtual-hosted-style URL on AWS, like "https://bucket.s3.us-east-1.amazonaws.com".
+	Endpoint string
+	// Region is used to sign the request. Defaults to "us-east-1".
+	Region string
+	// AccessKeyID, SecretAccessKey and SessionToken are the credentials used to sign the request. If the AccessKeyID is empty, the request is anonymous.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Client is used to send the request. If it is nil, http.DefaultClient is used.
+	Client Doer
+}
+
+// DetectS3 registers a detector function that sends a HEAD request for a bucket of an S3 compatible object storage, signed with the AWS Signature Version 4. This verifies both that the storage is reachable, and that the credentials are authorized to access the bucket, without listing or transferring any objects.
+// The request is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectS3(bucket string, c S3Config) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		region := c.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		bucketURL := "https://" + bucket + ".s3." + region + ".amazonaws.com/"
+		if c.Endpoint != "" {
+			bucketURL = strings.TrimSuffix(c.Endpoint, "/") + "/" + url.PathEscape(bucket)
+		}
+		req, err := http.NewRequest(http.MethodHead, bucketURL, nil)
+		if err != nil {
+			return err
+		}
+		if c.AccessKeyID != "" {
+			signS3Request(req, c.AccessKeyID, c.SecretAccessKey, c.SessionToken, region, time.Now())
+		}
+		client := c.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		res, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		switch res.StatusCode {
+		case http.StatusOK:
+			return nil
+		case http.StatusMovedPermanently:
+			return fmt.Errorf("s3 bucket %s is in the %s region", bucket, res.Header.Get("X-Amz-Bucket-Region"))
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("access to s3 bucket %s was denied with http status: %d", bucket, res.StatusCode)
+		case http.StatusNotFound:
+			return errors.New("s3 bucket not found: " + bucket)
+		default:
+			return fmt.Errorf("s3 returned http status: %d", res.StatusCode)
+		}
+	})
+}
+
+// emptySHA256 is the hash of an empty payload
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signS3Request signs a request without a body for the S3 service with the AWS Signature Version 4. All headers of the request are signed, along with its host.
+func signS3Request(req *http.Request, accessKeyID, secretAccessKey, sessionToken, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{req.Method, path, req.URL.Query().Encode(), canonicalHeaders.String(), signedHeaders, emptySHA256}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,24 @@
+package detective
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// stateSchema is the JSON Schema of the State served by the handler of an instance
+//
+//go:embed state.schema.json
+var stateSchema []byte
+
+// SchemaHandler returns an HTTP handler that serves the JSON Schema of the state reported by the handler of the Detective instance, so that API gateways can validate the health payload, and client generators can type it:
+//
+//	http.Handle("/health/schema", d.SchemaHandler())
+func (d *Detective) SchemaHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.handleCORS(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(stateSchema)
+	})
+}
@@ -0,0 +1,60 @@
+package detective
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type jsonSchema struct {
+	Type       string                `json:"type"`
+	Required   []string              `json:"required"`
+	Properties map[string]jsonSchema `json:"properties"`
+}
+
+// jsonFields returns the JSON names of the fields of the struct type, and the names of the fields that are always encoded
+func jsonFields(t reflect.Type) (names, required []string) {
+	for i := 0; i < t.NumField(); i++ {
+		name, options, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		names = append(names, name)
+		if options != "omitempty" {
+			required = append(required, name)
+		}
+	}
+	return names, required
+}
+
+func TestStateSchemaMatchesStruct(t *testing.T) {
+	var schema jsonSchema
+	require.NoError(t, json.Unmarshal(stateSchema, &schema))
+	names, required := jsonFields(reflect.TypeOf(State{}))
+	properties := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		properties = append(properties, name)
+	}
+	assert.ElementsMatch(t, names, properties)
+	assert.ElementsMatch(t, required, schema.Required)
+
+	names, _ = jsonFields(reflect.TypeOf(BuildInfo{}))
+	properties = properties[:0]
+	for name := range schema.Properties["build"].Properties {
+		properties = append(properties, name)
+	}
+	assert.ElementsMatch(t, names, properties)
+}
+
+func TestSchemaHandler(t *testing.T) {
+	rw := httptest.NewRecorder()
+	New("sample").SchemaHandler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/health/schema", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "application/schema+json", rw.Header().Get("Content-Type"))
+	assert.True(t, json.Valid(rw.Body.Bytes()))
+}
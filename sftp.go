@@ -0,0 +1,123 @@
+package detective
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"io"
+)
+
+// SFTPConfig configures the detector registered with DetectSFTP
+type SFTPConfig struct {
+	// SSHConfig configures the connection to the server, which has to authenticate the client
+	SSHConfig
+	// Path is a file or directory that is expected to exist. If set, the dependency is unhealthy unless the path can be stat'ed.
+	Path string
+}
+
+// DetectSFTP registers a detector function that logs in to an SFTP server (for example, "sftp.example.com:22"), and starts an SFTP session. If a path is configured, it is stat'ed once the session has started.
+// The type and SHA256 fingerprint of the host key of the server are reported in the details of the state of the dependency. The check is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectSFTP(addr string, c SFTPConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		_, err := withSSHClient(ctx, addr, c.SSHConfig, func(client *ssh.Client) error {
+			session, err := client.NewSession()
+			if err != nil {
+				return err
+			}
+			defer session.Close()
+			w, err := session.StdinPipe()
+			if err != nil {
+				return err
+			}
+			r, err := session.StdoutPipe()
+			if err != nil {
+				return err
+			}
+			if err := session.RequestSubsystem("sftp"); err != nil {
+				return err
+			}
+
+			// SSH_FXP_INIT with version 3, which is the version implemented by most servers
+			if err := writeSFTPPacket(w, sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+				return err
+			}
+			typ, _, err := readSFTPPacket(r)
+			if err != nil {
+				return err
+			}
+			if typ != sftpVersion {
+				return fmt.Errorf("unexpected sftp packet of type %d", typ)
+			}
+			if c.Path == "" {
+				return nil
+			}
+
+			// SSH_FXP_STAT with request id 1
+			payload := binary.BigEndian.AppendUint32(nil, 1)
+			payload = binary.BigEndian.AppendUint32(payload, uint32(len(c.Path)))
+			payload = append(payload, c.Path...)
+			if err := writeSFTPPacket(w, sftpStat, payload); err != nil {
+				return err
+			}
+			typ, payload, err = readSFTPPacket(r)
+			if err != nil {
+				return err
+			}
+			switch typ {
+			case sftpAttrs:
+				return nil
+			case sftpStatus:
+				// SSH_FXP_STATUS: uint32 id, uint32 error code, string error message, string language tag
+				if len(payload) < 12 {
+					return errors.New("invalid sftp status")
+				}
+				code := binary.BigEndian.Uint32(payload[4:])
+				msg := payload[12:]
+				if n := binary.BigEndian.Uint32(payload[8:]); int(n) <= len(msg) {
+					msg = msg[:n]
+				}
+				return fmt.Errorf("sftp stat %s failed: %s (%d)", c.Path, msg, code)
+			}
+			return fmt.Errorf("unexpected sftp packet of type %d", typ)
+		})
+		return err
+	})
+}
+
+const (
+	sftpInit    = 1
+	sftpVersion = 2
+	sftpStat    = 17
+	sftpStatus  = 101
+	sftpAttrs   = 105
+
+	// maxSFTPPacketSize bounds the size of the packets read from the server
+	maxSFTPPacketSize = 256 * 1024
+)
+
+func writeSFTPPacket(w io.Writer, typ byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, typ)
+	_, err := w.Write(append(packet, payload...))
+	return err
+}
+
+func readSFTPPacket(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length == 0 || length > maxSFTPPacketSize {
+		return 0, nil, fmt.Errorf("invalid sftp packet length: %d", length)
+	}
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
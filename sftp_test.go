@@ -0,0 +1,64 @@
+package detective
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"testing"
+)
+
+// serveSFTP serves a minimal SFTP session, in which only the /upload path exists
+func serveSFTP(rw io.ReadWriter) {
+	if typ, _, err := readSFTPPacket(rw); err != nil || typ != sftpInit {
+		return
+	}
+	writeSFTPPacket(rw, sftpVersion, binary.BigEndian.AppendUint32(nil, 3))
+	for {
+		typ, payload, err := readSFTPPacket(rw)
+		if err != nil || typ != sftpStat {
+			return
+		}
+		id := payload[:4]
+		if path := string(payload[8:]); path == "/upload" {
+			// Attributes without any fields
+			writeSFTPPacket(rw, sftpAttrs, append(id, 0, 0, 0, 0))
+			continue
+		}
+		status := binary.BigEndian.AppendUint32(id, 2)
+		status = binary.BigEndian.AppendUint32(status, 12)
+		status = append(status, "No such file"...)
+		status = binary.BigEndian.AppendUint32(status, 0)
+		writeSFTPPacket(rw, sftpStatus, status)
+	}
+}
+
+func TestDetectSFTP(t *testing.T) {
+	addr, hostKey := serveSSH(t)
+	credentials := SSHConfig{User: "health", Auth: []ssh.AuthMethod{ssh.Password("secret")}, HostKeyCallback: ssh.FixedHostKey(hostKey)}
+	tests := []struct {
+		name string
+		c    SFTPConfig
+		err  string
+	}{
+		{name: "session", c: SFTPConfig{SSHConfig: credentials}},
+		{name: "existing path", c: SFTPConfig{SSHConfig: credentials, Path: "/upload"}},
+		{name: "missing path", c: SFTPConfig{SSHConfig: credentials, Path: "/download"}, err: "sftp stat /download failed: No such file (2)"},
+		{name: "unauthenticated", c: SFTPConfig{Path: "/upload"}, err: "unable to authenticate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("sftp")
+			d.DetectSFTP(addr, tt.c)
+			st := d.getState(context.Background())
+			if tt.err != "" {
+				assert.False(t, st.Ok)
+				assert.Contains(t, st.Error, tt.err)
+				return
+			}
+			assert.True(t, st.Ok, st.Error)
+			assert.Equal(t, ssh.FingerprintSHA256(hostKey), st.Details["host_key_fingerprint"])
+		})
+	}
+}
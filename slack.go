@@ -0,0 +1,56 @@
+package detective
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SlackNotifier is a Notifier that posts formatted messages about health transitions to a Slack incoming webhook
+type SlackNotifier struct {
+	WebhookURL string
+	// Channel overrides the default channel of the webhook, if it is set
+	Channel string
+	// Client is used to send the request. If it is nil, http.DefaultClient is used.
+	Client Doer
+}
+
+// NewSlackNotifier creates a SlackNotifier that posts messages to the provided incoming webhook URL
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{WebhookURL: webhookURL}
+}
+
+type slackMessage struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+// Notify posts a message describing the event to the webhook
+func (n *SlackNotifier) Notify(ctx context.Context, e Event) error {
+	body, err := json.Marshal(slackMessage{Channel: n.Channel, Text: slackText(e)})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, n.Client, n.WebhookURL, body)
+}
+
+var slackEmoji = map[Health]string{
+	Healthy:   ":large_green_circle:",
+	Degraded:  ":large_yellow_circle:",
+	Unhealthy: ":red_circle:",
+}
+
+func slackText(e Event) string {
+	subject := "*" + e.Detective + "*"
+	if e.Dependency != "" {
+		subject = "*" + e.Dependency + "* on " + subject
+	}
+	newHealth := effectiveHealth(e.New)
+	lines := []string{fmt.Sprintf("%s %s changed from %s → %s", slackEmoji[newHealth], subject, effectiveHealth(e.Old), newHealth)}
+	if e.New.Error != "" {
+		lines = append(lines, "Error: "+e.New.Error)
+	}
+	lines = append(lines, fmt.Sprintf("Latency: %s", e.New.Latency))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,45 @@
+package detective
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSlackNotifier(t *testing.T) {
+	messages := make(chan slackMessage, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+		messages <- m
+	}))
+	defer s.Close()
+
+	n := NewSlackNotifier(s.URL)
+	n.Channel = "#alerts"
+	e := Event{
+		Detective:  "sample",
+		Dependency: "db",
+		Old:        State{Name: "db"}.withOk(),
+		New:        State{Name: "db"}.withError(errors.New("connection refused")).withLatency(12 * time.Millisecond),
+	}
+	require.NoError(t, n.Notify(context.Background(), e))
+	m := <-messages
+	assert.Equal(t, "#alerts", m.Channel)
+	assert.Equal(t, ":red_circle: *db* on *sample* changed from healthy → unhealthy\nError: connection refused\nLatency: 12ms", m.Text)
+}
+
+func TestSlackText(t *testing.T) {
+	e := Event{
+		Detective: "sample",
+		Old:       State{Name: "sample"}.withDegraded(errors.New("degraded dependency")),
+		New:       State{Name: "sample"}.withOk().withLatency(time.Second),
+	}
+	assert.Equal(t, ":large_green_circle: *sample* changed from degraded → healthy\nLatency: 1s", slackText(e))
+}
@@ -0,0 +1,83 @@
+package detective
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+)
+
+// SMTPConfig configures the detector registered with DetectSMTP
+type SMTPConfig struct {
+	// HeloName is the name sent with the EHLO command. If empty, "localhost" is sent.
+	HeloName string
+	// StartTLS upgrades the connection with the STARTTLS command, and verifies the certificate of the server. The dependency is unhealthy if the server does not support STARTTLS.
+	StartTLS bool
+	// ImplicitTLS connects with TLS from the start, as used by SMTP submission on port 465
+	ImplicitTLS bool
+	// TLSConfig is used for the TLS handshake. If the ServerName is not set, the host of the address is used.
+	TLSConfig *tls.Config
+}
+
+// DetectSMTP registers a detector function that connects to the SMTP server at the provided address (for example, "smtp.example.com:587"), and greets it with the EHLO command. The dial and the commands are bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectSMTP(addr string, c SMTPConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+		config := &tls.Config{}
+		if c.TLSConfig != nil {
+			config = c.TLSConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+
+		var conn net.Conn
+		if c.ImplicitTLS {
+			dialer := tls.Dialer{Config: config}
+			conn, err = dialer.DialContext(ctx, "tcp", addr)
+		} else {
+			var dialer net.Dialer
+			conn, err = dialer.DialContext(ctx, "tcp", addr)
+		}
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		// The SMTP client does not use contexts, so the connection is closed if the check is cancelled
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		stop := context.AfterFunc(ctx, func() {
+			conn.Close()
+		})
+		defer stop()
+
+		client, err := smtp.NewClient(conn, host)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		helo := c.HeloName
+		if helo == "" {
+			helo = "localhost"
+		}
+		if err := client.Hello(helo); err != nil {
+			return err
+		}
+		if c.StartTLS {
+			if ok, _ := client.Extension("STARTTLS"); !ok {
+				return errors.New("smtp server " + addr + " does not support STARTTLS")
+			}
+			if err := client.StartTLS(config); err != nil {
+				return err
+			}
+		}
+		return client.Quit()
+	})
+}
@@ -0,0 +1,106 @@
+package detective
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveSMTP runs a minimal SMTP server, which supports STARTTLS if the TLS configuration is set
+func serveSMTP(t *testing.T, config *tls.Config, implicitTLS bool) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		l.Close()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			if implicitTLS {
+				conn = tls.Server(conn, config)
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				conn.Write([]byte("220 localhost ESMTP\r\n"))
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+					case "EHLO":
+						if config != nil && !implicitTLS {
+							conn.Write([]byte("250-localhost\r\n250 STARTTLS\r\n"))
+						} else {
+							conn.Write([]byte("250 localhost\r\n"))
+						}
+					case "STARTTLS":
+						conn.Write([]byte("220 ready\r\n"))
+						conn = tls.Server(conn, config)
+						r = bufio.NewReader(conn)
+					case "QUIT":
+						conn.Write([]byte("221 bye\r\n"))
+						return
+					default:
+						conn.Write([]byte("502 not implemented\r\n"))
+					}
+				}
+			}(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestDetectSMTP(t *testing.T) {
+	// The certificate of a TLS test server is valid for 127.0.0.1
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	serverConfig := &tls.Config{Certificates: s.TLS.Certificates}
+	trusted := &tls.Config{RootCAs: pool}
+
+	plain := serveSMTP(t, nil, false)
+	starttls := serveSMTP(t, serverConfig, false)
+	implicit := serveSMTP(t, serverConfig, true)
+
+	tests := []struct {
+		name string
+		addr string
+		c    SMTPConfig
+		err  string
+	}{
+		{name: "ehlo", addr: plain},
+		{name: "starttls", addr: starttls, c: SMTPConfig{StartTLS: true, TLSConfig: trusted}},
+		{name: "starttls not supported", addr: plain, c: SMTPConfig{StartTLS: true}, err: "smtp server " + plain + " does not support STARTTLS"},
+		{name: "untrusted certificate", addr: starttls, c: SMTPConfig{StartTLS: true}, err: "certificate"},
+		{name: "implicit tls", addr: implicit, c: SMTPConfig{ImplicitTLS: true, TLSConfig: trusted}},
+		{name: "unreachable", addr: "127.0.0.1:1", err: "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New("sample")
+			d.Dependency("smtp").WithTimeout(time.Second).DetectSMTP(tt.addr, tt.c)
+			dep := d.GetState().Dependencies[0]
+			if tt.err == "" {
+				assert.True(t, dep.Ok, dep.Error)
+				return
+			}
+			assert.False(t, dep.Ok)
+			assert.Contains(t, dep.Error, tt.err)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package detective
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DetectSQL registers a detector function that pings the database. The ping is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectSQL(db *sql.DB) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		return db.PingContext(ctx)
+	})
+}
+
+// DetectSQLQuery is similar to DetectSQL, but also runs the provided validation query (like "SELECT 1") once the database has been pinged. The dependency is considered unhealthy if the query returns an error.
+func (d *Dependency) DetectSQLQuery(db *sql.DB, query string) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		if err := db.PingContext(ctx); err != nil {
+			return err
+		}
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		return rows.Err()
+	})
+}
@@ -0,0 +1,114 @@
+package detective
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+// fakeDriver is an SQL driver whose connections fail to ping, or to query, depending on the DSN
+type fakeDriver struct{}
+
+type fakeConn struct {
+	dsn string
+}
+
+type fakeRows struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{dsn: dsn}, nil
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	if c.dsn == "ping-error" {
+		return errors.New("ping failed")
+	}
+	return nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.dsn == "query-error" {
+		return nil, errors.New("query failed")
+	}
+	return fakeRows{}, nil
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (fakeRows) Columns() []string {
+	return []string{"1"}
+}
+
+func (fakeRows) Close() error {
+	return nil
+}
+
+func (fakeRows) Next(dest []driver.Value) error {
+	return io.EOF
+}
+
+func init() {
+	sql.Register("detective-fake", fakeDriver{})
+}
+
+func TestDetectSQL(t *testing.T) {
+	tests := []struct {
+		name          string
+		dsn           string
+		query         string
+		expectedState State
+	}{
+		{
+			name:          "success",
+			dsn:           "ok",
+			expectedState: State{Name: "db", Ok: true, Status: "Ok"},
+		},
+		{
+			name:          "ping failure",
+			dsn:           "ping-error",
+			expectedState: State{Name: "db", Ok: false, Status: "Error: ping failed"},
+		},
+		{
+			name:          "query success",
+			dsn:           "ok",
+			query:         "SELECT 1",
+			expectedState: State{Name: "db", Ok: true, Status: "Ok"},
+		},
+		{
+			name:          "query failure",
+			dsn:           "query-error",
+			query:         "SELECT 1",
+			expectedState: State{Name: "db", Ok: false, Status: "Error: query failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := sql.Open("detective-fake", tt.dsn)
+			require.NoError(t, err)
+			defer db.Close()
+
+			d := newDependency("db")
+			if tt.query == "" {
+				d.DetectSQL(db)
+			} else {
+				d.DetectSQLQuery(db, tt.query)
+			}
+			assertStatesEqual(t, tt.expectedState, d.getState(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package detective
+
+import (
+	"context"
+	"golang.org/x/crypto/ssh"
+	"net"
+)
+
+// SSHConfig configures the detector registered with DetectSSH
+type SSHConfig struct {
+	// User and Auth are the credentials used to authenticate. If no authentication methods are provided, the dependency is healthy once the key exchange is complete, even though the server does not authenticate the client.
+	User string
+	Auth []ssh.AuthMethod
+	// HostKeyCallback verifies the host key of the server, for example with the golang.org/x/crypto/ssh/knownhosts package, or ssh.FixedHostKey. If it is nil, any host key is accepted.
+	HostKeyCallback ssh.HostKeyCallback
+}
+
+// DetectSSH registers a detector function that completes an SSH handshake with a server (for example, "bastion.example.com:22"), without opening a session. The type and SHA256 fingerprint of the host key of the server are reported in the details of the state of the dependency.
+// The handshake is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectSSH(addr string, c SSHConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		exchanged, err := withSSHClient(ctx, addr, c, func(client *ssh.Client) error {
+			return nil
+		})
+		if err != nil && exchanged && len(c.Auth) == 0 {
+			return nil
+		}
+		return err
+	})
+}
+
+// withSSHClient connects and authenticates to an SSH server, and calls f with the client. It reports whether the key exchange was complete, even if the authentication failed. The type and fingerprint of the host key are set as details of the context.
+func withSSHClient(ctx context.Context, addr string, c SSHConfig, f func(client *ssh.Client) error) (bool, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	// The host key callback is called once the key exchange is complete, and the server has proven that it holds the host key
+	exchanged := false
+	config := &ssh.ClientConfig{
+		User: c.User,
+		Auth: c.Auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if c.HostKeyCallback != nil {
+				if err := c.HostKeyCallback(hostname, remote, key); err != nil {
+					return err
+				}
+			}
+			exchanged = true
+			SetDetail(ctx, "host_key_type", key.Type())
+			SetDetail(ctx, "host_key_fingerprint", ssh.FingerprintSHA256(key))
+			return nil
+		},
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		return exchanged, err
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+	return exchanged, f(client)
+}
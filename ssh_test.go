@@ -0,0 +1,123 @@
+package detective
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"testing"
+)
+
+// serveSSH runs an SSH server, which accepts the health:secret credentials, and returns its address and host key. Sessions can only start the SFTP subsystem, which is served by serveSFTP.
+func serveSSH(t *testing.T) (string, ssh.PublicKey) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(private)
+	require.NoError(t, err)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() == "health" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("invalid credentials")
+		},
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		l.Close()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				defer sshConn.Close()
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					if ch.ChannelType() != "session" {
+						ch.Reject(ssh.UnknownChannelType, "unknown channel type")
+						continue
+					}
+					channel, requests, err := ch.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						defer channel.Close()
+						for req := range requests {
+							// The payload of a subsystem request is the name of the subsystem, as an SSH string
+							ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+							req.Reply(ok, nil)
+							if ok {
+								serveSFTP(channel)
+								return
+							}
+						}
+					}()
+				}
+			}(conn)
+		}
+	}()
+	return l.Addr().String(), signer.PublicKey()
+}
+
+func TestDetectSSH(t *testing.T) {
+	addr, hostKey := serveSSH(t)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(otherKey)
+
+	tests := []struct {
+		name string
+		c    SSHConfig
+		err  string
+	}{
+		{name: "unauthenticated", c: SSHConfig{}},
+		{name: "authenticated", c: SSHConfig{User: "health", Auth: []ssh.AuthMethod{ssh.Password("secret")}, HostKeyCallback: ssh.FixedHostKey(hostKey)}},
+		{name: "invalid credentials", c: SSHConfig{User: "health", Auth: []ssh.AuthMethod{ssh.Password("wrong")}}, err: "unable to authenticate"},
+		{name: "unknown host key", c: SSHConfig{HostKeyCallback: ssh.FixedHostKey(otherSigner.PublicKey())}, err: "host key mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("ssh")
+			d.DetectSSH(addr, tt.c)
+			st := d.getState(context.Background())
+			if tt.err != "" {
+				assert.False(t, st.Ok)
+				assert.Contains(t, st.Error, tt.err)
+				return
+			}
+			assert.True(t, st.Ok, st.Error)
+			assert.Equal(t, map[string]interface{}{"host_key_type": "ssh-ed25519", "host_key_fingerprint": ssh.FingerprintSHA256(hostKey)}, st.Details)
+		})
+	}
+
+	t.Run("not an ssh server", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+		go func() {
+			conn, err := l.Accept()
+			if err == nil {
+				conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+				conn.Close()
+			}
+		}()
+		d := newDependency("ssh")
+		d.DetectSSH(l.Addr().String(), SSHConfig{})
+		assert.False(t, d.getState(context.Background()).Ok)
+	})
+}
@@ -5,13 +5,38 @@ import (
 	"time"
 )
 
-// State describes the current status of an entity. This entity can be a Dependency, or a Detective instance. A State can contain other States as well.
+// Health describes how healthy an entity is
+type Health string
+
+const (
+	// Healthy indicates that an entity, and all of its dependencies are working as expected
+	Healthy Health = "healthy"
+	// Degraded indicates that an entity is working, but some of its non-critical dependencies have failed
+	Degraded Health = "degraded"
+	// Unhealthy indicates that an entity, or one of its dependencies has failed
+	Unhealthy Health = "unhealthy"
+)
+
+// State describes the current status of an entity. This entity can be a Dependency, or a Detective instance. A State can contain other States as well, in the order set with WithOrdering.
+// The JSON field names of a State are a stable schema, which is served as a JSON Schema by SchemaHandler. Fields may be added, but existing ones are not renamed or removed.
+// The Details of a dependency contain additional information reported by its detector function with SetDetail. Disabled entities are not checked, and are ignored when aggregating the health of their parent. CheckedAt is the time at which the entity was last checked, and LastSuccessAt the last time at which it was found to be working. The Metadata of a dependency is set with WithMetadata, and the Build of a Detective instance with WithBuildInfo.
 type State struct {
-	Name         string        `json:"name"`
-	Ok           bool          `json:"active"`
-	Status       string        `json:"status"`
-	Latency      time.Duration `json:"latency"`
-	Dependencies []State       `json:"dependencies,omitempty"`
+	Name          string                 `json:"name"`
+	Ok            bool                   `json:"active"`
+	Status        string                 `json:"status"`
+	Error         string                 `json:"error,omitempty"`
+	Health        Health                 `json:"health"`
+	NonCritical   bool                   `json:"non_critical,omitempty"`
+	Disabled      bool                   `json:"disabled,omitempty"`
+	Attempts      int                    `json:"attempts,omitempty"`
+	Latency       time.Duration          `json:"latency"`
+	LatencyMs     float64                `json:"latency_ms"`
+	Details       map[string]interface{} `json:"details,omitempty"`
+	Metadata      map[string]string      `json:"metadata,omitempty"`
+	CheckedAt     *time.Time             `json:"checked_at,omitempty"`
+	LastSuccessAt *time.Time             `json:"last_success_at,omitempty"`
+	Build         *BuildInfo             `json:"build,omitempty"`
+	Dependencies  []State                `json:"dependencies,omitempty"`
 }
 
 func (s State) withError(err error) State {
@@ -21,6 +46,8 @@ func (s State) withError(err error) State {
 	ns := s
 	ns.Ok = false
 	ns.Status = "Error: " + err.Error()
+	ns.Error = err.Error()
+	ns.Health = Unhealthy
 	return ns
 }
 
@@ -28,23 +55,148 @@ func (s State) withOk() State {
 	ns := s
 	ns.Ok = true
 	ns.Status = "Ok"
+	ns.Error = ""
+	ns.Health = Healthy
+	return ns
+}
+
+func (s State) withLatency(latency time.Duration) State {
+	ns := s
+	ns.Latency = latency
+	ns.LatencyMs = float64(latency) / float64(time.Millisecond)
+	return ns
+}
+
+func (s State) withDegraded(err error) State {
+	ns := s
+	ns.Ok = true
+	ns.Status = "Degraded: " + err.Error()
+	ns.Error = err.Error()
+	ns.Health = Degraded
+	return ns
+}
+
+// withDisabled reports an entity that was not checked, because it is disabled
+func (s State) withDisabled() State {
+	ns := s.withOk()
+	ns.Status = "Disabled"
+	ns.Disabled = true
 	return ns
 }
 
 func (s State) withDependencies(dependencies []State) State {
+	return s.withAggregatedDependencies(dependencies, aggregateHealth)
+}
+
+func (s State) withAggregatedDependencies(dependencies []State, aggregate Aggregation) State {
 	finalState := s
 	finalState.Dependencies = dependencies
-	if !noErrors(dependencies) {
+	switch aggregate(enabledStates(dependencies)) {
+	case Unhealthy:
 		return finalState.withError(errors.New("dependency failure"))
+	case Degraded:
+		return finalState.withDegraded(errors.New("degraded dependency"))
 	}
 	return finalState.withOk()
 }
 
-func noErrors(states []State) bool {
+// enabledStates returns the states that are not disabled
+func enabledStates(states []State) []State {
+	enabled := states
+	for i := range states {
+		if states[i].Disabled {
+			enabled = make([]State, 0, len(states))
+			for _, s := range states {
+				if !s.Disabled {
+					enabled = append(enabled, s)
+				}
+			}
+			break
+		}
+	}
+	return enabled
+}
+
+// aggregateHealth returns Unhealthy if any critical state is unhealthy, and Degraded if any other state is not healthy
+func aggregateHealth(states []State) Health {
+	h := Healthy
 	for i := range states {
-		if !states[i].Ok {
-			return false
+		switch effectiveHealth(states[i]) {
+		case Unhealthy:
+			if !states[i].NonCritical {
+				return Unhealthy
+			}
+			h = Degraded
+		case Degraded:
+			h = Degraded
+		}
+	}
+	return h
+}
+
+func effectiveHealth(s State) Health {
+	if s.Health != "" {
+		return s.Health
+	}
+	if s.Ok {
+		return Healthy
+	}
+	return Unhealthy
+}
+
+// withDefaultHealth fills in the health of states received from older detective instances, which only report whether they are Ok
+func (s State) withDefaultHealth() State {
+	ns := s
+	ns.Health = effectiveHealth(s)
+	if len(s.Dependencies) > 0 {
+		ns.Dependencies = make([]State, len(s.Dependencies))
+		for i := range s.Dependencies {
+			ns.Dependencies[i] = s.Dependencies[i].withDefaultHealth()
+		}
+	}
+	return ns
+}
+
+// withMaxDepth removes the dependencies of the state that are nested more than depth levels below it
+func (s State) withMaxDepth(depth int) State {
+	ns := s
+	if depth <= 0 {
+		ns.Dependencies = nil
+		return ns
+	}
+	if len(s.Dependencies) > 0 {
+		ns.Dependencies = make([]State, len(s.Dependencies))
+		for i := range s.Dependencies {
+			ns.Dependencies[i] = s.Dependencies[i].withMaxDepth(depth - 1)
 		}
 	}
-	return true
+	return ns
+}
+
+// summary returns the aggregated state, without its dependencies, details and build information
+func (s State) summary() State {
+	ns := s
+	ns.Dependencies = nil
+	ns.Details = nil
+	ns.Build = nil
+	return ns
+}
+
+// withoutErrors removes error details from the state, and all of its dependencies
+func (s State) withoutErrors() State {
+	ns := s
+	ns.Error = ""
+	switch effectiveHealth(s) {
+	case Unhealthy:
+		ns.Status = "Error"
+	case Degraded:
+		ns.Status = "Degraded"
+	}
+	if len(s.Dependencies) > 0 {
+		ns.Dependencies = make([]State, len(s.Dependencies))
+		for i := range s.Dependencies {
+			ns.Dependencies[i] = s.Dependencies[i].withoutErrors()
+		}
+	}
+	return ns
 }
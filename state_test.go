@@ -1,10 +1,12 @@
 package detective
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestDependentState(t *testing.T) {
@@ -36,6 +38,7 @@ func TestDependentState(t *testing.T) {
 				Name:   "sample",
 				Status: "Ok",
 				Ok:     true,
+				Health: Healthy,
 				Dependencies: []State{
 					State{
 						Name: "state1",
@@ -66,7 +69,9 @@ func TestDependentState(t *testing.T) {
 			want: State{
 				Name:   "sample",
 				Status: "Error: dependency failure",
+				Error:  "dependency failure",
 				Ok:     false,
+				Health: Unhealthy,
 				Dependencies: []State{
 					State{
 						Name: "state1",
@@ -79,6 +84,68 @@ func TestDependentState(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "returns degraded state if only non-critical dependencies are unsuccessful",
+			args: args{
+				name: "sample",
+				dependencies: []State{
+					State{
+						Name: "state1",
+						Ok:   true,
+					},
+					State{
+						Name:        "state2",
+						Ok:          false,
+						NonCritical: true,
+					},
+				},
+			},
+			want: State{
+				Name:   "sample",
+				Status: "Degraded: degraded dependency",
+				Error:  "degraded dependency",
+				Ok:     true,
+				Health: Degraded,
+				Dependencies: []State{
+					State{
+						Name: "state1",
+						Ok:   true,
+					},
+					State{
+						Name:        "state2",
+						Ok:          false,
+						NonCritical: true,
+					},
+				},
+			},
+		},
+		{
+			name: "returns degraded state if some dependencies are degraded",
+			args: args{
+				name: "sample",
+				dependencies: []State{
+					State{
+						Name:   "state1",
+						Ok:     true,
+						Health: Degraded,
+					},
+				},
+			},
+			want: State{
+				Name:   "sample",
+				Status: "Degraded: degraded dependency",
+				Error:  "degraded dependency",
+				Ok:     true,
+				Health: Degraded,
+				Dependencies: []State{
+					State{
+						Name:   "state1",
+						Ok:     true,
+						Health: Degraded,
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -99,3 +166,35 @@ func assertStatesEqual(t *testing.T, s1, s2 State) {
 		assertStatesEqual(t, s1.Dependencies[i], s2.Dependencies[i])
 	}
 }
+
+func TestStateWithLatency(t *testing.T) {
+	s := State{Name: "sample"}.withLatency(1500 * time.Microsecond)
+	assert.Equal(t, 1500*time.Microsecond, s.Latency)
+	assert.Equal(t, 1.5, s.LatencyMs)
+}
+
+func TestStateWithoutErrors(t *testing.T) {
+	s := State{Name: "sample"}.withDependencies([]State{
+		State{Name: "state1"}.withError(errors.New("connection refused at 10.0.0.1")),
+		State{Name: "state2"}.withOk(),
+	})
+	require.Equal(t, "dependency failure", s.Error)
+	require.Equal(t, "connection refused at 10.0.0.1", s.Dependencies[0].Error)
+
+	redacted := s.withoutErrors()
+	assert.Equal(t, "", redacted.Error)
+	assert.Equal(t, "Error", redacted.Status)
+	assert.Equal(t, "", redacted.Dependencies[0].Error)
+	assert.Equal(t, "Error", redacted.Dependencies[0].Status)
+	assert.Equal(t, "Ok", redacted.Dependencies[1].Status)
+	assert.Equal(t, "connection refused at 10.0.0.1", s.Dependencies[0].Error, "original state should not be modified")
+}
+
+func TestDisabledStatesAreNotAggregated(t *testing.T) {
+	failed := State{Name: "db"}.withError(errors.New("failed"))
+	disabled := State{Name: "cache"}.withError(errors.New("failed"))
+	disabled.Disabled = true
+	assert.Equal(t, Unhealthy, State{}.withDependencies([]State{failed, disabled}).Health)
+	assert.Equal(t, Healthy, State{}.withDependencies([]State{disabled}).Health)
+	assert.Equal(t, Healthy, State{}.withAggregatedDependencies([]State{disabled}, Quorum(1)).Health)
+}
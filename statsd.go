@@ -0,0 +1,105 @@
+package detective
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+)
+
+// maxStatsDPacketSize keeps packets below the usual MTU, so that they are not fragmented
+const maxStatsDPacketSize = 1432
+
+// StatsDConfig configures the emitter registered with WithStatsD
+type StatsDConfig struct {
+	// Address is the UDP address of the StatsD server (for example, "127.0.0.1:8125")
+	Address string
+	// Prefix is prepended to the name of every metric (for example, "myapp.")
+	Prefix string
+	// Tags emits the names of the instance and of the dependencies as DogStatsD tags. Otherwise, they are part of the metric names, as plain StatsD does not support tags.
+	Tags bool
+}
+
+type statsdEmitter struct {
+	config StatsDConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+var statsdNameReplacer = strings.NewReplacer(" ", "_", ".", "_", ":", "_", "|", "_", "@", "_", "#", "_", ",", "_", "\n", "_")
+
+// lines returns the metrics describing the state: whether the instance and each of its dependencies are up, and the duration of each check in milliseconds
+func (e *statsdEmitter) lines(s State) []string {
+	name := statsdNameReplacer.Replace(s.Name)
+	metric := func(metric, dependency, value, kind string) string {
+		if e.config.Tags {
+			tags := "detective:" + name
+			if dependency != "" {
+				tags += ",dependency:" + dependency
+			}
+			return e.config.Prefix + metric + ":" + value + "|" + kind + "|#" + tags
+		}
+		if dependency != "" {
+			return e.config.Prefix + name + "." + dependency + "." + metric + ":" + value + "|" + kind
+		}
+		return e.config.Prefix + name + "." + metric + ":" + value + "|" + kind
+	}
+	lines := []string{metric("up", "", formatFloat(boolToFloat(s.Ok)), "g")}
+	for _, dep := range s.Dependencies {
+		depName := statsdNameReplacer.Replace(dep.Name)
+		lines = append(lines,
+			metric("dependency.up", depName, formatFloat(boolToFloat(dep.Ok)), "g"),
+			metric("check.duration", depName, formatFloat(dep.LatencyMs), "ms"),
+		)
+	}
+	return lines
+}
+
+// emit sends the metrics of the state in as few packets as possible
+func (e *statsdEmitter) emit(s State) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn == nil {
+		conn, err := net.Dial("udp", e.config.Address)
+		if err != nil {
+			return err
+		}
+		e.conn = conn
+	}
+	var packet bytes.Buffer
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := e.conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+	for _, line := range e.lines(s) {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxStatsDPacketSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	return flush()
+}
+
+// WithStatsD sends the status of the instance and of its dependencies, and the duration of each check to a StatsD or DogStatsD server, after every check of all dependencies
+func WithStatsD(c StatsDConfig) Option {
+	return func(d *Detective) {
+		e := &statsdEmitter{config: c}
+		d.observers = append(d.observers, func(s State) {
+			if err := e.emit(s); err != nil {
+				d.log(slog.LevelError, fmt.Sprintf("detective %s: failed to send metrics to %s: %v", d.name, c.Address, err), slog.Any("error", err))
+			}
+		})
+	}
+}
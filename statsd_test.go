@@ -0,0 +1,93 @@
+package detective
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readStatsD(t *testing.T, conn net.PacketConn) []string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, maxStatsDPacketSize)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	return strings.Split(string(buf[:n]), "\n")
+}
+
+func TestStatsDTags(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	d := New("sample", WithStatsD(StatsDConfig{Address: conn.LocalAddr().String(), Prefix: "app.", Tags: true}))
+	d.Dependency("db").Detect(func() error {
+		return nil
+	})
+	d.Dependency("cache").Detect(func() error {
+		return errors.New("failed")
+	})
+	d.GetState()
+
+	lines := readStatsD(t, conn)
+	assert.Len(t, lines, 5)
+	assert.Equal(t, "app.up:0|g|#detective:sample", lines[0])
+	assert.Contains(t, lines, "app.dependency.up:1|g|#detective:sample,dependency:db")
+	assert.Contains(t, lines, "app.dependency.up:0|g|#detective:sample,dependency:cache")
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "app.check.duration:") {
+			assert.Regexp(t, `^app\.check\.duration:[0-9.e-]+\|ms\|#detective:sample,dependency:(db|cache)$`, line)
+		}
+	}
+}
+
+func TestStatsDPlain(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	d := New("my app", WithStatsD(StatsDConfig{Address: conn.LocalAddr().String()}))
+	d.Dependency("db.primary").Detect(func() error {
+		return nil
+	})
+	d.GetState()
+
+	lines := readStatsD(t, conn)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "my_app.up:1|g", lines[0])
+	assert.Equal(t, "my_app.db_primary.dependency.up:1|g", lines[1])
+	assert.Regexp(t, `^my_app\.db_primary\.check\.duration:[0-9.e-]+\|ms$`, lines[2])
+}
+
+func TestStatsDPacketSize(t *testing.T) {
+	e := &statsdEmitter{config: StatsDConfig{Tags: true}}
+	s := State{Name: "sample"}
+	for i := 0; i < 100; i++ {
+		s.Dependencies = append(s.Dependencies, State{Name: strings.Repeat("d", 20) + string(rune('a'+i%26))})
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	e.config.Address = conn.LocalAddr().String()
+	require.NoError(t, e.emit(s))
+
+	total := 0
+	for total < len(e.lines(s)) {
+		total += len(readStatsD(t, conn))
+	}
+	assert.Equal(t, len(e.lines(s)), total)
+}
+
+func TestStatsDInvalidAddress(t *testing.T) {
+	logger := &testLogger{}
+	d := New("sample", WithLogger(logger), WithStatsD(StatsDConfig{Address: "invalid address"}))
+	d.Dependency("db").Detect(func() error {
+		return nil
+	})
+	s := d.GetState()
+	assert.True(t, s.Ok)
+	assert.Contains(t, strings.Join(logger.snapshot(), "\n"), "failed to send metrics")
+}
@@ -0,0 +1,300 @@
+package detective
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// broadcaster delivers the latest state of an instance to its subscribers. Slow subscribers only get the latest state, rather than blocking the checks.
+type broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan State]struct{}
+}
+
+// subscribe returns a channel receiving the states published from now on, and a function to cancel the subscription
+func (b *broadcaster) subscribe() (<-chan State, func()) {
+	ch := make(chan State, 1)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers == nil {
+		b.subscribers = map[chan State]struct{}{}
+	}
+	b.subscribers[ch] = struct{}{}
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, ch)
+	}
+}
+
+func (b *broadcaster) publish(s State) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		// A state that has not been received yet is replaced by the latest one
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- s
+	}
+}
+
+// streamKeepAlive is the interval at which a comment is sent to idle streams, so that proxies do not close them
+const streamKeepAlive = 30 * time.Second
+
+// StreamHandler returns an HTTP handler that streams the state of the Detective instance as Server-Sent Events, so that dashboards can update live without polling. The current state is sent as soon as a client connects, followed by the state collected by every check of all dependencies, like the ones made by StartPeriodic. Each event is named "state", and its data is the state encoded as JSON:
+//
+//	const events = new EventSource("/health/stream");
+//	events.addEventListener("state", e => render(JSON.parse(e.data)));
+func (d *Detective) StreamHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.handleCORS(w, r) {
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		send := func(s State) bool {
+			if d.redactErrors {
+				s = s.withoutErrors()
+			}
+			data, err := json.Marshal(s)
+			if err != nil {
+				d.log(slog.LevelError, fmt.Sprintf("detective %s: failed to encode state: %v", d.name, err), slog.Any("error", err))
+				return false
+			}
+			if _, err := fmt.Fprintf(w, "event: state\ndata: %s\n\n", data); err != nil {
+				return false
+			}
+			flusher.Flush()
+			return true
+		}
+		// Without periodic checks, reading the current state checks all dependencies, and the subscription starts afterwards, so that the same state is not sent twice
+		if !send(d.sharedState(r.Context(), nil)) {
+			return
+		}
+		states, unsubscribe := d.stream.subscribe()
+		defer unsubscribe()
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case s := <-states:
+				if !send(s) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// A streamMessage is sent to the clients of the WebSocketHandler
+type streamMessage struct {
+	// Type is "state" for a full state, or "delta" for the transitions since the previous message
+	Type    string        `json:"type"`
+	State   *State        `json:"state,omitempty"`
+	Changes []stateChange `json:"changes,omitempty"`
+}
+
+// A stateChange describes the transition of the instance, or of one of its nested dependencies, identified by the names of its ancestors and its own name
+type stateChange struct {
+	Path []string `json:"path"`
+	// Old is the previous health, which is empty if the entity was added
+	Old Health `json:"old,omitempty"`
+	// New is the state of the entity, without its dependencies, and is nil if the entity was removed
+	New *State `json:"new,omitempty"`
+}
+
+// stateChanges returns the transitions between two states of the same entity, and of their dependencies
+func stateChanges(path []string, old State, s State) []stateChange {
+	var changes []stateChange
+	if effectiveHealth(old) != effectiveHealth(s) {
+		changes = append(changes, stateChange{Path: path, Old: effectiveHealth(old), New: withoutDependencies(s)})
+	}
+	previous := make(map[string]State, len(old.Dependencies))
+	for _, dep := range old.Dependencies {
+		previous[dep.Name] = dep
+	}
+	for _, dep := range s.Dependencies {
+		depPath := append(path[:len(path):len(path)], dep.Name)
+		if oldDep, ok := previous[dep.Name]; ok {
+			changes = append(changes, stateChanges(depPath, oldDep, dep)...)
+			delete(previous, dep.Name)
+		} else {
+			changes = append(changes, stateChange{Path: depPath, New: withoutDependencies(dep)})
+		}
+	}
+	for _, dep := range old.Dependencies {
+		if _, removed := previous[dep.Name]; removed {
+			changes = append(changes, stateChange{Path: append(path[:len(path):len(path)], dep.Name), Old: effectiveHealth(dep)})
+		}
+	}
+	return changes
+}
+
+func withoutDependencies(s State) *State {
+	s.Dependencies = nil
+	return &s
+}
+
+// WebSocketHandler returns an HTTP handler that streams the state of the Detective instance over a WebSocket connection, using a JSON protocol. The server sends messages like:
+//
+//	{"type": "state", "state": {...}}                                                          the full state, sent when the client connects
+//	{"type": "delta", "changes": [{"path": ["storage", "s3"], "old": "healthy", "new": {...}}]} the transitions found by a check of all dependencies
+//
+// where the path of a change contains the names of the ancestors of the entity below the instance, and its own name. The state of the instance itself has an empty path. The client can send a {"type": "snapshot"} message at any time to get the full state again.
+func (d *Detective) WebSocketHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+			w.Header().Set("Upgrade", "websocket")
+			http.Error(w, "a websocket handshake is required", http.StatusUpgradeRequired)
+			return
+		}
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if r.Method != http.MethodGet || key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+			http.Error(w, "invalid websocket handshake", http.StatusBadRequest)
+			return
+		}
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "websockets are not supported", http.StatusInternalServerError)
+			return
+		}
+		s := d.sharedState(r.Context(), nil)
+		conn, rw, err := hijacker.Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		accept := sha1.Sum([]byte(key + websocketGUID))
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(accept[:]))
+		if err := rw.Flush(); err != nil {
+			return
+		}
+		send := func(opcode byte, payload []byte) bool {
+			conn.SetWriteDeadline(time.Now().Add(streamKeepAlive))
+			return writeWebSocketFrame(conn, opcode, payload, false) == nil
+		}
+		sendMessage := func(m streamMessage) bool {
+			if d.redactErrors {
+				if m.State != nil {
+					redacted := m.State.withoutErrors()
+					m.State = &redacted
+				}
+				for i, change := range m.Changes {
+					if change.New != nil {
+						m.Changes[i].New = withoutDependencies(change.New.withoutErrors())
+					}
+				}
+			}
+			data, err := json.Marshal(m)
+			if err != nil {
+				d.log(slog.LevelError, fmt.Sprintf("detective %s: failed to encode state: %v", d.name, err), slog.Any("error", err))
+				return false
+			}
+			return send(websocketText, data)
+		}
+		if !sendMessage(streamMessage{Type: "state", State: &s}) {
+			return
+		}
+		states, unsubscribe := d.stream.subscribe()
+		defer unsubscribe()
+
+		// Frames are read in the background, and all frames are written by this goroutine
+		type frame struct {
+			opcode  byte
+			payload []byte
+		}
+		frames := make(chan frame)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			defer close(frames)
+			for {
+				opcode, payload, err := readWebSocketFrame(rw.Reader)
+				if err != nil {
+					return
+				}
+				select {
+				case frames <- frame{opcode, payload}:
+				case <-done:
+					return
+				}
+				if opcode == websocketClose {
+					return
+				}
+			}
+		}()
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case f, ok := <-frames:
+				if !ok {
+					return
+				}
+				switch f.opcode {
+				case websocketClose:
+					send(websocketClose, f.payload)
+					return
+				case websocketPing:
+					if !send(websocketPong, f.payload) {
+						return
+					}
+				case websocketText:
+					var request struct {
+						Type string `json:"type"`
+					}
+					if json.Unmarshal(f.payload, &request) == nil && request.Type == "snapshot" {
+						if !sendMessage(streamMessage{Type: "state", State: &s}) {
+							return
+						}
+					}
+				}
+			case next := <-states:
+				changes := stateChanges([]string{}, s, next)
+				s = next
+				if len(changes) > 0 && !sendMessage(streamMessage{Type: "delta", Changes: changes}) {
+					return
+				}
+			case <-keepAlive.C:
+				if !send(websocketPing, nil) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// headerContainsToken returns true if the comma separated values of the header contain the token, ignoring case
+func headerContainsToken(h http.Header, key, token string) bool {
+	for _, value := range h.Values(key) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package detective
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStreamHandler(t *testing.T) {
+	var failing atomic.Bool
+	d := New("sample")
+	d.Dependency("db").Detect(func() error {
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	s := httptest.NewServer(d.StreamHandler())
+	defer s.Close()
+
+	res, err := http.Get(s.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	events := bufio.NewReader(res.Body)
+	next := func() State {
+		var state State
+		for {
+			line, err := events.ReadString('\n')
+			require.NoError(t, err)
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				require.NoError(t, json.Unmarshal([]byte(data), &state))
+				return state
+			}
+		}
+	}
+
+	assert.Equal(t, Healthy, next().Health)
+	failing.Store(true)
+	require.NoError(t, d.StartPeriodic(10*time.Millisecond))
+	defer d.Stop()
+	for {
+		if state := next(); state.Health == Unhealthy {
+			assert.Equal(t, "connection refused", state.Dependencies[0].Error)
+			break
+		}
+	}
+}
+
+func TestStateChanges(t *testing.T) {
+	old := State{Name: "sample"}.withDependencies([]State{
+		State{Name: "db"}.withOk(),
+		State{Name: "storage"}.withDependencies([]State{State{Name: "s3"}.withOk()}),
+		State{Name: "legacy"}.withOk(),
+	})
+	s := State{Name: "sample"}.withDependencies([]State{
+		State{Name: "db"}.withOk(),
+		State{Name: "storage"}.withDependencies([]State{State{Name: "s3"}.withError(errors.New("access denied"))}),
+		State{Name: "cache"}.withOk(),
+	})
+	changes := stateChanges([]string{}, old, s)
+	require.Len(t, changes, 5)
+	assert.Equal(t, []string{}, changes[0].Path)
+	assert.Equal(t, Healthy, changes[0].Old)
+	assert.Equal(t, Unhealthy, changes[0].New.Health)
+	assert.Empty(t, changes[0].New.Dependencies)
+	assert.Equal(t, []string{"storage"}, changes[1].Path)
+	assert.Equal(t, []string{"storage", "s3"}, changes[2].Path)
+	assert.Equal(t, "access denied", changes[2].New.Error)
+	assert.Equal(t, stateChange{Path: []string{"cache"}, New: &State{Name: "cache", Ok: true, Status: "Ok", Health: Healthy}}, changes[3])
+	assert.Equal(t, stateChange{Path: []string{"legacy"}, Old: Healthy}, changes[4])
+	assert.Empty(t, stateChanges([]string{}, s, s))
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	var failing atomic.Bool
+	d := New("sample")
+	d.Dependency("db").Detect(func() error {
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	s := httptest.NewServer(d.WebSocketHandler())
+	defer s.Close()
+
+	res, err := http.Get(s.URL)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUpgradeRequired, res.StatusCode)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(s.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: detective\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	r := bufio.NewReader(conn)
+	res, err = http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", res.Header.Get("Sec-WebSocket-Accept"))
+	next := func() streamMessage {
+		opcode, payload, err := readWebSocketFrame(r)
+		require.NoError(t, err)
+		require.Equal(t, byte(websocketText), opcode)
+		var m streamMessage
+		require.NoError(t, json.Unmarshal(payload, &m))
+		return m
+	}
+
+	m := next()
+	assert.Equal(t, "state", m.Type)
+	assert.Equal(t, Healthy, m.State.Health)
+
+	failing.Store(true)
+	require.NoError(t, d.StartPeriodic(10*time.Millisecond))
+	defer d.Stop()
+	m = next()
+	assert.Equal(t, "delta", m.Type)
+	require.Len(t, m.Changes, 2)
+	assert.Equal(t, []string{}, m.Changes[0].Path)
+	assert.Equal(t, Unhealthy, m.Changes[0].New.Health)
+	assert.Equal(t, []string{"db"}, m.Changes[1].Path)
+	assert.Equal(t, Healthy, m.Changes[1].Old)
+	assert.Equal(t, "connection refused", m.Changes[1].New.Error)
+
+	require.NoError(t, writeWebSocketFrame(conn, websocketText, []byte(`{"type": "snapshot"}`), true))
+	m = next()
+	assert.Equal(t, "state", m.Type)
+	assert.Equal(t, Unhealthy, m.State.Health)
+
+	require.NoError(t, writeWebSocketFrame(conn, websocketClose, []byte{0x03, 0xe8}, true))
+	opcode, payload, err := readWebSocketFrame(r)
+	require.NoError(t, err)
+	assert.Equal(t, byte(websocketClose), opcode)
+	assert.Equal(t, []byte{0x03, 0xe8}, payload)
+}
@@ -0,0 +1,20 @@
+package detective
+
+import (
+	"context"
+	"net"
+)
+
+// DetectTCP registers a detector function that opens a TCP connection to the provided address (for example, "smtp.example.com:25"). The dependency is considered healthy if the connection is established. The dial is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectTCP(addr string) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
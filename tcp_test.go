@@ -0,0 +1,25 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestDetectTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+
+	d := newDependency("tcp")
+	d.DetectTCP(addr)
+	assertStatesEqual(t, State{Name: "tcp", Ok: true, Status: "Ok"}, d.getState(context.Background()))
+
+	// Once the listener is closed, the port is no longer reachable
+	require.NoError(t, l.Close())
+	s := d.getState(context.Background())
+	assert.False(t, s.Ok)
+	assert.Equal(t, Unhealthy, s.Health)
+}
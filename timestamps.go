@@ -0,0 +1,29 @@
+package detective
+
+import (
+	"sync"
+	"time"
+)
+
+// successTracker remembers when an entity was last checked successfully
+type successTracker struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// track sets the time at which the state was checked, and the time of the last check in which the entity was working
+func (t *successTracker) track(s State, at time.Time) State {
+	at = at.UTC()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s.Ok {
+		t.last = at
+	}
+	ns := s
+	ns.CheckedAt = &at
+	if !t.last.IsZero() {
+		last := t.last
+		ns.LastSuccessAt = &last
+	}
+	return ns
+}
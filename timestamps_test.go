@@ -0,0 +1,69 @@
+package detective
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDependencyTimestamps(t *testing.T) {
+	failing := false
+	d := newDependency("sample")
+	d.Detect(func() error {
+		if failing {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	before := time.Now()
+	s := d.getState(context.Background())
+	require.NotNil(t, s.CheckedAt)
+	require.NotNil(t, s.LastSuccessAt)
+	assert.False(t, s.CheckedAt.Before(before.Add(-time.Millisecond)))
+	assert.Equal(t, *s.CheckedAt, *s.LastSuccessAt)
+	lastSuccess := *s.LastSuccessAt
+
+	failing = true
+	s = d.getState(context.Background())
+	require.NotNil(t, s.LastSuccessAt)
+	assert.Equal(t, lastSuccess, *s.LastSuccessAt, "the last success should not change while the dependency fails")
+	assert.False(t, s.CheckedAt.Before(lastSuccess))
+}
+
+func TestNeverSuccessfulTimestamps(t *testing.T) {
+	d := newDependency("sample")
+	d.Detect(func() error { return errors.New("failed") })
+	s := d.getState(context.Background())
+	assert.NotNil(t, s.CheckedAt)
+	assert.Nil(t, s.LastSuccessAt)
+}
+
+func TestDetectiveTimestamps(t *testing.T) {
+	remote := New("remote")
+	server := httptest.NewServer(remote)
+	defer server.Close()
+
+	d := New("sample")
+	d.Dependency("db")
+	require.NoError(t, d.Endpoint(server.URL))
+	s := d.GetState()
+	require.NotNil(t, s.CheckedAt)
+	require.NotNil(t, s.LastSuccessAt)
+	for _, dep := range s.Dependencies {
+		assert.NotNil(t, dep.CheckedAt, dep.Name)
+		assert.NotNil(t, dep.LastSuccessAt, dep.Name)
+	}
+
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &raw))
+	assert.Contains(t, raw, "checked_at")
+	assert.Contains(t, raw, "last_success_at")
+}
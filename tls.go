@@ -0,0 +1,77 @@
+package detective
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// defaultExpiryWindow is the expiry window used by DetectTLSCertificate, if none is configured
+const defaultExpiryWindow = 14 * 24 * time.Hour
+
+// TLSCertificateConfig configures the detector registered with DetectTLSCertificate
+type TLSCertificateConfig struct {
+	// ExpiryWindow is the duration before the expiry of a certificate, from which the dependency is reported as failing. If zero, a window of 14 days is used.
+	ExpiryWindow time.Duration
+	// Degrade reports the dependency as degraded instead of unhealthy when a certificate expires within the window. Expired, and otherwise invalid certificates are always reported as unhealthy.
+	Degrade bool
+	// Config is used for the TLS handshake. If the ServerName is not set, the host of the address is used.
+	Config *tls.Config
+}
+
+// DetectTLSCertificate registers a detector function that connects to the provided address (for example, "example.com:443"), verifies the certificate chain it serves, and fails when any certificate in the chain expires within the expiry window of the configuration
+func (d *Dependency) DetectTLSCertificate(addr string, c TLSCertificateConfig) {
+	window := c.ExpiryWindow
+	if window <= 0 {
+		window = defaultExpiryWindow
+	}
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		config := &tls.Config{}
+		if c.Config != nil {
+			config = c.Config.Clone()
+		}
+		if config.ServerName == "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return err
+			}
+			config.ServerName = host
+		}
+		dialer := tls.Dialer{Config: config}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return checkCertificateExpiry(conn.(*tls.Conn).ConnectionState(), window, c.Degrade, time.Now())
+	})
+}
+
+func checkCertificateExpiry(cs tls.ConnectionState, window time.Duration, degrade bool, now time.Time) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no certificates were served")
+	}
+	var expiring error
+	for _, cert := range cs.PeerCertificates {
+		name := cert.Subject.CommonName
+		if name == "" {
+			name = cert.SerialNumber.String()
+		}
+		remaining := cert.NotAfter.Sub(now)
+		if remaining <= 0 {
+			return fmt.Errorf("certificate %s expired on %s", name, cert.NotAfter.Format(time.RFC3339))
+		}
+		if remaining < window && expiring == nil {
+			expiring = fmt.Errorf("certificate %s expires on %s", name, cert.NotAfter.Format(time.RFC3339))
+		}
+	}
+	if expiring != nil && degrade {
+		return Degrade(expiring)
+	}
+	return expiring
+}
@@ -0,0 +1,66 @@
+package detective
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectTLSCertificate(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "https://")
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate())
+	config := &tls.Config{RootCAs: roots}
+	// The certificate of the test server is valid for several decades
+	longWindow := 100 * 365 * 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		config   TLSCertificateConfig
+		expected Health
+	}{
+		{"valid certificate", TLSCertificateConfig{Config: config}, Healthy},
+		{"expiring certificate", TLSCertificateConfig{Config: config, ExpiryWindow: longWindow}, Unhealthy},
+		{"expiring certificate with degrade", TLSCertificateConfig{Config: config, ExpiryWindow: longWindow, Degrade: true}, Degraded},
+		{"untrusted certificate", TLSCertificateConfig{Degrade: true}, Unhealthy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("tls")
+			d.DetectTLSCertificate(addr, tt.config)
+			st := d.getState(context.Background())
+			assert.Equal(t, tt.expected, st.Health, st.Error)
+		})
+	}
+}
+
+func TestCheckCertificateExpiry(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := func(name string, notAfter time.Time) *x509.Certificate {
+		return &x509.Certificate{Subject: pkix.Name{CommonName: name}, SerialNumber: big.NewInt(1), NotAfter: notAfter}
+	}
+	leaf := cert("example.com", now.Add(300*24*time.Hour))
+	intermediate := cert("", now.Add(10*24*time.Hour))
+	expired := cert("expired.example.com", now.Add(-time.Hour))
+
+	err := checkCertificateExpiry(tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, intermediate}}, defaultExpiryWindow, true, now)
+	assert.EqualError(t, err, "certificate 1 expires on 2020-01-11T00:00:00Z")
+	assert.True(t, isDegraded(err))
+
+	err = checkCertificateExpiry(tls.ConnectionState{PeerCertificates: []*x509.Certificate{intermediate, expired}}, defaultExpiryWindow, true, now)
+	assert.EqualError(t, err, "certificate expired.example.com expired on 2019-12-31T23:00:00Z")
+	assert.False(t, isDegraded(err))
+
+	assert.NoError(t, checkCertificateExpiry(tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}, defaultExpiryWindow, false, now))
+	assert.EqualError(t, checkCertificateExpiry(tls.ConnectionState{}, defaultExpiryWindow, false, now), "no certificates were served")
+}
@@ -0,0 +1,52 @@
+package detective
+
+import (
+	"context"
+	"net/http"
+)
+
+// A Tracer records spans around health checks, so that slow checks can be diagnosed with a tracing backend. The detectiveotel package provides a Tracer for OpenTelemetry.
+type Tracer interface {
+	// Start starts a span with the provided name and attributes as a child of the span in ctx, and returns a context containing the new span
+	Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span)
+	// Inject adds the trace context of ctx to the header of a request to another detective instance, or to a plain HTTP endpoint
+	Inject(ctx context.Context, header http.Header)
+	// Extract returns a context containing the trace context of the header of a request to the handler, so that its checks continue the trace of the caller
+	Extract(ctx context.Context, header http.Header) context.Context
+}
+
+// A Span is started by a Tracer, and is ended with the state of the check it covers
+type Span interface {
+	End(s State)
+}
+
+type tracerKey struct{}
+
+// startSpan starts a span if a tracer is configured. The tracer is added to the context, so that endpoint requests made within the span can propagate the trace context.
+func (d *Detective) startSpan(ctx context.Context, name string, attributes map[string]string) (context.Context, func(State)) {
+	if d.tracer == nil {
+		return ctx, func(State) {}
+	}
+	ctx, span := d.tracer.Start(context.WithValue(ctx, tracerKey{}, d.tracer), name, attributes)
+	return ctx, span.End
+}
+
+// traced wraps a check with a span
+func (d *Detective) traced(name string, attributes map[string]string, check func(context.Context) State) func(context.Context) State {
+	if d.tracer == nil {
+		return check
+	}
+	return func(ctx context.Context) State {
+		ctx, end := d.startSpan(ctx, name, attributes)
+		s := check(ctx)
+		end(s)
+		return s
+	}
+}
+
+// injectTraceContext adds the trace context to the header of an endpoint request, if the check is traced
+func injectTraceContext(ctx context.Context, header http.Header) {
+	if t, ok := ctx.Value(tracerKey{}).(Tracer); ok {
+		t.Inject(ctx, header)
+	}
+}
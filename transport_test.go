@@ -0,0 +1,265 @@
+package detective
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	dm "github.com/sohamkamani/detective/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEndpointTLSConfig(t *testing.T) {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	s.StartTLS()
+	defer s.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	trusted := &tls.Config{RootCAs: pool, Certificates: s.TLS.Certificates}
+
+	tests := []struct {
+		name     string
+		detOpts  []Option
+		opts     []EndpointOption
+		expected Health
+		err      string
+	}{
+		{name: "untrusted", expected: Unhealthy, err: "certificate"},
+		{name: "endpoint config", opts: []EndpointOption{TLSConfig(trusted)}, expected: Healthy},
+		{name: "instance config", detOpts: []Option{WithTLSConfig(trusted)}, expected: Healthy},
+		{name: "endpoint config takes precedence", detOpts: []Option{WithTLSConfig(trusted)}, opts: []EndpointOption{TLSConfig(&tls.Config{RootCAs: pool})}, expected: Unhealthy, err: "certificate"},
+		{name: "custom transport", detOpts: []Option{WithHTTPClient(&http.Client{Transport: &http.Transport{}})}, opts: []EndpointOption{TLSConfig(trusted)}, expected: Healthy},
+		{name: "not an http client", detOpts: []Option{WithHTTPClient(&dm.MockClient{})}, opts: []EndpointOption{TLSConfig(trusted)}, expected: Unhealthy, err: "the HTTP client has to be an *http.Client to apply a TLS configuration"},
+		{name: "not an http transport", detOpts: []Option{WithHTTPClient(&http.Client{Transport: roundTripperFunc(nil)})}, opts: []EndpointOption{TLSConfig(trusted)}, expected: Unhealthy, err: "the HTTP client has to use an *http.Transport to apply a TLS configuration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New("sample", tt.detOpts...)
+			require.NoError(t, d.EndpointWithOptions(s.URL, append(tt.opts, ExpectStatus(http.StatusOK))...))
+			dep := d.GetState().Dependencies[0]
+			assert.Equal(t, tt.expected, dep.Health)
+			assert.Contains(t, dep.Error, tt.err)
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestSplitUnixURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		socket string
+		target string
+		err    string
+	}{
+		{url: "unix:///var/run/app.sock/health", socket: "/var/run/app.sock", target: "http://localhost/health"},
+		{url: "unix:///var/run/app.sock", socket: "/var/run/app.sock", target: "http://localhost/"},
+		{url: "unix:///var/run/app.sock/health/live?verbose=1", socket: "/var/run/app.sock", target: "http://localhost/health/live?verbose=1"},
+		{url: "unix://./app.sock/health", socket: "./app.sock", target: "http://localhost/health"},
+		{url: "unix:///var/run/app.socket/health", err: "the path of the socket in unix:///var/run/app.socket/health should end with .sock"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			socket, target, err := splitUnixURL(u)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.socket, socket)
+			assert.Equal(t, tt.target, target.String())
+		})
+	}
+}
+
+func TestUnixSocketEndpoint(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	other := New("other")
+	other.Dependency("db").Detect(func() error {
+		return nil
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/health", other)
+	s := &http.Server{Handler: mux}
+	go s.Serve(l)
+	defer s.Close()
+
+	d := New("sample")
+	require.NoError(t, d.Endpoint("unix://"+socket+"/health"))
+	state := d.GetState()
+	require.Len(t, state.Dependencies, 1)
+	assert.Equal(t, "other", state.Dependencies[0].Name)
+	assert.True(t, state.Ok)
+
+	assert.True(t, d.DisableEndpoint("unix://"+socket+"/health"))
+	assert.True(t, d.RemoveEndpoint("unix://"+socket+"/health"))
+
+	assert.EqualError(t, d.Endpoint("unix:///var/run/app/health"), "the path of the socket in unix:///var/run/app/health should end with .sock")
+	req, err := http.NewRequest(http.MethodGet, "unix:///var/run/app/health", nil)
+	require.NoError(t, err)
+	d.EndpointReq(req)
+	assert.Equal(t, "the path of the socket in unix:///var/run/app/health should end with .sock", d.GetState().Dependencies[0].Error)
+}
+
+func TestEndpointClient(t *testing.T) {
+	var shared, own int
+	sharedClient := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		shared++
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})}
+	ownClient := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		own++
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+	})}
+
+	d := New("sample", WithHTTPClient(sharedClient))
+	require.NoError(t, d.EndpointWithOptions("http://shared.example.com/health", ExpectStatus(http.StatusOK)))
+	require.NoError(t, d.EndpointWithOptions("http://own.example.com/health", ExpectStatus(http.StatusNoContent), Client(ownClient)))
+	assert.True(t, d.GetState().Ok)
+	assert.Equal(t, 1, shared)
+	assert.Equal(t, 1, own)
+
+	// Transport options are applied to a copy of the endpoint client, which has to be an *http.Client
+	require.NoError(t, d.EndpointWithOptions("https://tls.example.com/health", Client(ownClient), TLSConfig(&tls.Config{})))
+	assert.Equal(t, "the HTTP client has to use an *http.Transport to apply a TLS configuration", d.GetState().Dependencies[2].Error)
+}
+
+// serveSOCKS5 runs a SOCKS5 proxy without authentication, which counts the connections it proxies
+func serveSOCKS5(t *testing.T, connections *atomic.Int32) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		l.Close()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				// The greeting lists the authentication methods of the client, and the proxy selects "no authentication"
+				greeting := make([]byte, 2)
+				if _, err := io.ReadFull(r, greeting); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(r, make([]byte, greeting[1])); err != nil {
+					return
+				}
+				conn.Write([]byte{5, 0})
+				// CONNECT requests with an IPv4 address, or a domain name
+				req := make([]byte, 4)
+				if _, err := io.ReadFull(r, req); err != nil {
+					return
+				}
+				var host string
+				switch req[3] {
+				case 1:
+					ip := make([]byte, 4)
+					io.ReadFull(r, ip)
+					host = net.IP(ip).String()
+				case 3:
+					n, _ := r.ReadByte()
+					name := make([]byte, n)
+					io.ReadFull(r, name)
+					host = string(name)
+				default:
+					return
+				}
+				port := make([]byte, 2)
+				io.ReadFull(r, port)
+				target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1]))))
+				if err != nil {
+					conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer target.Close()
+				connections.Add(1)
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				go io.Copy(target, r)
+				io.Copy(conn, target)
+			}(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestEndpointProxy(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"remote","status":"Ok","active":true}`))
+	}))
+	defer remote.Close()
+	var proxied []string
+	httpProxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Proxies receive the absolute URL of the request
+		proxied = append(proxied, r.URL.String())
+		if r.URL.Host != "remote.internal" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"name":"internal","status":"Ok","active":true}`))
+	}))
+	defer httpProxy.Close()
+	httpProxyURL, _ := url.Parse(httpProxy.URL)
+	var socksConnections atomic.Int32
+	socksURL, _ := url.Parse("socks5://" + serveSOCKS5(t, &socksConnections))
+
+	d := New("sample", WithProxy(httpProxyURL))
+	require.NoError(t, d.Endpoint("http://remote.internal/health"))
+	d.Group("payments").Endpoint("http://remote.internal/payments/health")
+	require.NoError(t, d.EndpointWithOptions(remote.URL, Proxy(socksURL)))
+	s := d.GetState()
+	assert.True(t, s.Ok, s.Dependencies)
+	assert.ElementsMatch(t, []string{"http://remote.internal/health", "http://remote.internal/payments/health"}, proxied)
+	assert.Equal(t, int32(1), socksConnections.Load())
+
+	d = New("sample", WithHTTPClient(&http.Client{Transport: roundTripperFunc(nil)}))
+	require.NoError(t, d.EndpointWithOptions(remote.URL, Proxy(socksURL)))
+	assert.Equal(t, "the HTTP client has to use an *http.Transport to use a proxy", d.GetState().Dependencies[0].Error)
+}
+
+func TestEndpointRedirects(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			http.Redirect(w, r, "/sso", http.StatusFound)
+		case "/sso":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		}
+	}))
+	defer s.Close()
+
+	d := New("sample")
+	require.NoError(t, d.EndpointWithOptions(s.URL+"/health", ExpectStatus(http.StatusOK)))
+	require.NoError(t, d.EndpointWithOptions(s.URL+"/health", ExpectStatus(http.StatusOK), NoRedirects()))
+	require.NoError(t, d.EndpointWithOptions(s.URL+"/health", ExpectStatus(http.StatusOK), MaxRedirects(1)))
+	require.NoError(t, d.EndpointWithOptions(s.URL+"/health", ExpectStatus(http.StatusOK), MaxRedirects(2)))
+	deps := d.GetState().Dependencies
+	require.Len(t, deps, 4)
+	assert.True(t, deps[0].Ok)
+	assert.False(t, deps[1].Ok)
+	assert.Contains(t, deps[1].Error, "302")
+	assert.False(t, deps[2].Ok)
+	assert.Contains(t, deps[2].Error, "stopped after 1 redirects")
+	assert.True(t, deps[3].Ok)
+}
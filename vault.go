@@ -0,0 +1,94 @@
+package detective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// VaultConfig configures the detector registered with DetectVault. The health of the dependency in each state of the Vault server can be overridden, and the zero value of each field uses the default.
+type VaultConfig struct {
+	// StandbyHealth is the health of the dependency if the server is unsealed, but is a standby (or performance standby) node. Defaults to Healthy, since standby nodes forward requests to the active node.
+	StandbyHealth Health
+	// SealedHealth is the health of the dependency if the server is sealed. Defaults to Unhealthy.
+	SealedHealth Health
+	// UninitializedHealth is the health of the dependency if the server has not been initialized. Defaults to Unhealthy.
+	UninitializedHealth Health
+	// Client is used to send the request. If it is nil, http.DefaultClient is used.
+	Client Doer
+}
+
+// DetectVault registers a detector function that requests the health of a HashiCorp Vault server from its /v1/sys/health endpoint, at a URL like "https://vault.example.com:8200". The dependency is healthy if the server is active, and its health in the standby, sealed and uninitialized states is configured with VaultConfig.
+// The state of the server ("active", "standby", "performance_standby", "dr_secondary", "sealed" or "uninitialized"), its version and the name of its cluster are reported in the details of the state of the dependency. The request is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectVault(vaultURL string, c VaultConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		base, err := url.Parse(strings.TrimSuffix(vaultURL, "/"))
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest(http.MethodGet, base.String()+"/v1/sys/health", nil)
+		if err != nil {
+			return err
+		}
+		var health struct {
+			Version     string `json:"version"`
+			ClusterName string `json:"cluster_name"`
+		}
+		// The status code of the response encodes the state of the server, and the body is the same for all states
+		code, err := doJSON(c.Client, req.WithContext(ctx), &health)
+		if err != nil {
+			return err
+		}
+		var status string
+		var result error
+		switch code {
+		case http.StatusOK:
+			status = "active"
+		case http.StatusTooManyRequests, 473:
+			status = "standby"
+			if code == 473 {
+				status = "performance_standby"
+			}
+			result = vaultHealth(c.StandbyHealth, Healthy, errors.New("vault server is a "+strings.ReplaceAll(status, "_", " ")+" node"))
+		case 472:
+			status = "dr_secondary"
+			result = errors.New("vault server is a disaster recovery secondary")
+		case http.StatusServiceUnavailable:
+			status = "sealed"
+			result = vaultHealth(c.SealedHealth, Unhealthy, errors.New("vault server is sealed"))
+		case http.StatusNotImplemented:
+			status = "uninitialized"
+			result = vaultHealth(c.UninitializedHealth, Unhealthy, errors.New("vault server is not initialized"))
+		default:
+			return fmt.Errorf("vault health endpoint returned http status: %d", code)
+		}
+		SetDetail(ctx, "vault_status", status)
+		if health.Version != "" {
+			SetDetail(ctx, "version", health.Version)
+		}
+		if health.ClusterName != "" {
+			SetDetail(ctx, "cluster_name", health.ClusterName)
+		}
+		return result
+	})
+}
+
+// vaultHealth returns the error that reports the configured health, or the default health if none is configured
+func vaultHealth(h, def Health, err error) error {
+	if h == "" {
+		h = def
+	}
+	switch h {
+	case Healthy:
+		return nil
+	case Degraded:
+		return Degrade(err)
+	default:
+		return err
+	}
+}
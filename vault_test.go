@@ -0,0 +1,55 @@
+package detective
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectVault(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		c        VaultConfig
+		expected Health
+		err      string
+		status   string
+	}{
+		{name: "active", code: http.StatusOK, expected: Healthy, status: "active"},
+		{name: "standby", code: http.StatusTooManyRequests, expected: Healthy, status: "standby"},
+		{name: "performance standby", code: 473, expected: Healthy, status: "performance_standby"},
+		{name: "degraded standby", code: http.StatusTooManyRequests, c: VaultConfig{StandbyHealth: Degraded}, expected: Degraded, err: "vault server is a standby node", status: "standby"},
+		{name: "unhealthy performance standby", code: 473, c: VaultConfig{StandbyHealth: Unhealthy}, expected: Unhealthy, err: "vault server is a performance standby node", status: "performance_standby"},
+		{name: "disaster recovery secondary", code: 472, expected: Unhealthy, err: "vault server is a disaster recovery secondary", status: "dr_secondary"},
+		{name: "sealed", code: http.StatusServiceUnavailable, expected: Unhealthy, err: "vault server is sealed", status: "sealed"},
+		{name: "degraded sealed", code: http.StatusServiceUnavailable, c: VaultConfig{SealedHealth: Degraded}, expected: Degraded, err: "vault server is sealed", status: "sealed"},
+		{name: "uninitialized", code: http.StatusNotImplemented, expected: Unhealthy, err: "vault server is not initialized", status: "uninitialized"},
+		{name: "healthy uninitialized", code: http.StatusNotImplemented, c: VaultConfig{UninitializedHealth: Healthy}, expected: Healthy, status: "uninitialized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/sys/health", r.URL.Path)
+				w.WriteHeader(tt.code)
+				w.Write([]byte(`{"initialized": true, "sealed": false, "standby": false, "version": "1.15.2", "cluster_name": "vault-cluster"}`))
+			}))
+			defer s.Close()
+			d := newDependency("vault")
+			d.DetectVault(s.URL+"/", tt.c)
+			st := d.getState(context.Background())
+			assert.Equal(t, tt.expected, st.Health)
+			assert.Equal(t, tt.err, st.Error)
+			assert.Equal(t, map[string]interface{}{"vault_status": tt.status, "version": "1.15.2", "cluster_name": "vault-cluster"}, st.Details)
+		})
+	}
+
+	t.Run("unexpected status", func(t *testing.T) {
+		s := httptest.NewServer(http.NotFoundHandler())
+		defer s.Close()
+		d := newDependency("vault")
+		d.DetectVault(s.URL, VaultConfig{})
+		assertStatesEqual(t, State{Name: "vault", Ok: false, Status: "Error: vault health endpoint returned http status: 404"}, d.getState(context.Background()))
+	})
+}
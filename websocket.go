@@ -0,0 +1,237 @@
+package detective
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WebSocketConfig configures the detector registered with DetectWebSocket
+type WebSocketConfig struct {
+	// Header is added to the handshake request, for example to authenticate, or to set the Origin of the request
+	Header http.Header
+	// Ping sends a ping once the connection is established, and requires the server to respond with a pong
+	Ping bool
+	// TLSConfig is used to connect to wss:// URLs. If the ServerName is not set, the host of the URL is used.
+	TLSConfig *tls.Config
+}
+
+// websocketGUID is appended to the key of a handshake to compute the accept key of the server
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// DetectWebSocket registers a detector function that performs a WebSocket handshake with a ws:// or wss:// URL, and optionally measures the round trip time of a ping, which is reported in the details of the state of the dependency. The connection is closed gracefully once checked.
+// The check is bounded by the timeout of the dependency, or a default timeout of 5 seconds if none is set.
+func (d *Dependency) DetectWebSocket(wsURL string, c WebSocketConfig) {
+	d.DetectContext(func(ctx context.Context) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		u, err := url.Parse(wsURL)
+		if err != nil {
+			return err
+		}
+		conn, err := dialWebSocket(ctx, u, c.TLSConfig)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		stop := context.AfterFunc(ctx, func() {
+			conn.Close()
+		})
+		defer stop()
+
+		key := make([]byte, 16)
+		rand.Read(key)
+		encodedKey := base64.StdEncoding.EncodeToString(key)
+		httpURL := *u
+		httpURL.Scheme = map[string]string{"ws": "http", "wss": "https"}[u.Scheme]
+		req, err := http.NewRequest(http.MethodGet, httpURL.String(), nil)
+		if err != nil {
+			return err
+		}
+		for k, v := range c.Header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Key", encodedKey)
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		if err := req.Write(conn); err != nil {
+			return err
+		}
+		r := bufio.NewReader(conn)
+		res, err := http.ReadResponse(r, req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusSwitchingProtocols {
+			return fmt.Errorf("websocket handshake failed with http status: %s", res.Status)
+		}
+		accept := sha1.Sum([]byte(encodedKey + websocketGUID))
+		if res.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(accept[:]) {
+			return errors.New("websocket handshake failed: invalid Sec-WebSocket-Accept header")
+		}
+
+		if c.Ping {
+			payload := []byte("detective")
+			start := time.Now()
+			if err := writeWebSocketFrame(conn, websocketPing, payload, true); err != nil {
+				return err
+			}
+			if err := readWebSocketPong(r, payload); err != nil {
+				return err
+			}
+			SetDetail(ctx, "ping_rtt_ms", durationMs(time.Since(start)))
+		}
+		// A close frame with the normal closure status
+		return writeWebSocketFrame(conn, websocketClose, []byte{0x03, 0xe8}, true)
+	})
+}
+
+func dialWebSocket(ctx context.Context, u *url.URL, config *tls.Config) (net.Conn, error) {
+	host, port := u.Hostname(), u.Port()
+	switch u.Scheme {
+	case "ws":
+		if port == "" {
+			port = "80"
+		}
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	case "wss":
+		if port == "" {
+			port = "443"
+		}
+		if config == nil {
+			config = &tls.Config{}
+		} else {
+			config = config.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+		// The handshake is an HTTP/1.1 request, even if the server supports HTTP/2
+		config.NextProtos = []string{"http/1.1"}
+		dialer := tls.Dialer{Config: config}
+		return dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	}
+	return nil, fmt.Errorf("unsupported websocket url scheme %q", u.Scheme)
+}
+
+const (
+	websocketText  = 0x1
+	websocketClose = 0x8
+	websocketPing  = 0x9
+	websocketPong  = 0xa
+
+	// maxWebSocketFrameSize bounds the size of the frames read from the server
+	maxWebSocketFrameSize = 1 << 20
+)
+
+// readWebSocketPong reads frames until a pong with the payload of the ping. Other frames, like messages sent by the server once connected, are skipped.
+func readWebSocketPong(r *bufio.Reader, payload []byte) error {
+	for {
+		opcode, data, err := readWebSocketFrame(r)
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case websocketPong:
+			if string(data) == string(payload) {
+				return nil
+			}
+		case websocketClose:
+			if len(data) >= 2 {
+				return fmt.Errorf("websocket closed by the server with status %d: %s", binary.BigEndian.Uint16(data), data[2:])
+			}
+			return errors.New("websocket closed by the server")
+		}
+	}
+}
+
+// writeWebSocketFrame writes a final frame. Frames sent by clients have to be masked.
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte, masked bool) error {
+	frame := []byte{0x80 | opcode}
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	data := payload
+	if masked {
+		mask := make([]byte, 4)
+		rand.Read(mask)
+		frame = append(frame, mask...)
+		data = make([]byte, len(payload))
+		for i, b := range payload {
+			data[i] = b ^ mask[i%4]
+		}
+	}
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+// readWebSocketFrame reads a frame, and returns its opcode and unmasked payload
+func readWebSocketFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(b[:])
+	}
+	if length > maxWebSocketFrameSize {
+		return 0, nil, fmt.Errorf("websocket frame of %d bytes is too large", length)
+	}
+	var mask []byte
+	if header[1]&0x80 != 0 {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(r, mask); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if mask != nil {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return header[0] & 0x0f, payload, nil
+}
@@ -0,0 +1,107 @@
+package detective
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// websocketHandler accepts WebSocket connections which send a token header, sends a welcome message, and answers pings with pongs. On the /closing path, pings are answered by closing the connection.
+func websocketHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		assert.Equal(t, "websocket", r.Header.Get("Upgrade"))
+		assert.Equal(t, "13", r.Header.Get("Sec-WebSocket-Version"))
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		accept := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n")
+		writeWebSocketFrame(rw, websocketText, []byte("welcome"), false)
+		rw.Flush()
+		for {
+			opcode, payload, err := readWebSocketFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			switch {
+			case opcode == websocketPing && r.URL.Path == "/closing":
+				writeWebSocketFrame(rw, websocketClose, append([]byte{0x03, 0xf3}, "internal error"...), false)
+				rw.Flush()
+				return
+			case opcode == websocketPing:
+				writeWebSocketFrame(rw, websocketPong, payload, false)
+				rw.Flush()
+			case opcode == websocketClose:
+				writeWebSocketFrame(rw, websocketClose, payload, false)
+				rw.Flush()
+				return
+			}
+		}
+	})
+}
+
+func TestDetectWebSocket(t *testing.T) {
+	s := httptest.NewServer(websocketHandler(t))
+	defer s.Close()
+	tlsServer := httptest.NewTLSServer(websocketHandler(t))
+	defer tlsServer.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+
+	wsURL := strings.Replace(s.URL, "http://", "ws://", 1)
+	header := http.Header{"X-Token": {"secret"}}
+	tests := []struct {
+		name string
+		url  string
+		c    WebSocketConfig
+		err  string
+		ping bool
+	}{
+		{name: "handshake", url: wsURL + "/socket", c: WebSocketConfig{Header: header}},
+		{name: "ping", url: wsURL + "/socket", c: WebSocketConfig{Header: header, Ping: true}, ping: true},
+		{name: "tls", url: strings.Replace(tlsServer.URL, "https://", "wss://", 1), c: WebSocketConfig{Header: header, Ping: true, TLSConfig: &tls.Config{RootCAs: roots}}, ping: true},
+		{name: "refused", url: wsURL + "/socket", err: "websocket handshake failed with http status: 403 Forbidden"},
+		{name: "closed", url: wsURL + "/closing", c: WebSocketConfig{Header: header, Ping: true}, err: "websocket closed by the server with status 1011: internal error"},
+		{name: "unsupported scheme", url: s.URL, err: `unsupported websocket url scheme "http"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDependency("websocket")
+			d.DetectWebSocket(tt.url, tt.c)
+			st := d.getState(context.Background())
+			if tt.err != "" {
+				assert.False(t, st.Ok)
+				assert.Equal(t, tt.err, st.Error)
+				return
+			}
+			assert.True(t, st.Ok, st.Error)
+			assert.Equal(t, tt.ping, st.Details["ping_rtt_ms"] != nil)
+		})
+	}
+}
+
+func TestWebSocketFrames(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 70000} {
+		var b strings.Builder
+		payload := []byte(strings.Repeat("x", size))
+		assert.NoError(t, writeWebSocketFrame(&b, websocketText, payload, true))
+		opcode, got, err := readWebSocketFrame(bufio.NewReader(strings.NewReader(b.String())))
+		assert.NoError(t, err)
+		assert.Equal(t, byte(websocketText), opcode)
+		assert.Equal(t, payload, got)
+	}
+}